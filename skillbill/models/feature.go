@@ -1,7 +1,7 @@
-package models
-
-type Feature struct {
-	FeatureID   string `json:"featureid"`
-	FeatureName string `json:"featurename"`
-	DocType     string `json:"doctype"`
-}
+package models
+
+type Feature struct {
+	FeatureID   string `json:"featureid"`
+	FeatureName string `json:"featurename"`
+	DocType     string `json:"doctype"`
+}
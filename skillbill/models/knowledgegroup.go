@@ -1,15 +1,17 @@
-package models
-
-type KnowledgeGroup struct {
-	GroupID		string	`json:"groupid"`
-	GroupName	string	`json:"groupname"`
-	DocType		string	`json:"doctype"`
-}
-
-type KnowledgeGroupMember struct {
-	ID			string	`json:"id"`
-	GroupID		string	`json:"groupid"`
-	MemberType	string	`json:"membertype"`
-	UserID		string	`json:"userid"`
-	DocType		string	`json:"doctype"`
+package models
+
+// KnowledgeGroup is a group of skills, the groups form a tree by their parent (Engineering, Backend, Go)
+type KnowledgeGroup struct {
+	GroupID		string	`json:"groupid"`
+	GroupName	string	`json:"groupname"`
+	ParentID	string	`json:"parentid"`
+	DocType		string	`json:"doctype"`
+}
+
+type KnowledgeGroupMember struct {
+	ID			string	`json:"id"`
+	GroupID		string	`json:"groupid"`
+	MemberType	string	`json:"membertype"`
+	UserID		string	`json:"userid"`
+	DocType		string	`json:"doctype"`
 }
\ No newline at end of file
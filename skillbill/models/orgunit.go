@@ -0,0 +1,9 @@
+package models
+
+// OrgUnit is a department of the organisation, a unit without parent is at the top
+type OrgUnit struct {
+	OrgUnitID   string `json:"orgunitid"`
+	OrgUnitName string `json:"orgunitname"`
+	ParentID    string `json:"parentid"`
+	DocType     string `json:"doctype"`
+}
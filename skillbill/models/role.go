@@ -1,8 +1,8 @@
-package models
-
-// Role model
-type Role struct {
-	RoleID   string `json:"roleid"`
-	RoleName string `json:"rolename"`
-	DocType  string `json:"doctype"`
-}
+package models
+
+// Role model
+type Role struct {
+	RoleID   string `json:"roleid"`
+	RoleName string `json:"rolename"`
+	DocType  string `json:"doctype"`
+}
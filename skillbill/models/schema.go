@@ -0,0 +1,210 @@
+package models
+
+import (
+	"strings"
+)
+
+// Doc types, stored in the doctype column of every record
+const (
+	UserDocType                    string = "user"
+	UserSecretDocType              string = "usersecret"
+	OrgUnitDocType                 string = "orgunit"
+	UserRoleDocType                string = "userrole"
+	RoleDocType                    string = "role"
+	FeatureDocType                 string = "feature"
+	RoleFeatureDocType             string = "rolefeature"
+	KnowledgeGroupDocType          string = "knowledgegroup"
+	KnowledgeGroupMemberDocType    string = "knowledgegroupmember"
+	TrackDocType                   string = "track"
+	MilestoneDocType               string = "milestone"
+	MilestoneDependencyDocType     string = "milestonedependency"
+	MilestoneSkillDocType          string = "milestoneskill"
+	SkillDocType                   string = "skill"
+	SkillResourceDocType           string = "skillresource"
+	SkillDependencyDocType         string = "skilldependency"
+	SkillAcceptanceCriteriaDocType string = "skillacceptancecriteria"
+	TranslationDocType             string = "translation"
+	RightDocType                   string = "right"
+	PlannedSkillDocType            string = "plannedskill"
+	InProgressSkillDocType         string = "inprogressskill"
+	CompletedSkillDocType          string = "completedskill"
+	AssessmentResultDocType        string = "assessmentresult"
+	AssessmentRequestDocType       string = "assessmentrequest"
+	MigrationDocType               string = "migration"
+	MigrationStateDocType          string = "migrationstate"
+	ConfigDocType                  string = "config"
+)
+
+// Column names, they are the json tags of the models and must be used to build queries
+const (
+	DocTypeColumnName       string = "doctype"
+	IDColumnName            string = "id"
+	SchemaVersionColumnName string = "schemaversion"
+	DeletedColumnName       string = "deleted"
+	DeletedByColumnName     string = "deletedby"
+	DeletedAtColumnName     string = "deletedat"
+
+	UserADLoginColumnName          string = "adlogin"
+	UserMSPIDColumnName            string = "mspid"
+	UserSecretHashColumnName       string = "secrethash"
+	UserPublicKeyColumnName        string = "publickey"
+	UserRoleIDColumnName           string = "roleid"
+	UserOrgUnitIDColumnName        string = "orgunitid"
+	UserManagerIDColumnName        string = "managerid"
+	UserDisabledColumnName         string = "disabled"
+	UserPendingColumnName          string = "pending"
+	UserApprovedByColumnName       string = "approvedby"
+	UserSecondFactorHashColumnName string = "secondfactorhash"
+
+	UserRoleUserIDColumnName     string = "userid"
+	UserRoleRoleIDColumnName     string = "roleid"
+	UserRoleScopeIDColumnName    string = "scopeid"
+	UserRoleValidFromColumnName  string = "validfrom"
+	UserRoleValidToColumnName    string = "validto"
+	UserRoleAssignedByColumnName string = "assignedby"
+	UserRoleRevokedByColumnName  string = "revokedby"
+
+	OrgUnitIDColumnName       string = "orgunitid"
+	OrgUnitNameColumnName     string = "orgunitname"
+	OrgUnitParentIDColumnName string = "parentid"
+
+	RoleIDColumnName   string = "roleid"
+	RoleNameColumnName string = "rolename"
+
+	FeatureIDColumnName   string = "featureid"
+	FeatureNameColumnName string = "featurename"
+
+	RoleFeatureAccessLevelColumnName string = "accesslevel"
+	RoleFeatureFeatureIDColumnName   string = "featureid"
+	RoleFeatureRoleIDColumnName      string = "roleid"
+
+	KnowledgeGroupIDColumnName       string = "groupid"
+	KnowledgeGroupNameColumnName     string = "groupname"
+	KnowledgeGroupParentIDColumnName string = "parentid"
+
+	KnowledgeGroupMemberGroupIDColumnName    string = "groupid"
+	KnowledgeGroupMemberMemberTypeColumnName string = "membertype"
+	KnowledgeGroupMemberUserIDColumnName     string = "userid"
+
+	TrackIDColumnName            string = "trackid"
+	TrackTranslationIDColumnName string = "tracktranslationid"
+	TrackVersionColumnName       string = "version"
+
+	MilestoneIDColumnName                 string = "milestoneid"
+	MilestoneTranslationIDColumnName      string = "milestonetranslationid"
+	MilestoneTrackIDColumnName            string = "trackid"
+	MilestoneVersionColumnName            string = "version"
+	MilestoneDependingMilestoneColumnName string = "dependingmilestone"
+	MilestoneSkillIDColumnName            string = "skillid"
+
+	SkillIDColumnName                       string = "skillid"
+	SkillAssessmentTypeColumnName           string = "assessmenttype"
+	SkillBackwardCompatibleToColumnName     string = "backwardcompatibleto"
+	SkillDescriptionTranslationIDColumnName string = "descriptiontranslationid"
+	SkillImageIDColumnName                  string = "imageid"
+	SkillKnowledgeGroupIDColumnName         string = "knowledgegroupid"
+	SkillLevelColumnName                    string = "level"
+	SkillNameTranslationIDColumnName        string = "nametranslationid"
+	SkillTimeEstimationInHoursColumnName    string = "timeestimationinhours"
+	SkillVersionColumnName                  string = "version"
+	SkillResourceLinkColumnName             string = "resourcelink"
+	SkillResourceTranslationIDColumnName    string = "resourcetranslationid"
+	SkillDependingOnSkillColumnName         string = "denpendingonskill"
+	SkillACIDColumnName                     string = "skillacid"
+
+	TranslationLanguageIDColumnName string = "languageid"
+	TranslationColumnName           string = "translation"
+	TranslationObjectIDColumnName   string = "translationobjectid"
+
+	RightIDColumnName   string = "rightid"
+	RightNameColumnName string = "rightname"
+
+	SkillPlanSkillIDColumnName             string = "skillid"
+	SkillPlanSkillVersionColumnName        string = "skillversion"
+	SkillPlanSkillLevelColumnName          string = "skilllevel"
+	SkillPlanUserIDColumnName              string = "userid"
+	SkillPlanPlannedFromColumnName         string = "plannedfrom"
+	SkillPlanPlannedToColumnName           string = "plannedto"
+	SkillPlanPriorityColumnName            string = "priority"
+	SkillPlanSkillACStartdateColumnName    string = "skillacstartdate"
+	SkillPlanTxIDColumnName                string = "txid"
+	SkillPlanAssessorCertificateColumnName string = "assessorcertificate"
+	SkillPlanAssessmentHashColumnName      string = "assessmenthash"
+	SkillPlanAssessorSignatureColumnName   string = "assessorsignature"
+	SkillPlanAssessedByColumnName          string = "assessedby"
+	SkillPlanCompletedOnColumnName         string = "completedon"
+	SkillPlanOutcomeColumnName             string = "outcome"
+	SkillPlanCommentColumnName             string = "comment"
+	SkillPlanSaltColumnName                string = "salt"
+	SkillPlanAssesseeIDColumnName          string = "assesseeid"
+	SkillPlanAssessorIDColumnName          string = "assessorid"
+	SkillPlanRequestedOnColumnName         string = "requestedon"
+)
+
+// Schema is the doc type and the json column names of an entity as stored on the ledger
+type Schema struct {
+	DocType string
+	Columns []string
+}
+
+// Schemas is the registry of every entity stored by the chaincodes, every record has its schema version
+var Schemas = []Schema{
+	{UserDocType, []string{UserADLoginColumnName, UserMSPIDColumnName, UserSecretHashColumnName, UserPublicKeyColumnName, UserRoleIDColumnName, UserOrgUnitIDColumnName, UserManagerIDColumnName, UserDisabledColumnName, UserPendingColumnName, UserApprovedByColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{UserSecretDocType, []string{UserADLoginColumnName, UserSecondFactorHashColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{UserRoleDocType, []string{IDColumnName, UserRoleUserIDColumnName, UserRoleRoleIDColumnName, UserRoleScopeIDColumnName, UserRoleValidFromColumnName, UserRoleValidToColumnName, UserRoleAssignedByColumnName, UserRoleRevokedByColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{OrgUnitDocType, []string{OrgUnitIDColumnName, OrgUnitNameColumnName, OrgUnitParentIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{RoleDocType, []string{RoleIDColumnName, RoleNameColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{FeatureDocType, []string{FeatureIDColumnName, FeatureNameColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{RoleFeatureDocType, []string{IDColumnName, RoleFeatureAccessLevelColumnName, RoleFeatureFeatureIDColumnName, RoleFeatureRoleIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{KnowledgeGroupDocType, []string{KnowledgeGroupIDColumnName, KnowledgeGroupNameColumnName, KnowledgeGroupParentIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{KnowledgeGroupMemberDocType, []string{IDColumnName, KnowledgeGroupMemberGroupIDColumnName, KnowledgeGroupMemberMemberTypeColumnName, KnowledgeGroupMemberUserIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{TrackDocType, []string{TrackIDColumnName, TrackTranslationIDColumnName, TrackVersionColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{MilestoneDocType, []string{DocTypeColumnName, MilestoneIDColumnName, MilestoneTranslationIDColumnName, MilestoneTrackIDColumnName, MilestoneVersionColumnName, SchemaVersionColumnName}},
+	{MilestoneDependencyDocType, []string{IDColumnName, MilestoneDependingMilestoneColumnName, MilestoneIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{MilestoneSkillDocType, []string{IDColumnName, MilestoneIDColumnName, MilestoneSkillIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{SkillDocType, []string{SkillAssessmentTypeColumnName, DocTypeColumnName, SkillBackwardCompatibleToColumnName, SkillDescriptionTranslationIDColumnName, SkillImageIDColumnName, SkillKnowledgeGroupIDColumnName, SkillLevelColumnName, SkillNameTranslationIDColumnName, SkillIDColumnName, SkillTimeEstimationInHoursColumnName, SkillVersionColumnName, SchemaVersionColumnName}},
+	{SkillResourceDocType, []string{IDColumnName, DocTypeColumnName, SkillResourceLinkColumnName, SkillResourceTranslationIDColumnName, SkillIDColumnName, SchemaVersionColumnName}},
+	{SkillDependencyDocType, []string{IDColumnName, DocTypeColumnName, SkillDependingOnSkillColumnName, SkillIDColumnName, SchemaVersionColumnName}},
+	{SkillAcceptanceCriteriaDocType, []string{IDColumnName, DocTypeColumnName, SkillDescriptionTranslationIDColumnName, SkillACIDColumnName, SkillIDColumnName, SchemaVersionColumnName}},
+	{TranslationDocType, []string{DocTypeColumnName, TranslationLanguageIDColumnName, TranslationColumnName, TranslationObjectIDColumnName, SchemaVersionColumnName}},
+	{RightDocType, []string{RightIDColumnName, RightNameColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{PlannedSkillDocType, []string{IDColumnName, SkillPlanPlannedFromColumnName, SkillPlanPlannedToColumnName, SkillPlanPriorityColumnName, SkillPlanSkillIDColumnName, SkillPlanUserIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{InProgressSkillDocType, []string{IDColumnName, SkillACIDColumnName, SkillPlanSkillACStartdateColumnName, SkillPlanSkillIDColumnName, SkillPlanUserIDColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{CompletedSkillDocType, []string{IDColumnName, SkillPlanSkillIDColumnName, SkillPlanSkillVersionColumnName, SkillPlanSkillLevelColumnName, SkillPlanUserIDColumnName, SkillPlanTxIDColumnName, SkillPlanAssessorCertificateColumnName, SkillPlanAssessmentHashColumnName, SkillPlanCompletedOnColumnName, SkillPlanAssessorSignatureColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{AssessmentResultDocType, []string{IDColumnName, SkillPlanAssessedByColumnName, SkillPlanCompletedOnColumnName, SkillPlanOutcomeColumnName, SkillPlanCommentColumnName, SkillPlanSaltColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+	{AssessmentRequestDocType, []string{IDColumnName, SkillPlanAssesseeIDColumnName, SkillPlanAssessorIDColumnName, SkillPlanSkillIDColumnName, SkillPlanRequestedOnColumnName, DocTypeColumnName, SchemaVersionColumnName}},
+}
+
+// GetSchema to find the schema of a doc type, the doc type is matched case insensitive
+func GetSchema(docType string) (Schema, bool) {
+	for _, schema := range Schemas {
+		if strings.EqualFold(schema.DocType, docType) {
+			return schema, true
+		}
+	}
+
+	return Schema{}, false
+}
+
+// Column returns the canonical name of a column of the schema, the name is matched case insensitive
+func (s Schema) Column(name string) (string, bool) {
+	for _, column := range s.Columns {
+		if strings.EqualFold(column, name) {
+			return column, true
+		}
+	}
+
+	return "", false
+}
+
+// CanonicalColumn returns the canonical name of a column of any schema, e.g. "RoleID" is "roleid".
+// Unknown names are returned unchanged.
+func CanonicalColumn(name string) string {
+	for _, schema := range Schemas {
+		if column, ok := schema.Column(name); ok {
+			return column
+		}
+	}
+
+	return name
+}
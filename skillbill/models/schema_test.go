@@ -0,0 +1,60 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemasMatchJSONTags(t *testing.T) {
+	entities := map[string]interface{}{
+		UserDocType:                    User{},
+		UserSecretDocType:              UserSecret{},
+		OrgUnitDocType:                 OrgUnit{},
+		UserRoleDocType:                UserRole{},
+		RoleDocType:                    Role{},
+		FeatureDocType:                 Feature{},
+		RoleFeatureDocType:             RoleFeature{},
+		KnowledgeGroupDocType:          KnowledgeGroup{},
+		KnowledgeGroupMemberDocType:    KnowledgeGroupMember{},
+		TrackDocType:                   Track{},
+		MilestoneDocType:               Milestone{},
+		MilestoneDependencyDocType:     MilestoneDependency{},
+		MilestoneSkillDocType:          MilestoneSkill{},
+		SkillDocType:                   Skill{},
+		SkillAcceptanceCriteriaDocType: SkillAcceptanceCriteria{},
+		TranslationDocType:             TranslationObject{},
+	}
+
+	for docType, entity := range entities {
+		schema, ok := GetSchema(docType)
+		if !ok {
+			t.Errorf("The doc type %s is not registered", docType)
+			continue
+		}
+
+		entityType := reflect.TypeOf(entity)
+		for i := 0; i < entityType.NumField(); i++ {
+			tag := strings.Split(entityType.Field(i).Tag.Get("json"), ",")[0]
+			if column, ok := schema.Column(tag); !ok || column != tag {
+				t.Errorf("The json tag %s of %s is not a column of its schema", tag, entityType.Name())
+			}
+		}
+	}
+}
+
+func TestCanonicalColumn(t *testing.T) {
+	cases := map[string]string{
+		"DocType":   DocTypeColumnName,
+		"RoleId":    RoleFeatureRoleIDColumnName,
+		"FeatureID": RoleFeatureFeatureIDColumnName,
+		"GroupID":   KnowledgeGroupMemberGroupIDColumnName,
+		"unknown":   "unknown",
+	}
+
+	for name, expected := range cases {
+		if column := CanonicalColumn(name); column != expected {
+			t.Errorf("Expected the column %s for %s but got %s", expected, name, column)
+		}
+	}
+}
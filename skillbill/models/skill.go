@@ -0,0 +1,38 @@
+package models
+
+// Skill model
+type Skill struct {
+	AssessmentType           string `json:"assessmenttype"`
+	DocType                  string `json:"doctype"`
+	BackwardCompatibleTo     string `json:"backwardcompatibleto"`
+	DescriptionTranslationID string `json:"descriptiontranslationid"`
+	ImageID                  string `json:"imageid"`
+	KnowledgeGroupID         string `json:"knowledgegroupid"`
+	Level                    string `json:"level"`
+	NameTranslationID        string `json:"nametranslationid"`
+	SkillID                  string `json:"skillid"`
+	TimeEstimationInHours    string `json:"timeestimationinhours"`
+	Version                  string `json:"version"`
+}
+
+type SkillAcceptanceCriteria struct {
+	ID                       string `json:"id"`
+	DocType                  string `json:"doctype"`
+	DescriptionTranslationID string `json:"descriptiontranslationid"`
+	SkillACID                string `json:"skillacid"`
+	SkillID                  string `json:"skillid"`
+}
+
+// SkillDependency is a skill which must be completed before the skill SkillID
+type SkillDependency struct {
+	ID               string `json:"id"`
+	DocType          string `json:"doctype"`
+	DependingOnSkill string `json:"denpendingonskill"`
+	SkillID          string `json:"skillid"`
+}
+
+// SkillGraph is a set of skills with the dependencies between them
+type SkillGraph struct {
+	Skills       []Skill           `json:"skills"`
+	Dependencies []SkillDependency `json:"dependencies"`
+}
@@ -1,8 +1,8 @@
-package models
-
-type Track struct {
-	TrackID					string 	`json:"trackid"`
-	TrackTranslationID		string	`json:"tracktranslationid"`
-	Version					string	`json:"version"`
-	DocType					string	`json:"doctype"`
+package models
+
+type Track struct {
+	TrackID					string 	`json:"trackid"`
+	TrackTranslationID		string	`json:"tracktranslationid"`
+	Version					string	`json:"version"`
+	DocType					string	`json:"doctype"`
 }
\ No newline at end of file
@@ -0,0 +1,9 @@
+package models
+
+// TranslationObject support multi language
+type TranslationObject struct {
+	DocType             string `json:"doctype"`
+	LanguageID          string `json:"languageid"`
+	Translation         string `json:"translation"`
+	TranslationObjectID string `json:"translationobjectid"`
+}
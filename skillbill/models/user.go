@@ -1,9 +1,25 @@
-package models
-
-type User struct {
-	ADLogin        string `json:"adlogin"`
-	HashedPassword string `json:"hashedpassword"`
-	PublicKey      string `json:"publickey"`
-	RoleID         string `json:"roleid"`
-	DocType        string `json:"doctype"`
-}
+package models
+
+// User is identified by the MSP ID and the ad login, the other records refer to the user by the ad login.
+// The manager is the ad login of the user they report to.
+// A disabled user can not login nor access any feature, neither can a user who registered and is pending the approval.
+type User struct {
+	ADLogin    string `json:"adlogin"`
+	MSPID      string `json:"mspid"`
+	SecretHash string `json:"secrethash"`
+	PublicKey  string `json:"publickey"`
+	RoleID     string `json:"roleid"`
+	OrgUnitID  string `json:"orgunitid"`
+	ManagerID  string `json:"managerid"`
+	Disabled   bool   `json:"disabled"`
+	Pending    bool   `json:"pending"`
+	ApprovedBy string `json:"approvedby"`
+	DocType    string `json:"doctype"`
+}
+
+// UserSecret is kept in a private data collection with the key of the user, only its hash is stored on User
+type UserSecret struct {
+	ADLogin          string `json:"adlogin"`
+	SecondFactorHash string `json:"secondfactorhash"`
+	DocType          string `json:"doctype"`
+}
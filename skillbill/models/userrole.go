@@ -0,0 +1,15 @@
+package models
+
+// UserRole assigns a role to a user, within a scope (the id of a knowledge group or a track) or globally when the scope is empty.
+// The assignment is active from ValidFrom until ValidTo, an empty bound is open. A revoked assignment is kept as history.
+type UserRole struct {
+	ID         string `json:"id"`
+	UserID     string `json:"userid"`
+	RoleID     string `json:"roleid"`
+	ScopeID    string `json:"scopeid"`
+	ValidFrom  string `json:"validfrom"`
+	ValidTo    string `json:"validto"`
+	AssignedBy string `json:"assignedby"`
+	RevokedBy  string `json:"revokedby"`
+	DocType    string `json:"doctype"`
+}
@@ -0,0 +1,364 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+)
+
+// Attributes of the enrollment certificate, issued by the CA (fabric-ca-client register --id.attrs)
+const (
+	RoleAttribute           string = "skillbill.role"
+	KnowledgeGroupAttribute string = "skillbill.knowledgegroups"
+)
+
+// Features seeded by the feature chaincode
+const (
+	SkillPlanManagementFeatureID   string = "025D1E9A-9B52-E811-AA17-FCAA145000C2"
+	SkillManagementFeatureID       string = "035D1E9A-9B52-E811-AA17-FCAA145000C2"
+	TrackManagementFeatureID       string = "045D1E9A-9B52-E811-AA17-FCAA145000C2"
+	MilestoneManagementFeatureID   string = "055D1E9A-9B52-E811-AA17-FCAA145000C2"
+	UserManagementFeatureID        string = "065D1E9A-9B52-E811-AA17-FCAA145000C2"
+	RoleManagementFeatureID        string = "075D1E9A-9B52-E811-AA17-FCAA145000C2"
+	KnowledgeGroupFeatureID        string = "085D1E9A-9B52-E811-AA17-FCAA145000C2"
+	FeatureManagementFeatureID     string = "095D1E9A-9B52-E811-AA17-FCAA145000C2"
+	TranslationManagementFeatureID string = "0A5D1E9A-9B52-E811-AA17-FCAA145000C2"
+)
+
+// Subject is the caller an access decision is made for
+type Subject struct {
+	ID                string
+	MSPID             string
+	RoleIDs           []string
+	KnowledgeGroupIDs []string
+	// FromCertificate is true when the roles were read from the certificate attributes
+	FromCertificate bool
+}
+
+// IAccessDecider decides whether a subject can access a feature with an access level
+type IAccessDecider interface {
+	CanAccess(stub shim.ChaincodeStubInterface, subject Subject, featureID string, accessLevel int) (bool, error)
+}
+
+// RoleResolver returns the on-ledger roles of the caller, used when the certificate has no role attribute
+type RoleResolver func(stub shim.ChaincodeStubInterface) ([]string, error)
+
+// ScopedRoleResolver returns the roles assigned to the caller within one of the scopes, e.g. a knowledge group and the groups above it
+type ScopedRoleResolver func(stub shim.ChaincodeStubInterface, scopeIDs []string) ([]string, error)
+
+// EnabledResolver is false when the caller is a registered user who has been disabled
+type EnabledResolver func(stub shim.ChaincodeStubInterface) (bool, error)
+
+// AttributeMSPResolver returns the MSPs whose CA is trusted to issue the role and knowledge group attributes
+type AttributeMSPResolver func(stub shim.ChaincodeStubInterface) ([]string, error)
+
+// AccessControl checks the caller permission with a pluggable decider
+type AccessControl struct {
+	Decider  IAccessDecider
+	Fallback RoleResolver
+	Scoped   ScopedRoleResolver
+	Enabled  EnabledResolver
+	// AttributeMSPs are the MSPs the certificate attributes are read from, the callers of other MSPs get their roles from the ledger
+	AttributeMSPs AttributeMSPResolver
+}
+
+// NewAccessControl is constructor, roles are granted by the role chaincode and fall back to the user of the security chaincode
+func NewAccessControl() AccessControl {
+	return AccessControl{Decider: RoleFeatureDecider{}, Fallback: LedgerRoles, Scoped: LedgerScopedRoles, Enabled: LedgerEnabled,
+		AttributeMSPs: LedgerAttributeMSPIDs}
+}
+
+// GetSubject reads role and knowledge group attributes from the enrollment certificate of the caller,
+// the attributes of a certificate of an MSP which is not trusted to issue them are ignored.
+// It fails when the trusted MSPs are not configured.
+func (a AccessControl) GetSubject(stub shim.ChaincodeStubInterface) (Subject, error) {
+	subject := Subject{}
+
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return subject, err
+	}
+
+	cert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return subject, err
+	}
+
+	subject.ID = cert.Subject.CommonName
+	subject.MSPID = mspID
+
+	if a.AttributeMSPs == nil {
+		return subject, errs.New(errs.Internal, "The MSPs trusted to issue the certificate attributes are not configured")
+	}
+
+	attributeMSPIDs, err := a.AttributeMSPs(stub)
+	if err != nil {
+		return subject, err
+	}
+
+	if contains(attributeMSPIDs, mspID) {
+		if groups, found, _ := cid.GetAttributeValue(stub, KnowledgeGroupAttribute); found {
+			subject.KnowledgeGroupIDs = splitAttribute(groups)
+		}
+
+		if roles, found, _ := cid.GetAttributeValue(stub, RoleAttribute); found && roles != "" {
+			subject.RoleIDs = splitAttribute(roles)
+			subject.FromCertificate = true
+			return subject, nil
+		}
+	}
+
+	if a.Fallback == nil {
+		return subject, nil
+	}
+
+	subject.RoleIDs, err = a.Fallback(stub)
+
+	return subject, err
+}
+
+// CheckUserPermission to check user can access feature, within one of the optional scopes.
+// The roles assigned within a scope are added to the global roles of the caller, a disabled user can access no feature.
+// accessLevel : 0- readonly, 1- write and read
+func (a AccessControl) CheckUserPermission(stub shim.ChaincodeStubInterface, featureID string, accessLevel string, scopeIDs ...string) sc.Response {
+
+	level, err := strconv.Atoi(accessLevel)
+	if err != nil {
+		return errs.Failf(errs.InvalidArgument, "Invalid access level %s", accessLevel)
+	}
+
+	// The roles of the certificate do not depend on the ledger, so the user is checked first
+	if a.Enabled != nil {
+		enabled, err := a.Enabled(stub)
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Could not check the caller is enabled"))
+		}
+
+		if !enabled {
+			return shim.Success([]byte(strconv.FormatBool(false)))
+		}
+	}
+
+	subject, err := a.GetSubject(stub)
+	if errs.Is(err, errs.NotFound) {
+		// A caller who is not registered has no role
+		return shim.Success([]byte(strconv.FormatBool(false)))
+	}
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get the caller"))
+	}
+
+	if len(scopeIDs) > 0 && a.Scoped != nil {
+		scopedRoles, err := a.Scoped(stub, scopeIDs)
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Could not get the scoped roles of the caller"))
+		}
+
+		subject.RoleIDs = append(subject.RoleIDs, scopedRoles...)
+	}
+
+	canAccess, err := a.Decider.CanAccess(stub, subject, featureID, level)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not check the permission"))
+	}
+
+	return shim.Success([]byte(strconv.FormatBool(canAccess)))
+}
+
+// CheckPermission returns an error unless the caller can access the feature with the access level, within one of the optional scopes
+func (a AccessControl) CheckPermission(stub shim.ChaincodeStubInterface, featureID string, accessLevel string, scopeIDs ...string) error {
+	response := a.CheckUserPermission(stub, featureID, accessLevel, scopeIDs...)
+	if response.Status != shim.OK {
+		return errs.Wrap(errs.FromResponse(response), "Could not check the permission")
+	}
+
+	if string(response.Payload) != "true" {
+		return errs.Errorf(errs.PermissionDenied, "Permission denied, the caller can not access the feature %s", featureID)
+	}
+
+	return nil
+}
+
+// CheckAdministrator returns an error unless the caller can write the features, which only the administrators can
+func (a AccessControl) CheckAdministrator(stub shim.ChaincodeStubInterface) error {
+	response := a.CheckUserPermission(stub, FeatureManagementFeatureID, "1")
+	if response.Status != shim.OK {
+		return errs.Wrap(errs.FromResponse(response), "Could not check the permission")
+	}
+
+	if string(response.Payload) != "true" {
+		return errs.New(errs.PermissionDenied, "Permission denied, the function can only be called by an administrator")
+	}
+
+	return nil
+}
+
+// RoleFeatureResolver returns the features assigned to the roles
+type RoleFeatureResolver func(stub shim.ChaincodeStubInterface, roleIDs []string) ([]models.RoleFeature, error)
+
+// RoleFeatureDecider grants access by the features assigned to the roles,
+// read from the role chaincode unless Features is set, e.g. by the role chaincode itself
+type RoleFeatureDecider struct {
+	Features RoleFeatureResolver
+}
+
+// CanAccess is true when one of the roles has the feature, read write includes read only
+func (d RoleFeatureDecider) CanAccess(stub shim.ChaincodeStubInterface, subject Subject, featureID string, accessLevel int) (bool, error) {
+
+	if len(subject.RoleIDs) == 0 {
+		return false, nil
+	}
+
+	features := d.Features
+	if features == nil {
+		features = LedgerRoleFeatures
+	}
+
+	roleFeatures, err := features(stub, subject.RoleIDs)
+	if err != nil {
+		return false, err
+	}
+
+	for _, roleFeature := range roleFeatures {
+		if roleFeature.FeatureID == featureID && roleFeature.AccessLevel >= accessLevel {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// LedgerRoleFeatures returns the features of the roles assigned in the role chaincode
+func LedgerRoleFeatures(stub shim.ChaincodeStubInterface, roleIDs []string) ([]models.RoleFeature, error) {
+
+	response := InvokeChaincode(stub, "role", "getFeaturesByRoleIDs", strings.Join(roleIDs, ","))
+	if response.Status != shim.OK {
+		return nil, errs.Wrap(errs.FromResponse(response), "Failed to query chaincode")
+	}
+
+	roleFeatures := make([]models.RoleFeature, 0)
+	err := json.Unmarshal(response.Payload, &roleFeatures)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not parse json to feature object")
+	}
+
+	return roleFeatures, nil
+}
+
+// LedgerRoles returns the roles of the caller registered in the security chaincode,
+// the role of the user and the roles actively assigned to them without scope
+func LedgerRoles(stub shim.ChaincodeStubInterface) ([]string, error) {
+
+	response := InvokeChaincode(stub, "security", "GetCurrentRoles")
+	if response.Status != shim.OK {
+		return nil, errs.Wrap(errs.FromResponse(response), "Failed to get the roles of the current user")
+	}
+
+	roles := []string{}
+	err := json.Unmarshal(response.Payload, &roles)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not parse json to the roles")
+	}
+
+	return roles, nil
+}
+
+// LedgerScopedRoles returns the roles assigned to the caller within the scopes in the security chaincode
+func LedgerScopedRoles(stub shim.ChaincodeStubInterface, scopeIDs []string) ([]string, error) {
+
+	response := InvokeChaincode(stub, "security", "GetScopedRoles", strings.Join(scopeIDs, ","))
+	if response.Status != shim.OK {
+		return nil, errs.Wrap(errs.FromResponse(response), "Failed to get the scoped roles")
+	}
+
+	roles := []string{}
+	err := json.Unmarshal(response.Payload, &roles)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not parse json to the roles")
+	}
+
+	return roles, nil
+}
+
+// LedgerEnabled is false when the caller is registered in the security chaincode and disabled
+func LedgerEnabled(stub shim.ChaincodeStubInterface) (bool, error) {
+
+	response := InvokeChaincode(stub, "security", "IsCallerEnabled")
+	if response.Status != shim.OK {
+		return false, errs.Wrap(errs.FromResponse(response), "Failed to check the current user")
+	}
+
+	return string(response.Payload) == "true", nil
+}
+
+// AttributeMSPConfig is the record of the MSPs trusted to issue the certificate attributes
+type AttributeMSPConfig struct {
+	MSPIDs  []string `json:"mspids"`
+	DocType string   `json:"doctype"`
+}
+
+// InitAttributeMSPIDs stores the MSPs trusted to issue the certificate attributes, they are the parameters of Init,
+// e.g. {"Args":["init","Org1MSP"]}. Init runs on every upgrade as well, an upgrade without parameters keeps the stored MSPs.
+func InitAttributeMSPIDs(stub shim.ChaincodeStubInterface) error {
+	_, params := stub.GetFunctionAndParameters()
+
+	mspIDs := splitAttribute(strings.Join(params, ","))
+	if len(mspIDs) == 0 {
+		_, err := LedgerAttributeMSPIDs(stub)
+		return err
+	}
+
+	key, err := stub.CreateCompositeKey(models.ConfigDocType, []string{})
+	if err != nil {
+		return err
+	}
+
+	data, _ := json.Marshal(AttributeMSPConfig{MSPIDs: mspIDs, DocType: models.ConfigDocType})
+
+	return stub.PutState(key, data)
+}
+
+// LedgerAttributeMSPIDs returns the MSPs stored by InitAttributeMSPIDs, it fails when there are none
+func LedgerAttributeMSPIDs(stub shim.ChaincodeStubInterface) ([]string, error) {
+	key, err := stub.CreateCompositeKey(models.ConfigDocType, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := stub.GetState(key)
+	if err != nil {
+		return nil, errs.Wrap(err, "Failed to read the MSPs trusted to issue the certificate attributes")
+	}
+
+	config := AttributeMSPConfig{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, errs.Wrap(err, "Could not parse json to the MSPs trusted to issue the certificate attributes")
+		}
+	}
+
+	if len(config.MSPIDs) == 0 {
+		return nil, errs.New(errs.Internal, `The MSPs trusted to issue the certificate attributes are not configured, `+
+			`instantiate or upgrade the chaincode with them, e.g. {"Args":["init","Org1MSP"]}`)
+	}
+
+	return config.MSPIDs, nil
+}
+
+func splitAttribute(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+)
+
+// RecordCheck returns an error when the caller may not delete the record of the key.
+// The delete check of a chaincode also guards the restore and the listing of its archived records.
+type RecordCheck func(stub shim.ChaincodeStubInterface, key string) error
+
+// AdministratorCheck is the record check of the chaincodes whose records have no owner, only administrators restore them like they purge them
+func AdministratorCheck(stub shim.ChaincodeStubInterface, key string) error {
+	return CreateBase().CheckAdministrator(stub)
+}
+
+// Restore brings back a record archived by a soft delete, the caller needs the permission to delete it
+// arg[0] : key of the record
+func (t Base) Restore(stub shim.ChaincodeStubInterface, args []string, canDelete RecordCheck) sc.Response {
+	if len(args) != 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 1")
+	}
+
+	err := canDelete(stub, args[0])
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = repository.Restore(stub, args[0])
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// ListArchived returns the archived records of the doc types of the chaincode the caller may delete
+// arg[0] : optional doc type, every doc type of the chaincode without it
+func (t Base) ListArchived(stub shim.ChaincodeStubInterface, args []string, canDelete RecordCheck, docTypes ...string) sc.Response {
+	if len(args) > 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting at most 1")
+	}
+
+	if len(args) == 1 && args[0] != "" {
+		docType, ok := findDocType(docTypes, args[0])
+		if !ok {
+			return errs.Fail(errs.InvalidArgument, "Invalid doc type " + args[0] + ", expecting one of " + strings.Join(docTypes, ", "))
+		}
+
+		docTypes = []string{docType}
+	}
+
+	records := []json.RawMessage{}
+	for _, docType := range docTypes {
+		archived, err := repository.GetArchivedRecords(stub, docType)
+		if err != nil {
+			return errs.Response(err)
+		}
+
+		for _, record := range archived {
+			err = canDelete(stub, record.Key)
+			if errs.Is(err, errs.PermissionDenied) {
+				continue
+			}
+
+			if err != nil {
+				return errs.Response(err)
+			}
+
+			records = append(records, record.Value)
+		}
+	}
+
+	data, _ := json.Marshal(records)
+
+	return shim.Success(data)
+}
+
+// Purge removes an archived record for good, only administrators can purge
+// arg[0] : key of the record
+func (t Base) Purge(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 1")
+	}
+
+	err := t.CheckAdministrator(stub)
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = repository.Purge(stub, args[0])
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	return shim.Success(nil)
+}
+
+func findDocType(docTypes []string, docType string) (string, bool) {
+	for _, candidate := range docTypes {
+		if strings.EqualFold(candidate, docType) {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
@@ -1,49 +1,60 @@
-package core
-
-import (
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-)
-
-// CreateBase is constructor
-func CreateBase() IBase {
-	return Base{}
-}
-
-type Base struct {
-}
-
-func toChaincodeArgs(args ...string) [][]byte {
-	bargs := make([][]byte, len(args))
-	for i, arg := range args {
-		bargs[i] = []byte(arg)
-	}
-	return bargs
-}
-
-// ValidateLogin to check user can login (check public key of caller)
-func (t Base) ValidateLogin(stub shim.ChaincodeStubInterface, password string) sc.Response {
-
-	channelName := ""
-	chaincodeName := "security"
-	functionName := "ValidateLogin"
-
-	queryArgs := toChaincodeArgs(functionName, password)
-
-	response := stub.InvokeChaincode(chaincodeName, queryArgs, channelName)
-	return response
-}
-
-// CheckUserPermission to check user can access feature
-// arg[0] : featureID, arg[1] : accessLevel (0- readonly, 1- write and read)
-func (t Base) CheckUserPermission(stub shim.ChaincodeStubInterface, featureID string, accessLevel string) sc.Response {
-
-	channelName := ""
-	chaincodeName := "security"
-	functionName := "CheckUserPermission"
-
-	queryArgs := toChaincodeArgs(functionName, featureID, accessLevel)
-
-	response := stub.InvokeChaincode(chaincodeName, queryArgs, channelName)
-	return response
-}
+package core
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+// CreateBase is constructor
+func CreateBase() IBase {
+	return Base{Access: NewAccessControl()}
+}
+
+type Base struct {
+	Access AccessControl
+}
+
+func toChaincodeArgs(args ...string) [][]byte {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	return bargs
+}
+
+// InvokeChaincode to call a function of another chaincode on the same channel
+func InvokeChaincode(stub shim.ChaincodeStubInterface, chaincodeName string, functionName string, args ...string) sc.Response {
+
+	channelName := ""
+
+	queryArgs := toChaincodeArgs(append([]string{functionName}, args...)...)
+
+	response := stub.InvokeChaincode(chaincodeName, queryArgs, channelName)
+	return response
+}
+
+// ValidateLogin to check user can login (check the identity of caller).
+// The optional second factor is read by the security chaincode from the transient data of the proposal.
+func (t Base) ValidateLogin(stub shim.ChaincodeStubInterface) sc.Response {
+
+	channelName := ""
+	chaincodeName := "security"
+	functionName := "ValidateLogin"
+
+	queryArgs := toChaincodeArgs(functionName)
+
+	response := stub.InvokeChaincode(chaincodeName, queryArgs, channelName)
+	return response
+}
+
+// CheckUserPermission to check user can access feature
+// The roles are read from the certificate attributes, or from the security chaincode when there is none
+// arg[0] : featureID, arg[1] : accessLevel (0- readonly, 1- write and read), the optional scopes add the roles assigned within them
+func (t Base) CheckUserPermission(stub shim.ChaincodeStubInterface, featureID string, accessLevel string, scopeIDs ...string) sc.Response {
+	return t.Access.CheckUserPermission(stub, featureID, accessLevel, scopeIDs...)
+}
+
+// CheckAdministrator returns an error unless the caller is an administrator
+func (t Base) CheckAdministrator(stub shim.ChaincodeStubInterface) error {
+	return t.Access.CheckAdministrator(stub)
+}
@@ -1,11 +1,17 @@
-package core
-
-import (
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-)
-
-type IBase interface {
-	ValidateLogin(shim.ChaincodeStubInterface, string) sc.Response
-	CheckUserPermission(shim.ChaincodeStubInterface, string, string) sc.Response
-}
+package core
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+type IBase interface {
+	ValidateLogin(shim.ChaincodeStubInterface) sc.Response
+	CheckUserPermission(shim.ChaincodeStubInterface, string, string, ...string) sc.Response
+	CheckAdministrator(shim.ChaincodeStubInterface) error
+	Migrate(shim.ChaincodeStubInterface, []string) sc.Response
+	GetMigrations(shim.ChaincodeStubInterface) sc.Response
+	Restore(shim.ChaincodeStubInterface, []string, RecordCheck) sc.Response
+	ListArchived(shim.ChaincodeStubInterface, []string, RecordCheck, ...string) sc.Response
+	Purge(shim.ChaincodeStubInterface, []string) sc.Response
+}
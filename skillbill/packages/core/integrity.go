@@ -0,0 +1,323 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+)
+
+// Delete modes, passed as the optional last argument of the delete functions
+const (
+	// DeleteRestrict refuses to delete a record which is still referenced, it is the default
+	DeleteRestrict string = "restrict"
+	// DeleteCascade deletes the records which reference the deleted one, or clears their reference
+	DeleteCascade string = "cascade"
+	// DeleteSoft keeps the record flagged as deleted, so the references stay valid
+	DeleteSoft string = "soft"
+)
+
+// Reference is a column of the records which point to an entity.
+// The records are stored by the chaincode itself, or by another chaincode when Chaincode is set.
+type Reference struct {
+	Chaincode string
+	DocType   string
+	Column    string
+	// Clear empties the column of the records on cascade instead of deleting them
+	Clear bool
+}
+
+// Inbound is a reference of the records of the chaincode to the records of another chaincode,
+// the other chaincode looks it up and cascades its deletes to it.
+// FeatureID is the feature the delete of the referenced record checks, the caller needs write access to it
+// globally or within the referenced record to cascade the delete.
+type Inbound struct {
+	DocType   string
+	Column    string
+	Clear     bool
+	FeatureID string
+}
+
+// Integrity looks up the references to a record before it is deleted, by the doc type of the record.
+// The functions called by the other chaincodes only accept the inbound references.
+type Integrity struct {
+	References map[string][]Reference
+	Inbound    []Inbound
+	// Access checks the callers of the cascades, NewAccessControl when it has no decider
+	Access AccessControl
+}
+
+// GetDeleteMode returns the delete mode passed at the index of the arguments, restrict when it is missing
+func GetDeleteMode(args []string, index int) (string, error) {
+	if len(args) <= index || args[index] == "" {
+		return DeleteRestrict, nil
+	}
+
+	mode := strings.ToLower(args[index])
+	if mode != DeleteRestrict && mode != DeleteCascade && mode != DeleteSoft {
+		return "", errs.Errorf(errs.InvalidArgument, "Invalid delete mode %s, expecting %s, %s or %s", args[index], DeleteRestrict, DeleteCascade, DeleteSoft)
+	}
+
+	return mode, nil
+}
+
+// Delete removes a record after checking the records which reference it, depending on the mode
+func (i Integrity) Delete(stub shim.ChaincodeStubInterface, key string, mode string) error {
+	if len(key) < 1 {
+		return errs.New(errs.InvalidArgument, "Entity Ids are empty. Please specify the entity id to delete.")
+	}
+
+	value, err := stub.GetState(key)
+	if err != nil {
+		return errs.Wrapf(err, "Failed to delete %s", key)
+	}
+
+	if len(value) == 0 {
+		return errs.Errorf(errs.NotFound, "Failed to delete, because the key %s does not exist.", key)
+	}
+
+	docType, _ := repository.DocTypeOf(value)
+	references := i.References[strings.ToLower(docType)]
+
+	switch mode {
+	case DeleteSoft:
+		return repository.SoftDelete(stub, key)
+
+	case DeleteCascade:
+		for _, reference := range references {
+			err = i.cascade(stub, reference, key)
+			if err != nil {
+				return err
+			}
+		}
+
+	case DeleteRestrict:
+		for _, reference := range references {
+			keys, err := i.findReferences(stub, reference, key)
+			if err != nil {
+				return err
+			}
+
+			if len(keys) > 0 {
+				return errs.Errorf(errs.Conflict, "The %s %s is still referenced by the %s %s", docType, key, reference.DocType, strings.Join(keys, ","))
+			}
+		}
+
+	default:
+		return errs.Errorf(errs.InvalidArgument, "Invalid delete mode %s", mode)
+	}
+
+	return stub.DelState(key)
+}
+
+func (i Integrity) findReferences(stub shim.ChaincodeStubInterface, reference Reference, value string) ([]string, error) {
+	if reference.Chaincode == "" {
+		return FindReferences(stub, reference.DocType, reference.Column, value)
+	}
+
+	response := InvokeChaincode(stub, reference.Chaincode, "GetReferences", reference.DocType, reference.Column, value)
+	if response.Status != shim.OK {
+		return nil, errs.Wrapf(errs.FromResponse(response), "Failed to get the references from chaincode %s", reference.Chaincode)
+	}
+
+	keys := []string{}
+	err := json.Unmarshal(response.Payload, &keys)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not parse json to the references")
+	}
+
+	return keys, nil
+}
+
+func (i Integrity) cascade(stub shim.ChaincodeStubInterface, reference Reference, value string) error {
+	if reference.Chaincode != "" {
+		function := "DeleteReferences"
+		if reference.Clear {
+			function = "ClearReferences"
+		}
+
+		response := InvokeChaincode(stub, reference.Chaincode, function, reference.DocType, reference.Column, value)
+		if response.Status != shim.OK {
+			return errs.Wrapf(errs.FromResponse(response), "Failed to cascade the delete to chaincode %s", reference.Chaincode)
+		}
+
+		return nil
+	}
+
+	if reference.Clear {
+		return clearReferences(stub, reference.DocType, reference.Column, value)
+	}
+
+	return i.deleteReferences(stub, reference.DocType, reference.Column, value)
+}
+
+// deleteReferences deletes the records whose column has the value, and cascades to their own references
+func (i Integrity) deleteReferences(stub shim.ChaincodeStubInterface, docType string, column string, value string) error {
+	keys, err := FindReferences(stub, docType, column, value)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = i.Delete(stub, key, DeleteCascade)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearReferences empties the column of the records which have the value
+func clearReferences(stub shim.ChaincodeStubInterface, docType string, column string, value string) error {
+	keys, err := FindReferences(stub, docType, column, value)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		data, err := repository.GetDocument(stub, key)
+		if err != nil {
+			return err
+		}
+
+		var record map[string]interface{}
+		err = json.Unmarshal(data, &record)
+		if err != nil {
+			return errs.Wrapf(err, "Could not parse the record %s", key)
+		}
+
+		record[column] = ""
+		data, _ = json.Marshal(record)
+
+		err = repository.PutDocument(stub, key, data)
+		if err != nil {
+			return errs.Wrapf(err, "Failed to clear the reference of %s", key)
+		}
+	}
+
+	return nil
+}
+
+// FindReferences returns the keys of the records of a doc type whose column has the value
+func FindReferences(stub shim.ChaincodeStubInterface, docType string, column string, value string) ([]string, error) {
+	selector := map[string]interface{}{models.DocTypeColumnName: docType, column: value}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	resultsIterator, err := stub.GetQueryResult(string(query))
+	if err != nil {
+		return nil, errs.Wrapf(err, "Failed to get the references of %s", value)
+	}
+	defer resultsIterator.Close()
+
+	keys := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, queryResponse.Key)
+	}
+
+	return keys, nil
+}
+
+// inbound returns the inbound reference of the doc type and the column
+func (i Integrity) inbound(docType string, column string) (Inbound, error) {
+	for _, inbound := range i.Inbound {
+		if strings.EqualFold(inbound.DocType, docType) && strings.EqualFold(inbound.Column, column) {
+			return inbound, nil
+		}
+	}
+
+	return Inbound{}, errs.Errorf(errs.InvalidArgument, "The column %s of the %s is not a reference of another chaincode", column, docType)
+}
+
+// checkCascade returns an error unless the inbound reference can be cascaded the way it is declared,
+// by a caller who can delete the referenced record
+func (i Integrity) checkCascade(stub shim.ChaincodeStubInterface, docType string, column string, clear bool, value string) (Inbound, error) {
+	inbound, err := i.inbound(docType, column)
+	if err != nil {
+		return inbound, err
+	}
+
+	if inbound.Clear != clear {
+		return inbound, errs.Errorf(errs.InvalidArgument, "The references of the column %s of the %s can not be cascaded this way", column, docType)
+	}
+
+	access := i.Access
+	if access.Decider == nil {
+		access = NewAccessControl()
+	}
+
+	return inbound, access.CheckPermission(stub, inbound.FeatureID, "1", value)
+}
+
+// GetReferences returns the keys of the records which reference a record of another chaincode
+// arg[0] : doc type, arg[1] : column, arg[2] : referenced key
+func (i Integrity) GetReferences(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 3 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 3")
+	}
+
+	inbound, err := i.inbound(args[0], args[1])
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	keys, err := FindReferences(stub, inbound.DocType, inbound.Column, args[2])
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	data, _ := json.Marshal(keys)
+
+	return shim.Success(data)
+}
+
+// DeleteReferences cascades the delete of a record of another chaincode to the records which reference it,
+// the caller must be able to delete the record
+// arg[0] : doc type, arg[1] : column, arg[2] : deleted key
+func (i Integrity) DeleteReferences(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 3 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 3")
+	}
+
+	inbound, err := i.checkCascade(stub, args[0], args[1], false, args[2])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete the references of " + args[2]))
+	}
+
+	err = i.deleteReferences(stub, inbound.DocType, inbound.Column, args[2])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete the references of " + args[2]))
+	}
+
+	return shim.Success(nil)
+}
+
+// ClearReferences empties the column of the records which reference a deleted record of another chaincode,
+// the caller must be able to delete the record
+// arg[0] : doc type, arg[1] : column, arg[2] : deleted key
+func (i Integrity) ClearReferences(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 3 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 3")
+	}
+
+	inbound, err := i.checkCascade(stub, args[0], args[1], true, args[2])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to clear the references of " + args[2]))
+	}
+
+	err = clearReferences(stub, inbound.DocType, inbound.Column, args[2])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to clear the references of " + args[2]))
+	}
+
+	return shim.Success(nil)
+}
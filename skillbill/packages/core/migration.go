@@ -0,0 +1,62 @@
+package core
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+)
+
+// DefaultMigrationPageSize is the number of records read by a batch of Migrate
+const DefaultMigrationPageSize int = 100
+
+// Migrate upgrades a batch of the records of the chaincode to the current schema versions, only administrators can run it.
+// It is called again with the returned bookmark until the result is completed.
+// arg[0] : optional page size, arg[1] : optional bookmark, the batch resumes where the previous one stopped without it
+func (t Base) Migrate(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) > 2 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting at most 2")
+	}
+
+	err := t.CheckAdministrator(stub)
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	pageSize := DefaultMigrationPageSize
+	if len(args) > 0 && args[0] != "" {
+		pageSize, err = strconv.Atoi(args[0])
+		if err != nil {
+			return errs.Fail(errs.InvalidArgument, "Invalid page size " + args[0])
+		}
+	}
+
+	bookmark := ""
+	if len(args) > 1 {
+		bookmark = args[1]
+	}
+
+	result, err := repository.Migrate(stub, bookmark, pageSize)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to migrate"))
+	}
+
+	data, _ := json.Marshal(result)
+
+	return shim.Success(data)
+}
+
+// GetMigrations returns the migrations which have run over every record of the chaincode
+func (t Base) GetMigrations(stub shim.ChaincodeStubInterface) sc.Response {
+	records, err := repository.GetMigrationRecords(stub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get migrations"))
+	}
+
+	data, _ := json.Marshal(records)
+
+	return shim.Success(data)
+}
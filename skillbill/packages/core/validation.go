@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skillbill/packages/errs"
+)
+
+// Types of the arguments, an argument is a string unless its schema has another type.
+// A pair is a column and a value separated by a comma, like "userid,dave".
+const (
+	ArgString string = "string"
+	ArgInt    string = "int"
+	ArgNumber string = "number"
+	ArgBool   string = "bool"
+	ArgDate   string = "date"
+	ArgPair   string = "pair"
+)
+
+// DateLayout is the format of the dates unless the schema of the argument has another one
+const DateLayout string = "2006-01-02"
+
+// Arg is the schema of an argument, Name is used in the errors
+type Arg struct {
+	Name string
+	Type string
+	// Required arguments can not be missing or empty, the optional ones are not checked when they are
+	Required bool
+	// Enum lists the values the argument can take, any value when it is empty
+	Enum []string
+	// Format is the layout of a date, DateLayout by default
+	Format string
+	// Repeated is the last argument of a schema, it takes all the remaining arguments
+	Repeated bool
+}
+
+// Schema is the schema of the arguments of a function, in their order.
+// The arguments after the last required one can be left out.
+type Schema []Arg
+
+// Schemas are the schemas of the functions of a chaincode by function name
+type Schemas map[string]Schema
+
+// Validate returns an INVALID_ARGUMENT error when the arguments of a function do not match its schema,
+// a function without schema is not checked
+func (s Schemas) Validate(function string, args []string) error {
+	schema, ok := s[function]
+	if !ok {
+		return nil
+	}
+
+	return schema.Validate(args)
+}
+
+// Validate returns an INVALID_ARGUMENT error when the number of arguments or one of them does not match the schema
+func (s Schema) Validate(args []string) error {
+	min := 0
+	for i, arg := range s {
+		if arg.Required {
+			min = i + 1
+		}
+	}
+
+	if len(args) < min || (len(args) > len(s) && !s.repeated()) {
+		return errs.New(errs.InvalidArgument, "Incorrect number of arguments. Expecting "+s.count(min))
+	}
+
+	for i, value := range args {
+		arg := s[len(s)-1]
+		if i < len(s) {
+			arg = s[i]
+		}
+
+		if err := arg.validate(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s Schema) repeated() bool {
+	return len(s) > 0 && s[len(s)-1].Repeated
+}
+
+func (s Schema) count(min int) string {
+	if s.repeated() {
+		return fmt.Sprintf("at least %d", min)
+	}
+
+	if min == len(s) {
+		return strconv.Itoa(min)
+	}
+
+	if min+1 == len(s) {
+		return fmt.Sprintf("%d or %d", min, len(s))
+	}
+
+	return fmt.Sprintf("%d to %d", min, len(s))
+}
+
+func (a Arg) validate(value string) error {
+	if value == "" {
+		if a.Required {
+			return errs.Errorf(errs.InvalidArgument, "The %s is required", a.Name)
+		}
+
+		return nil
+	}
+
+	switch a.Type {
+	case ArgInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return errs.Errorf(errs.InvalidArgument, "The %s %s is not an integer", a.Name, value)
+		}
+	case ArgNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return errs.Errorf(errs.InvalidArgument, "The %s %s is not a number", a.Name, value)
+		}
+	case ArgBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errs.Errorf(errs.InvalidArgument, "The %s %s is not true or false", a.Name, value)
+		}
+	case ArgDate:
+		format := a.Format
+		if format == "" {
+			format = DateLayout
+		}
+
+		if _, err := time.Parse(format, value); err != nil {
+			return errs.Errorf(errs.InvalidArgument, "The %s %s is not a date like %s", a.Name, value, format)
+		}
+	case ArgPair:
+		if pair := strings.SplitN(value, ",", 2); len(pair) != 2 || pair[0] == "" {
+			return errs.Errorf(errs.InvalidArgument, "The %s %s is not a pair like column,value", a.Name, value)
+		}
+	}
+
+	if len(a.Enum) > 0 && !contains(a.Enum, value) {
+		return errs.Errorf(errs.InvalidArgument, "The %s %s is not one of %s", a.Name, value, strings.Join(a.Enum, ", "))
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skillbill/packages/errs"
+)
+
+var plannedSkill = Schema{
+	{Name: "id", Required: true},
+	{Name: "planned from", Type: ArgDate, Required: true},
+	{Name: "priority", Type: ArgInt, Required: true},
+	{Name: "level", Enum: []string{"0", "1"}},
+	{Name: "hours", Type: ArgNumber},
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{"valid arguments", []string{"P1", "2018-01-01", "1", "0", "1.5"}, ""},
+		{"optional arguments left out", []string{"P1", "2018-01-01", "1"}, ""},
+		{"optional argument empty", []string{"P1", "2018-01-01", "1", "", "2"}, ""},
+		{"missing arguments", []string{"P1"}, "Incorrect number of arguments. Expecting 3 to 5"},
+		{"too many arguments", []string{"P1", "2018-01-01", "1", "0", "1", "x"}, "Incorrect number of arguments. Expecting 3 to 5"},
+		{"empty required argument", []string{"", "2018-01-01", "1"}, "The id is required"},
+		{"invalid date", []string{"P1", "01/02/2018", "1"}, "The planned from 01/02/2018 is not a date like 2006-01-02"},
+		{"invalid integer", []string{"P1", "2018-01-01", "high"}, "The priority high is not an integer"},
+		{"value out of the enum", []string{"P1", "2018-01-01", "1", "5"}, "The level 5 is not one of 0, 1"},
+		{"invalid number", []string{"P1", "2018-01-01", "1", "0", "many"}, "The hours many is not a number"},
+	}
+
+	for _, c := range cases {
+		err := plannedSkill.Validate(c.args)
+
+		if c.message == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+
+		if c.message != "" && (!errs.Is(err, errs.InvalidArgument) || err.Error() != c.message) {
+			t.Errorf("%s: expected the invalid argument %q but got %v", c.name, c.message, err)
+		}
+	}
+}
+
+func TestSchemasValidate(t *testing.T) {
+	schemas := Schemas{"get": {{Name: "id", Required: true}}, "delete": {{Name: "id", Required: true}, {Name: "delete mode"}}}
+
+	if err := schemas.Validate("get", nil); err == nil || err.Error() != "Incorrect number of arguments. Expecting 1" {
+		t.Errorf("Expected the arguments of the function to be checked but got %v", err)
+	}
+
+	if err := schemas.Validate("delete", []string{}); err == nil || err.Error() != "Incorrect number of arguments. Expecting 1 or 2" {
+		t.Errorf("Expected the arguments of the function to be checked but got %v", err)
+	}
+
+	if err := schemas.Validate("list", []string{"a", "b"}); err != nil {
+		t.Errorf("Expected a function without schema not to be checked but got %v", err)
+	}
+}
+
+func TestValidateDateFormat(t *testing.T) {
+	schema := Schema{{Name: "valid from", Type: ArgDate, Format: time.RFC3339, Required: true}}
+
+	if err := schema.Validate([]string{"2018-01-01T00:00:00Z"}); err != nil {
+		t.Errorf("Expected the date in the format of the schema to be valid but got %v", err)
+	}
+
+	if err := schema.Validate([]string{"2018-01-01"}); !errs.Is(err, errs.InvalidArgument) {
+		t.Errorf("Expected a date in another format to be invalid but got %v", err)
+	}
+}
+
+func TestValidateRepeated(t *testing.T) {
+	schema := Schema{{Name: "type", Required: true}, {Name: "filter", Type: ArgPair, Repeated: true}}
+
+	if err := schema.Validate([]string{"planned", "userid,dave", "skillid,S1,S2"}); err != nil {
+		t.Errorf("Expected the remaining arguments to be checked against the repeated one but got %v", err)
+	}
+
+	if err := schema.Validate([]string{"planned"}); err != nil {
+		t.Errorf("Expected the repeated argument to be left out but got %v", err)
+	}
+
+	if err := schema.Validate(nil); err == nil || err.Error() != "Incorrect number of arguments. Expecting at least 1" {
+		t.Errorf("Expected the required arguments to be checked but got %v", err)
+	}
+
+	if err := schema.Validate([]string{"planned", "userid,dave", "userid"}); err == nil || err.Error() != "The filter userid is not a pair like column,value" {
+		t.Errorf("Expected every repeated argument to be checked but got %v", err)
+	}
+
+	if err := schema.Validate([]string{"planned", ",dave"}); !errs.Is(err, errs.InvalidArgument) {
+		t.Errorf("Expected a pair without column to be invalid but got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package credential
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// W3C Verifiable Credentials data model, see https://www.w3.org/TR/vc-data-model/
+const (
+	CredentialsContext string = "https://www.w3.org/2018/credentials/v1"
+
+	VerifiableCredentialType string = "VerifiableCredential"
+	SkillCredentialType      string = "SkillAchievementCredential"
+	LedgerProofType          string = "FabricLedgerProof"
+	AssertionMethod          string = "assertionMethod"
+
+	UserURNPrefix           string = "urn:skillbill:user:"
+	CompletedSkillURNPrefix string = "urn:skillbill:completedskill:"
+	FingerprintURNPrefix    string = "urn:sha256:"
+)
+
+// VerifiableCredential is a skill achievement exported from the skill plan, issued by the assessor who signed it
+type VerifiableCredential struct {
+	Context           []string          `json:"@context"`
+	ID                string            `json:"id"`
+	Type              []string          `json:"type"`
+	Issuer            string            `json:"issuer"`
+	IssuanceDate      string            `json:"issuanceDate"`
+	CredentialSubject CredentialSubject `json:"credentialSubject"`
+	Proof             Proof             `json:"proof"`
+}
+
+// CredentialSubject holds the claims about the user
+type CredentialSubject struct {
+	ID      string `json:"id"`
+	SkillID string `json:"skillId"`
+	Version string `json:"version"`
+	Level   string `json:"level"`
+}
+
+// Proof references the assessor certificate and the transaction that recorded the completion,
+// ProofValue is the signature of the record hash made by the assessor
+type Proof struct {
+	Type               string `json:"type"`
+	Created            string `json:"created"`
+	ProofPurpose       string `json:"proofPurpose"`
+	VerificationMethod string `json:"verificationMethod"`
+	TransactionID      string `json:"transactionId"`
+	LedgerKey          string `json:"ledgerKey"`
+	RecordHash         string `json:"recordHash"`
+	ProofValue         string `json:"proofValue"`
+}
+
+// LedgerProof is the ledger state a credential was issued from, exported for offline verification
+type LedgerProof struct {
+	Key                 string `json:"key"`
+	TxID                string `json:"txid"`
+	Record              []byte `json:"record"`
+	AssessorCertificate string `json:"assessorcertificate"`
+}
+
+// ledgerRecord is the part of a completed skill record a credential is issued from. The fields the ledger adds
+// to the record later, e.g. its schema version and archive flags, and the signature of the assessor are not part of it.
+type ledgerRecord struct {
+	ID                  string `json:"id"`
+	SkillID             string `json:"skillid"`
+	SkillVersion        string `json:"skillversion"`
+	SkillLevel          string `json:"skilllevel"`
+	UserID              string `json:"userid"`
+	TxID                string `json:"txid"`
+	AssessorCertificate string `json:"assessorcertificate"`
+	AssessmentHash      string `json:"assessmenthash"`
+	CompletedOn         string `json:"completedon"`
+	DocType             string `json:"doctype"`
+}
+
+// HashRecord returns the hex encoded sha256 of the canonical part of a completed skill record
+func HashRecord(record []byte) (string, error) {
+	var canonical ledgerRecord
+	err := json.Unmarshal(record, &canonical)
+	if err != nil {
+		return "", fmt.Errorf("Could not parse the ledger record, err %s", err)
+	}
+
+	data, _ := json.Marshal(canonical)
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// VerifyRecordSignature checks the hex encoded ASN.1 ECDSA signature of the sha256 of the record hash,
+// made with the key of the PEM encoded certificate
+func VerifyRecordSignature(certPEM string, recordHash string, signature string) error {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return err
+	}
+
+	key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("The certificate has no ECDSA key")
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("Invalid signature, err %s", err)
+	}
+
+	var rs struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+		return fmt.Errorf("Invalid signature, it is not ASN.1 encoded")
+	}
+
+	hash := sha256.Sum256([]byte(recordHash))
+	if !ecdsa.Verify(key, hash[:], rs.R, rs.S) {
+		return fmt.Errorf("The signature does not match the certificate")
+	}
+
+	return nil
+}
+
+// CertificateFingerprint returns the verification method for a PEM encoded certificate
+func CertificateFingerprint(certPEM string) (string, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(cert.Raw)
+	return FingerprintURNPrefix + hex.EncodeToString(hash[:]), nil
+}
+
+// ParseCertificate to parse a PEM encoded X.509 certificate
+func ParseCertificate(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, fmt.Errorf("Failed to decode PEM structure")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,46 @@
+package credential
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashRecordIgnoresLedgerFields(t *testing.T) {
+	_, proof := newCredential(t)
+
+	hash, _ := HashRecord(proof.Record)
+	stamped := strings.Replace(string(proof.Record), `"doctype"`, `"schemaversion":1,"deleted":true,"deletedby":"admin","assessorsignature":"00","doctype"`, 1)
+
+	if stampedHash, _ := HashRecord([]byte(stamped)); stampedHash != hash {
+		t.Errorf("Expected the hash of the record kept when the ledger stamps it but got %s and %s", hash, stampedHash)
+	}
+
+	changed := strings.Replace(string(proof.Record), `"userid":"alice"`, `"userid":"bob"`, 1)
+	if changedHash, _ := HashRecord([]byte(changed)); changedHash == hash {
+		t.Errorf("Expected another hash for another user")
+	}
+
+	if _, err := HashRecord([]byte("not json")); err == nil {
+		t.Errorf("Expected an error for a record which is not json")
+	}
+}
+
+func TestVerifyRecordSignature(t *testing.T) {
+	signature := assessor.Sign([]byte("hash"))
+
+	if err := VerifyRecordSignature(string(assessor.CertPEM), "hash", signature); err != nil {
+		t.Errorf("Expected the signature of the assessor to be valid but got %v", err)
+	}
+
+	if err := VerifyRecordSignature(string(assessor.CertPEM), "other", signature); err == nil {
+		t.Errorf("Expected the signature of another hash to be invalid")
+	}
+
+	if err := VerifyRecordSignature(string(assessor.CertPEM), "hash", "zz"); err == nil {
+		t.Errorf("Expected a signature which is not hex to be invalid")
+	}
+
+	if err := VerifyRecordSignature("not a certificate", "hash", signature); err == nil {
+		t.Errorf("Expected an invalid certificate to fail")
+	}
+}
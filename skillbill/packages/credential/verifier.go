@@ -0,0 +1,113 @@
+package credential
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Verifier checks skill credentials offline against an exported ledger proof
+type Verifier struct {
+	// Roots are the CA certificates trusted to issue assessor certificates, nil skips the chain check
+	Roots *x509.CertPool
+}
+
+// NewVerifier is constructor
+func NewVerifier(roots *x509.CertPool) Verifier {
+	return Verifier{Roots: roots}
+}
+
+// Verify returns an error when the credential does not match the ledger proof or is not signed by the assessor of the record
+func (v Verifier) Verify(vc VerifiableCredential, proof LedgerProof) error {
+
+	if vc.Proof.Type != LedgerProofType {
+		return fmt.Errorf("Unsupported proof type %s", vc.Proof.Type)
+	}
+
+	if vc.Proof.LedgerKey != proof.Key {
+		return fmt.Errorf("The credential refers to record %s but the proof is for %s", vc.Proof.LedgerKey, proof.Key)
+	}
+
+	recordHash, err := HashRecord(proof.Record)
+	if err != nil {
+		return err
+	}
+
+	if recordHash != vc.Proof.RecordHash {
+		return fmt.Errorf("The record hash does not match the credential")
+	}
+
+	var record ledgerRecord
+	json.Unmarshal(proof.Record, &record)
+
+	if record.DocType != "completedskill" || record.ID != proof.Key {
+		return fmt.Errorf("The ledger record %s is not a completed skill", proof.Key)
+	}
+
+	if record.TxID != proof.TxID || vc.Proof.TransactionID != proof.TxID {
+		return fmt.Errorf("The transaction id does not match the ledger record")
+	}
+
+	if vc.IssuanceDate != record.CompletedOn || vc.Proof.Created != record.CompletedOn {
+		return fmt.Errorf("The issuance date does not match the ledger record")
+	}
+
+	if vc.CredentialSubject.ID != UserURNPrefix+record.UserID {
+		return fmt.Errorf("The credential subject does not match the ledger record")
+	}
+
+	if vc.CredentialSubject.SkillID != record.SkillID {
+		return fmt.Errorf("The skill %s does not match the ledger record", vc.CredentialSubject.SkillID)
+	}
+
+	// The skill version and level are the ones assessed, the record hash covers them
+	if vc.CredentialSubject.Version != record.SkillVersion || vc.CredentialSubject.Level != record.SkillLevel {
+		return fmt.Errorf("The skill version or level does not match the ledger record")
+	}
+
+	if record.AssessorCertificate != proof.AssessorCertificate {
+		return fmt.Errorf("The assessor certificate does not match the ledger record")
+	}
+
+	fingerprint, err := CertificateFingerprint(proof.AssessorCertificate)
+	if err != nil {
+		return fmt.Errorf("Could not parse the assessor certificate, err %s", err)
+	}
+
+	if fingerprint != vc.Proof.VerificationMethod || fingerprint != vc.Issuer {
+		return fmt.Errorf("The assessor certificate does not match the credential")
+	}
+
+	// Only the assessor can sign the record, so the credential can not be made up from a copy of the ledger
+	err = VerifyRecordSignature(proof.AssessorCertificate, recordHash, vc.Proof.ProofValue)
+	if err != nil {
+		return fmt.Errorf("The credential is not signed by the assessor, err %s", err)
+	}
+
+	if v.Roots == nil {
+		return nil
+	}
+
+	return v.verifyAssessor(proof.AssessorCertificate, vc.IssuanceDate)
+}
+
+// verifyAssessor checks the assessor certificate chain at the time the credential was issued, which must be known
+func (v Verifier) verifyAssessor(certPEM string, issuanceDate string) error {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return err
+	}
+
+	at, err := time.Parse(time.RFC3339, issuanceDate)
+	if err != nil {
+		return fmt.Errorf("The issuance date %s is not a RFC3339 date", issuanceDate)
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{Roots: v.Roots, CurrentTime: at, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err != nil {
+		return fmt.Errorf("The assessor certificate is not trusted, err %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package credential
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skillbill/packages/testsupport"
+)
+
+var assessor = testsupport.MustNewIdentity("Org1MSP", "assessor", nil)
+
+// completedOn is within the validity of the certificate of the assessor
+var completedOn = time.Now().UTC().Format(time.RFC3339)
+
+// newCredential returns a credential signed by the assessor and the ledger proof it is issued from
+func newCredential(t *testing.T) (VerifiableCredential, LedgerProof) {
+	record := []byte(`{"id":"C1","skillid":"SKILL1","skillversion":"1","skilllevel":"2","userid":"alice","txid":"TX1","assessorcertificate":` + quote(string(assessor.CertPEM)) +
+		`,"assessmenthash":"abc","completedon":"` + completedOn + `","doctype":"completedskill"}`)
+
+	hash, err := HashRecord(record)
+	if err != nil {
+		t.Fatalf("Could not hash the record, err %v", err)
+	}
+
+	fingerprint, err := CertificateFingerprint(string(assessor.CertPEM))
+	if err != nil {
+		t.Fatalf("Could not get the fingerprint, err %v", err)
+	}
+
+	vc := VerifiableCredential{
+		Context:           []string{CredentialsContext},
+		ID:                CompletedSkillURNPrefix + "C1",
+		Type:              []string{VerifiableCredentialType, SkillCredentialType},
+		Issuer:            fingerprint,
+		IssuanceDate:      completedOn,
+		CredentialSubject: CredentialSubject{ID: UserURNPrefix + "alice", SkillID: "SKILL1", Version: "1", Level: "2"},
+		Proof: Proof{Type: LedgerProofType, Created: completedOn, ProofPurpose: AssertionMethod, VerificationMethod: fingerprint,
+			TransactionID: "TX1", LedgerKey: "C1", RecordHash: hash, ProofValue: assessor.Sign([]byte(hash))}}
+
+	return vc, LedgerProof{Key: "C1", TxID: "TX1", Record: record, AssessorCertificate: string(assessor.CertPEM)}
+}
+
+func quote(value string) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func TestVerify(t *testing.T) {
+	roots := x509.NewCertPool()
+	roots.AddCert(mustParse(t, string(assessor.CertPEM)))
+
+	cases := []struct {
+		name   string
+		change func(vc *VerifiableCredential, proof *LedgerProof)
+		valid  bool
+	}{
+		{"signed credential", func(vc *VerifiableCredential, proof *LedgerProof) {}, true},
+		{"record stamped by the ledger", func(vc *VerifiableCredential, proof *LedgerProof) {
+			proof.Record = []byte(strings.Replace(string(proof.Record), `"doctype"`, `"schemaversion":1,"doctype"`, 1))
+		}, true},
+		{"another subject", func(vc *VerifiableCredential, proof *LedgerProof) { vc.CredentialSubject.ID = UserURNPrefix + "bob" }, false},
+		{"another level", func(vc *VerifiableCredential, proof *LedgerProof) { vc.CredentialSubject.Level = "3" }, false},
+		{"another issuer", func(vc *VerifiableCredential, proof *LedgerProof) { vc.Issuer = "https://skillbill.example.com" }, false},
+		{"another issuance date", func(vc *VerifiableCredential, proof *LedgerProof) { vc.IssuanceDate = "2019-01-01T00:00:00Z" }, false},
+		{"record changed by the holder", func(vc *VerifiableCredential, proof *LedgerProof) {
+			proof.Record = []byte(strings.Replace(string(proof.Record), `"userid":"alice"`, `"userid":"bob"`, 1))
+			vc.CredentialSubject.ID = UserURNPrefix + "bob"
+			vc.Proof.RecordHash, _ = HashRecord(proof.Record)
+		}, false},
+		{"level changed by the holder", func(vc *VerifiableCredential, proof *LedgerProof) {
+			proof.Record = []byte(strings.Replace(string(proof.Record), `"skilllevel":"2"`, `"skilllevel":"3"`, 1))
+			vc.CredentialSubject.Level = "3"
+			vc.Proof.RecordHash, _ = HashRecord(proof.Record)
+		}, false},
+		{"without signature", func(vc *VerifiableCredential, proof *LedgerProof) { vc.Proof.ProofValue = "" }, false},
+		{"signed by another key", func(vc *VerifiableCredential, proof *LedgerProof) {
+			vc.Proof.ProofValue = testsupport.MustNewIdentity("Org1MSP", "assessor", nil).Sign([]byte(vc.Proof.RecordHash))
+		}, false},
+		{"another ledger key", func(vc *VerifiableCredential, proof *LedgerProof) { proof.Key = "C2" }, false},
+	}
+
+	for _, c := range cases {
+		vc, proof := newCredential(t)
+		c.change(&vc, &proof)
+
+		err := NewVerifier(roots).Verify(vc, proof)
+		if c.valid && err != nil {
+			t.Errorf("%s: expected a valid credential but got %v", c.name, err)
+		}
+
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected an invalid credential", c.name)
+		}
+	}
+}
+
+func TestVerifyAssessor(t *testing.T) {
+	roots := x509.NewCertPool()
+	roots.AddCert(mustParse(t, string(assessor.CertPEM)))
+
+	if err := NewVerifier(roots).verifyAssessor(string(assessor.CertPEM), "2018-06-01"); err == nil {
+		t.Errorf("Expected an issuance date which is not RFC3339 to fail")
+	}
+
+	other := testsupport.MustNewIdentity("Org2MSP", "assessor", nil)
+	if err := NewVerifier(roots).verifyAssessor(string(other.CertPEM), completedOn); err == nil {
+		t.Errorf("Expected a certificate of another CA not to be trusted")
+	}
+}
+
+func mustParse(t *testing.T, certPEM string) *x509.Certificate {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		t.Fatalf("Could not parse the certificate, err %v", err)
+	}
+
+	return cert
+}
@@ -0,0 +1,141 @@
+// Package errs gives the failed responses of the chaincodes a code the clients can branch on.
+// The message of a failed response is the json of an Error and its status matches the code.
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/logs"
+)
+
+// Code is the machine readable kind of an error
+type Code string
+
+// The codes of the errors
+const (
+	NotFound         Code = "NOT_FOUND"
+	AlreadyExists    Code = "ALREADY_EXISTS"
+	InvalidArgument  Code = "INVALID_ARGUMENT"
+	PermissionDenied Code = "PERMISSION_DENIED"
+	Conflict         Code = "CONFLICT"
+	Internal         Code = "INTERNAL"
+)
+
+// statuses of the responses by code, every status is above shim.ERRORTHRESHOLD so the peer rejects the proposal
+var statuses = map[Code]int32{
+	NotFound:         404,
+	AlreadyExists:    409,
+	InvalidArgument:  400,
+	PermissionDenied: 403,
+	Conflict:         409,
+	Internal:         shim.ERROR,
+}
+
+// InternalMessage is the message of the errors which are not an Error, their cause is only logged
+const InternalMessage string = "Internal error"
+
+// Error has a code and a message which can be returned to the client.
+// The cause is the error of a lower layer, it is logged but never returned.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	cause   error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + " due to " + e.cause.Error()
+	}
+
+	return e.Message
+}
+
+// New to create an error with the code
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf to create an error with the code, the message is formatted
+func Errorf(code Code, format string, a ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// Wrap to prefix the message of an error. An Error keeps its code,
+// any other error is INTERNAL and becomes the cause, so its message is not returned to the client.
+func Wrap(err error, message string) *Error {
+	if e, ok := err.(*Error); ok {
+		return &Error{Code: e.Code, Message: message + " due to " + e.Message, cause: e.cause}
+	}
+
+	return &Error{Code: Internal, Message: message, cause: err}
+}
+
+// Wrapf to prefix the message of an error like Wrap, the message is formatted
+func Wrapf(err error, format string, a ...interface{}) *Error {
+	return Wrap(err, fmt.Sprintf(format, a...))
+}
+
+// CodeOf is the code of an error, INTERNAL when it is not an Error
+func CodeOf(err error) Code {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+
+	return Internal
+}
+
+// Is true when the error has the code
+func Is(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
+
+// Status of the response of a code
+func Status(code Code) int32 {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+
+	return shim.ERROR
+}
+
+// Response is the failed response of an error, the message is the json of the Error.
+// An error which is not an Error is INTERNAL with a generic message, the causes are logged.
+func Response(err error) pb.Response {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Code: Internal, Message: InternalMessage, cause: err}
+	}
+
+	if e.cause != nil {
+		logs.LogError(e.Error())
+	}
+
+	data, _ := json.Marshal(e)
+
+	return pb.Response{Status: Status(e.Code), Message: string(data)}
+}
+
+// Fail is the failed response with the code and the message
+func Fail(code Code, message string) pb.Response {
+	return Response(New(code, message))
+}
+
+// Failf is the failed response with the code, the message is formatted
+func Failf(code Code, format string, a ...interface{}) pb.Response {
+	return Response(Errorf(code, format, a...))
+}
+
+// FromResponse is the error of a failed response of another chaincode, it keeps the code of the response.
+// A message which is not the json of an Error is the cause of an INTERNAL error.
+func FromResponse(response pb.Response) error {
+	var e Error
+	if err := json.Unmarshal([]byte(response.Message), &e); err != nil || e.Code == "" {
+		return errors.New(response.Message)
+	}
+
+	return &e
+}
@@ -0,0 +1,55 @@
+package errs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestResponse(t *testing.T) {
+	res := Fail(NotFound, "Could not find any user with name bob")
+
+	if res.Status != 404 || res.Message != `{"code":"NOT_FOUND","message":"Could not find any user with name bob"}` {
+		t.Errorf("Unexpected response %d %s", res.Status, res.Message)
+	}
+
+	if res.Status < shim.ERRORTHRESHOLD {
+		t.Errorf("Expected the peer to reject the response with status %d", res.Status)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	wrapped := Wrap(New(PermissionDenied, "the caller can not access the feature"), "Failed to delete track T1")
+
+	if wrapped.Code != PermissionDenied || wrapped.Message != "Failed to delete track T1 due to the caller can not access the feature" {
+		t.Errorf("Expected the code and the message to be kept but got %v", wrapped)
+	}
+
+	internal := Wrap(errors.New("GetState failed for key T1"), "Failed to delete track T1")
+	res := Response(internal)
+
+	if res.Status != shim.ERROR || res.Message != `{"code":"INTERNAL","message":"Failed to delete track T1"}` {
+		t.Errorf("Expected the cause to be hidden but got %d %s", res.Status, res.Message)
+	}
+
+	if internal.Error() != "Failed to delete track T1 due to GetState failed for key T1" {
+		t.Errorf("Expected the cause in the error but got %s", internal.Error())
+	}
+
+	if res := Response(errors.New("unmarshal failed")); res.Message != `{"code":"INTERNAL","message":"Internal error"}` {
+		t.Errorf("Expected a generic message but got %s", res.Message)
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	err := FromResponse(Failf(AlreadyExists, "The user %s existed already", "bob"))
+
+	if !Is(err, AlreadyExists) || err.Error() != "The user bob existed already" {
+		t.Errorf("Expected the error of the response but got %v", err)
+	}
+
+	if err := FromResponse(shim.Error("free text")); CodeOf(err) != Internal || err.Error() != "free text" {
+		t.Errorf("Expected an internal error but got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package mango
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+)
+
+// Stub wraps shim.MockStub, whose GetQueryResult is not implemented, and answers rich queries from its state.
+// It can be passed wherever a shim.ChaincodeStubInterface is expected, e.g. to the repositories.
+type Stub struct {
+	*shim.MockStub
+}
+
+// NewStub is constructor
+func NewStub(name string, cc shim.Chaincode) *Stub {
+	return &Stub{MockStub: shim.NewMockStub(name, cc)}
+}
+
+// GetQueryResult evaluates the Mango query against the state of the stub
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return QueryState(s.MockStub, query)
+}
+
+// QueryState evaluates the Mango query against the state of a MockStub
+func QueryState(stub *shim.MockStub, query string) (shim.StateQueryIteratorInterface, error) {
+	var docs []Document
+	for element := stub.Keys.Front(); element != nil; element = element.Next() {
+		key := element.Value.(string)
+		docs = append(docs, Document{Key: key, Value: stub.State[key]})
+	}
+
+	results, err := Execute(query, docs)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIterator(stub.Name, results), nil
+}
+
+// Iterator iterates over the query results
+type Iterator struct {
+	namespace string
+	results   []Document
+	index     int
+}
+
+// NewIterator is constructor
+func NewIterator(namespace string, results []Document) *Iterator {
+	return &Iterator{namespace: namespace, results: results}
+}
+
+// HasNext returns true if there is a next result
+func (it *Iterator) HasNext() bool {
+	return it.index < len(it.results)
+}
+
+// Next returns the next result
+func (it *Iterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("No more results")
+	}
+
+	result := it.results[it.index]
+	it.index = it.index + 1
+
+	return &queryresult.KV{Namespace: it.namespace, Key: result.Key, Value: result.Value}, nil
+}
+
+// Close the iterator
+func (it *Iterator) Close() error {
+	return nil
+}
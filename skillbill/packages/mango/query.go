@@ -0,0 +1,206 @@
+package mango
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Document is a ledger record a query is evaluated against
+type Document struct {
+	Key   string
+	Value []byte
+}
+
+// Query is a CouchDB Mango query, as passed to GetQueryResult
+type Query struct {
+	Selector map[string]interface{} `json:"selector"`
+	Fields   []string               `json:"fields"`
+	Sort     []interface{}          `json:"sort"`
+	Limit    int                    `json:"limit"`
+	Skip     int                    `json:"skip"`
+}
+
+// Parse to read a query string
+func Parse(query string) (Query, error) {
+	q := Query{}
+
+	err := json.Unmarshal([]byte(query), &q)
+	if err != nil {
+		return q, fmt.Errorf("Invalid query %s: %s", query, err)
+	}
+
+	if q.Selector == nil {
+		return q, fmt.Errorf("Invalid query %s: the selector is missing", query)
+	}
+
+	return q, nil
+}
+
+// Execute to parse the query and evaluate it against the documents
+func Execute(query string, docs []Document) ([]Document, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Evaluate(docs)
+}
+
+// Evaluate returns the matching documents, ordered by key like CouchDB unless the query is sorted.
+// Documents which are not json objects never match.
+func (q Query) Evaluate(docs []Document) ([]Document, error) {
+	type match struct {
+		key   string
+		doc   map[string]interface{}
+		value []byte
+	}
+
+	var matches []match
+	for _, item := range docs {
+		var doc map[string]interface{}
+		if json.Unmarshal(item.Value, &doc) != nil {
+			continue
+		}
+
+		ok, err := Match(q.Selector, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			matches = append(matches, match{key: item.Key, doc: doc, value: item.Value})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].key < matches[j].key
+	})
+
+	sortFields, err := parseSort(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sortFields) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			for _, field := range sortFields {
+				left, _ := lookup(matches[i].doc, field.name)
+				right, _ := lookup(matches[j].doc, field.name)
+
+				result := collate(left, right)
+				if result == 0 {
+					continue
+				}
+
+				if field.descending {
+					return result > 0
+				}
+				return result < 0
+			}
+			return false
+		})
+	}
+
+	if q.Skip > 0 {
+		if q.Skip >= len(matches) {
+			matches = nil
+		} else {
+			matches = matches[q.Skip:]
+		}
+	}
+
+	if q.Limit > 0 && q.Limit < len(matches) {
+		matches = matches[:q.Limit]
+	}
+
+	results := make([]Document, 0, len(matches))
+	for _, item := range matches {
+		value := item.value
+		if len(q.Fields) > 0 {
+			value, _ = json.Marshal(project(item.doc, q.Fields))
+		}
+
+		results = append(results, Document{Key: item.key, Value: value})
+	}
+
+	return results, nil
+}
+
+type sortField struct {
+	name       string
+	descending bool
+}
+
+// parseSort reads the sort syntax of Mango: ["field", {"field": "desc"}]
+func parseSort(items []interface{}) ([]sortField, error) {
+	var fields []sortField
+
+	for _, item := range items {
+		switch value := item.(type) {
+		case string:
+			fields = append(fields, sortField{name: value})
+		case map[string]interface{}:
+			for name, direction := range value {
+				switch direction {
+				case "asc":
+					fields = append(fields, sortField{name: name})
+				case "desc":
+					fields = append(fields, sortField{name: name, descending: true})
+				default:
+					return nil, fmt.Errorf("Invalid sort direction %v of field %s", direction, name)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("Invalid sort %v", item)
+		}
+	}
+
+	return fields, nil
+}
+
+// project keeps only the fields of the document, nested fields are written with a dot
+func project(doc map[string]interface{}, fields []string) map[string]interface{} {
+	result := map[string]interface{}{}
+
+	for _, field := range fields {
+		value, found := lookup(doc, field)
+		if !found {
+			continue
+		}
+
+		parts := strings.Split(field, ".")
+		target := result
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := target[part].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				target[part] = next
+			}
+			target = next
+		}
+		target[parts[len(parts)-1]] = value
+	}
+
+	return result
+}
+
+// lookup returns the value of a field, nested fields are written with a dot, e.g. "skill.level"
+func lookup(doc map[string]interface{}, field string) (interface{}, bool) {
+	var current interface{} = doc
+
+	for _, part := range strings.Split(field, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = object[part]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
@@ -0,0 +1,91 @@
+package mango
+
+import (
+	"strings"
+	"testing"
+)
+
+var docs = []Document{
+	{Key: "S3", Value: []byte(`{"doctype":"skill","skillid":"S3","level":3,"name":"Go","group":{"id":"G2"}}`)},
+	{Key: "S1", Value: []byte(`{"doctype":"skill","skillid":"S1","level":1,"name":"Java","group":{"id":"G1"}}`)},
+	{Key: "S2", Value: []byte(`{"doctype":"skill","skillid":"S2","level":2,"name":"Rust","group":{"id":"G1"}}`)},
+	{Key: "T1", Value: []byte(`{"doctype":"track","trackid":"T1","version":"1"}`)},
+	{Key: "RAW", Value: []byte(`not json`)},
+}
+
+func keys(results []Document) string {
+	var items []string
+	for _, result := range results {
+		items = append(items, result.Key)
+	}
+
+	return strings.Join(items, ",")
+}
+
+func TestExecute(t *testing.T) {
+	cases := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"equality orders by key", `{"selector":{"doctype":"skill"}}`, "S1,S2,S3"},
+		{"$eq", `{"selector":{"skillid":{"$eq":"S2"}}}`, "S2"},
+		{"$ne", `{"selector":{"doctype":"skill","level":{"$ne":2}}}`, "S1,S3"},
+		{"$gt", `{"selector":{"level":{"$gt":1}}}`, "S2,S3"},
+		{"$lt", `{"selector":{"level":{"$lt":3}}}`, "S1,S2"},
+		{"$gte and $lte", `{"selector":{"level":{"$gte":2,"$lte":2}}}`, "S2"},
+		{"$in", `{"selector":{"skillid":{"$in":["S1","S3","S9"]}}}`, "S1,S3"},
+		{"$or", `{"selector":{"$or":[{"skillid":"S1"},{"trackid":"T1"}]}}`, "S1,T1"},
+		{"$and", `{"selector":{"$and":[{"doctype":"skill"},{"level":{"$gt":1}}]}}`, "S2,S3"},
+		{"$or inside $and", `{"selector":{"doctype":"skill","$or":[{"level":1},{"level":3}]}}`, "S1,S3"},
+		{"$exists", `{"selector":{"trackid":{"$exists":true}}}`, "T1"},
+		{"missing field", `{"selector":{"trackid":{"$exists":false},"doctype":"track"}}`, ""},
+		{"nested selector", `{"selector":{"group":{"id":"G1"}}}`, "S1,S2"},
+		{"dotted field", `{"selector":{"group.id":"G2"}}`, "S3"},
+		{"sort descending", `{"selector":{"doctype":"skill"},"sort":[{"level":"desc"}]}`, "S3,S2,S1"},
+		{"sort ascending by name", `{"selector":{"doctype":"skill"},"sort":["name"]}`, "S3,S1,S2"},
+		{"limit", `{"selector":{"doctype":"skill"},"sort":[{"level":"desc"}],"limit":2}`, "S3,S2"},
+		{"skip", `{"selector":{"doctype":"skill"},"skip":2}`, "S3"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			results, err := Execute(c.query, docs)
+			if err != nil {
+				t.Fatalf("Execute failed: %s", err)
+			}
+
+			if keys(results) != c.expected {
+				t.Errorf("Expected %s but got %s", c.expected, keys(results))
+			}
+		})
+	}
+}
+
+func TestExecuteFields(t *testing.T) {
+	results, err := Execute(`{"selector":{"skillid":"S1"},"fields":["skillid","group.id","unknown"]}`, docs)
+	if err != nil {
+		t.Fatalf("Execute failed: %s", err)
+	}
+
+	if len(results) != 1 || string(results[0].Value) != `{"group":{"id":"G1"},"skillid":"S1"}` {
+		t.Errorf("Unexpected projection %v", results)
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	queries := []string{
+		`not json`,
+		`{"fields":["skillid"]}`,
+		`{"selector":{"level":{"$regex":"1"}}}`,
+		`{"selector":{"$or":{"level":1}}}`,
+		`{"selector":{"level":{"$in":1}}}`,
+		`{"selector":{"doctype":"skill"},"sort":[{"level":"up"}]}`,
+	}
+
+	for _, query := range queries {
+		if _, err := Execute(query, docs); err == nil {
+			t.Errorf("Expected an error for %s", query)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package mango
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Match to check a json document against a Mango selector.
+// Fields are combined with $and, a field condition is either a value ($eq), an object of operators
+// or a nested selector of a sub object.
+func Match(selector map[string]interface{}, doc map[string]interface{}) (bool, error) {
+	for field, condition := range selector {
+		if strings.HasPrefix(field, "$") {
+			ok, err := matchCombination(field, condition, doc)
+			if err != nil || !ok {
+				return false, err
+			}
+			continue
+		}
+
+		value, found := lookup(doc, field)
+
+		ok, err := matchCondition(value, found, condition)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchCombination evaluates $and and $or, their argument is a list of selectors
+func matchCombination(operator string, argument interface{}, doc map[string]interface{}) (bool, error) {
+	selectors, ok := argument.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("The argument of %s must be an array", operator)
+	}
+
+	switch operator {
+	case "$and":
+		for _, item := range selectors {
+			ok, err := matchSelectorItem(item, doc)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case "$or":
+		for _, item := range selectors {
+			ok, err := matchSelectorItem(item, doc)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	default:
+		return false, fmt.Errorf("Unsupported operator %s", operator)
+	}
+}
+
+func matchSelectorItem(item interface{}, doc map[string]interface{}) (bool, error) {
+	selector, ok := item.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("Invalid selector %v", item)
+	}
+
+	return Match(selector, doc)
+}
+
+// matchCondition evaluates the condition of a field
+func matchCondition(value interface{}, found bool, condition interface{}) (bool, error) {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return found && collate(value, condition) == 0, nil
+	}
+
+	for key, argument := range operators {
+		if !strings.HasPrefix(key, "$") {
+			// Nested selector, e.g. {"skill": {"level": "2"}}
+			object, _ := value.(map[string]interface{})
+			subValue, subFound := lookup(object, key)
+
+			ok, err := matchCondition(subValue, found && subFound, argument)
+			if err != nil || !ok {
+				return false, err
+			}
+			continue
+		}
+
+		ok, err := matchOperator(key, argument, value, found)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchOperator evaluates a condition operator, range operators use the CouchDB collation across types
+func matchOperator(operator string, argument interface{}, value interface{}, found bool) (bool, error) {
+	switch operator {
+	case "$eq":
+		return found && collate(value, argument) == 0, nil
+	case "$ne":
+		return found && collate(value, argument) != 0, nil
+	case "$gt":
+		return found && collate(value, argument) > 0, nil
+	case "$gte":
+		return found && collate(value, argument) >= 0, nil
+	case "$lt":
+		return found && collate(value, argument) < 0, nil
+	case "$lte":
+		return found && collate(value, argument) <= 0, nil
+	case "$exists":
+		exists, ok := argument.(bool)
+		if !ok {
+			return false, fmt.Errorf("The argument of $exists must be a boolean")
+		}
+		return found == exists, nil
+	case "$in":
+		items, ok := argument.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("The argument of $in must be an array")
+		}
+		if !found {
+			return false, nil
+		}
+		for _, item := range items {
+			if collate(value, item) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("Unsupported operator %s", operator)
+	}
+}
+
+// collate compares json values in the CouchDB order: null < false < true < numbers < strings < arrays < objects.
+// Strings are compared by bytes, CouchDB uses ICU collation.
+func collate(left interface{}, right interface{}) int {
+	leftRank, rightRank := typeRank(left), typeRank(right)
+	if leftRank != rightRank {
+		return compareInt(leftRank, rightRank)
+	}
+
+	switch l := left.(type) {
+	case bool:
+		r := right.(bool)
+		if l == r {
+			return 0
+		}
+		if !l {
+			return -1
+		}
+		return 1
+
+	case float64:
+		r := right.(float64)
+		if l < r {
+			return -1
+		}
+		if l > r {
+			return 1
+		}
+		return 0
+
+	case string:
+		return strings.Compare(l, right.(string))
+
+	case []interface{}:
+		r := right.([]interface{})
+		for i := 0; i < len(l) && i < len(r); i++ {
+			if result := collate(l[i], r[i]); result != 0 {
+				return result
+			}
+		}
+		return compareInt(len(l), len(r))
+
+	case map[string]interface{}:
+		r := right.(map[string]interface{})
+		leftKeys, rightKeys := sortedKeys(l), sortedKeys(r)
+		for i := 0; i < len(leftKeys) && i < len(rightKeys); i++ {
+			if result := strings.Compare(leftKeys[i], rightKeys[i]); result != 0 {
+				return result
+			}
+			if result := collate(l[leftKeys[i]], r[rightKeys[i]]); result != 0 {
+				return result
+			}
+		}
+		return compareInt(len(leftKeys), len(rightKeys))
+	}
+
+	return 0
+}
+
+func typeRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	case []interface{}:
+		return 4
+	default:
+		return 5
+	}
+}
+
+func compareInt(left int, right int) int {
+	if left < right {
+		return -1
+	}
+	if left > right {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
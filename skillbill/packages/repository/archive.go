@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// archiveFlags are the columns of an archived record
+type archiveFlags struct {
+	Deleted   bool   `json:"deleted"`
+	DeletedBy string `json:"deletedby"`
+	DeletedAt string `json:"deletedat"`
+}
+
+// IsArchived returns true when the record has been soft deleted
+func IsArchived(value []byte) bool {
+	var flags archiveFlags
+	if err := json.Unmarshal(value, &flags); err != nil {
+		return false
+	}
+
+	return flags.Deleted
+}
+
+// SoftDelete flags a record as deleted and keeps it on the ledger, so the records which point to it stay valid.
+// The caller and the time of the transaction are kept with the flag.
+func SoftDelete(APIstub shim.ChaincodeStubInterface, key string) error {
+	record, err := getRecord(APIstub, key)
+	if err != nil {
+		return errs.Wrapf(err, "Failed to delete %s", key)
+	}
+
+	if record[models.DeletedColumnName] == true {
+		return errs.Errorf(errs.Conflict, "Failed to delete, because the record %s is already archived.", key)
+	}
+
+	deletedBy, err := utils.GetCurrentUser(APIstub)
+	if err != nil {
+		return errs.Wrap(err, "Failed to get the current user")
+	}
+
+	deletedAt, err := utils.GetTxTime(APIstub)
+	if err != nil {
+		return errs.Wrap(err, "Failed to get the transaction time")
+	}
+
+	record[models.DeletedColumnName] = true
+	record[models.DeletedByColumnName] = deletedBy
+	record[models.DeletedAtColumnName] = deletedAt
+
+	return putRecord(APIstub, key, record)
+}
+
+// Restore brings back an archived record
+func Restore(APIstub shim.ChaincodeStubInterface, key string) error {
+	record, err := getRecord(APIstub, key)
+	if err != nil {
+		return errs.Wrapf(err, "Failed to restore %s", key)
+	}
+
+	if record[models.DeletedColumnName] != true {
+		return errs.Errorf(errs.Conflict, "Failed to restore, because the record %s is not archived.", key)
+	}
+
+	delete(record, models.DeletedColumnName)
+	delete(record, models.DeletedByColumnName)
+	delete(record, models.DeletedAtColumnName)
+
+	return putRecord(APIstub, key, record)
+}
+
+// Purge removes an archived record from the state for good, a record must be archived before it is purged
+func Purge(APIstub shim.ChaincodeStubInterface, key string) error {
+	record, err := getRecord(APIstub, key)
+	if err != nil {
+		return errs.Wrapf(err, "Failed to purge %s", key)
+	}
+
+	if record[models.DeletedColumnName] != true {
+		return errs.Errorf(errs.Conflict, "Failed to purge, because the record %s is not archived.", key)
+	}
+
+	return APIstub.DelState(key)
+}
+
+// ArchivedRecord is an archived record with its key
+type ArchivedRecord struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// GetArchived returns the archived records of a doc type
+func GetArchived(APIstub shim.ChaincodeStubInterface, docType string) ([]byte, error) {
+	records, err := GetArchivedRecords(APIstub, docType)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	for i, record := range records {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+
+		buffer.Write(record.Value)
+	}
+	buffer.WriteString("]")
+
+	return buffer.Bytes(), nil
+}
+
+// GetArchivedRecords returns the archived records of a doc type with their keys
+func GetArchivedRecords(APIstub shim.ChaincodeStubInterface, docType string) ([]ArchivedRecord, error) {
+	selector := map[string]interface{}{models.DocTypeColumnName: docType, models.DeletedColumnName: true}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	resultsIterator, err := APIstub.GetQueryResult(string(query))
+	if err != nil {
+		return nil, errs.Wrapf(err, "Failed to get the archived %s", docType)
+	}
+	defer resultsIterator.Close()
+
+	records := []ArchivedRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _, err := UpgradeDocument(queryResponse.Value)
+		if err != nil {
+			return nil, errs.Wrapf(err, "Failed to upgrade record %s", queryResponse.Key)
+		}
+
+		records = append(records, ArchivedRecord{Key: queryResponse.Key, Value: value})
+	}
+
+	return records, nil
+}
+
+func getRecord(APIstub shim.ChaincodeStubInterface, key string) (map[string]interface{}, error) {
+	value, err := GetDocument(APIstub, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, errs.Errorf(errs.NotFound, "the key %s does not exist", key)
+	}
+
+	var record map[string]interface{}
+	err = json.Unmarshal(value, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func putRecord(APIstub shim.ChaincodeStubInterface, key string, record map[string]interface{}) error {
+	data, _ := json.Marshal(record)
+
+	return PutDocument(APIstub, key, data)
+}
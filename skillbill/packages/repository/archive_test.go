@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/skillbill/models"
+)
+
+func TestIsArchived(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		archived bool
+	}{
+		{"flagged record", `{"doctype":"skill","deleted":true}`, true},
+		{"restored record", `{"doctype":"skill","deleted":false}`, false},
+		{"record without flag", `{"doctype":"skill"}`, false},
+		{"value which is not json", `abc`, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if IsArchived([]byte(c.value)) != c.archived {
+				t.Errorf("Expected archived %v for %s", c.archived, c.value)
+			}
+		})
+	}
+}
+
+func TestRestoreAndPurge(t *testing.T) {
+	stub := newMigrationStub()
+	stub.PutState("S1", []byte(`{"doctype":"skill","skillid":"S1","deleted":true,"deletedby":"admin","deletedat":"2018-06-01T00:00:00Z"}`))
+	stub.PutState("S2", []byte(`{"doctype":"skill","skillid":"S2"}`))
+
+	if err := Purge(stub, "S2"); err == nil {
+		t.Errorf("Expected an error for the purge of a record which is not archived")
+	}
+
+	if err := Restore(stub, "S1"); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	record := decode(t, stub.State["S1"])
+	if _, ok := record[models.DeletedByColumnName]; ok || IsArchived(stub.State["S1"]) {
+		t.Errorf("Expected the record restored but got %s", string(stub.State["S1"]))
+	}
+
+	if err := Restore(stub, "S1"); err == nil {
+		t.Errorf("Expected an error for the restore of a record which is not archived")
+	}
+
+	stub.PutState("S1", []byte(`{"doctype":"skill","skillid":"S1","deleted":true}`))
+	if err := Purge(stub, "S1"); err != nil || len(stub.State["S1"]) > 0 {
+		t.Errorf("Expected the archived record purged, err %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	logs "github.com/skillbill/packages/logs"
+)
+
+// FieldNamesMigration is the first schema version, the records have the columns of their schema
+const FieldNamesMigration string = "normalize-field-names"
+
+func init() {
+	for _, schema := range models.Schemas {
+		RegisterMigration(Migration{Name: FieldNamesMigration, DocType: schema.DocType, Version: 1, Upgrade: func(record map[string]interface{}) (map[string]interface{}, error) {
+			normalized, _ := normalizeRecord(record)
+			return normalized, nil
+		}})
+	}
+}
+
+// MigrateFieldNames is a one-off migration to rewrite the records whose json keys were mis-cased,
+// e.g. {"DocType":"Translation","LanguageID":"en"} is stored again as {"doctype":"translation","languageid":"en"}.
+// Only records of a registered doc type are rewritten, it returns the number of rewritten records.
+func MigrateFieldNames(APIstub shim.ChaincodeStubInterface) (int, error) {
+
+	resultsIterator, err := APIstub.GetStateByRange("", "")
+	if err != nil {
+		return 0, err
+	}
+	defer resultsIterator.Close()
+
+	count := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return count, err
+		}
+
+		value, changed := NormalizeFieldNames(queryResponse.Value)
+		if !changed {
+			continue
+		}
+
+		err = APIstub.PutState(queryResponse.Key, value)
+		if err != nil {
+			return count, errs.Wrapf(err, "Failed to migrate record %s", queryResponse.Key)
+		}
+
+		logs.LogInfo("Migrated the field names of record " + queryResponse.Key)
+		count = count + 1
+	}
+
+	return count, nil
+}
+
+// NormalizeFieldNames renames the keys of a json record to the columns of its schema, matched case insensitive.
+// Keys which are not in the schema are kept.
+func NormalizeFieldNames(value []byte) ([]byte, bool) {
+	var record map[string]interface{}
+	if json.Unmarshal(value, &record) != nil {
+		return value, false
+	}
+
+	normalized, changed := normalizeRecord(record)
+	if !changed {
+		return value, false
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return value, false
+	}
+
+	return data, true
+}
+
+// findSchema returns the schema of a record, the doctype column and its value are matched case insensitive
+func findSchema(record map[string]interface{}) (models.Schema, bool) {
+	docType, found := recordDocType(record)
+	if !found {
+		return models.Schema{}, false
+	}
+
+	return models.GetSchema(docType)
+}
+
+func normalizeRecord(record map[string]interface{}) (map[string]interface{}, bool) {
+	schema, found := findSchema(record)
+	if !found {
+		return record, false
+	}
+
+	changed := false
+	normalized := map[string]interface{}{}
+	for key, item := range record {
+		column, ok := schema.Column(key)
+		if !ok {
+			column = key
+		}
+
+		if column != key {
+			changed = true
+			// A key which is already canonical wins over a mis-cased duplicate
+			if _, exists := record[column]; exists {
+				continue
+			}
+		}
+
+		normalized[column] = item
+	}
+
+	if normalized[models.DocTypeColumnName] != schema.DocType {
+		normalized[models.DocTypeColumnName] = schema.DocType
+		changed = true
+	}
+
+	return normalized, changed
+}
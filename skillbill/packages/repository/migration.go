@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	logs "github.com/skillbill/packages/logs"
+)
+
+// Migration upgrades the documents of a doc type to a schema version.
+// Documents stored before the schema versions were introduced have the version 0.
+type Migration struct {
+	Name    string
+	DocType string
+	// Version is the schema version of the upgraded documents
+	Version int
+	Upgrade func(record map[string]interface{}) (map[string]interface{}, error)
+}
+
+// MigrationRecord is stored once a migration has run over every document of the chaincode
+type MigrationRecord struct {
+	Name    string `json:"name"`
+	Target  string `json:"target"`
+	Version int    `json:"version"`
+	TxID    string `json:"txid"`
+	DocType string `json:"doctype"`
+}
+
+// MigrationState is the bookmark of the batch migration in progress
+type MigrationState struct {
+	Bookmark string `json:"bookmark"`
+	Migrated int    `json:"migrated"`
+	DocType  string `json:"doctype"`
+}
+
+// MigrationResult is the outcome of a batch, the bookmark is empty when every document has been read
+type MigrationResult struct {
+	Migrated  int    `json:"migrated"`
+	Bookmark  string `json:"bookmark"`
+	Completed bool   `json:"completed"`
+}
+
+// lastKey is the end of a range query over every simple key
+var lastKey = string(utf8.MaxRune)
+
+var migrations []Migration
+
+// RegisterMigration adds a migration to the registry, it is called from the init of a package
+func RegisterMigration(migration Migration) {
+	migrations = append(migrations, migration)
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// GetMigrations returns the registered migrations ordered by version
+func GetMigrations() []Migration {
+	return migrations
+}
+
+// CurrentSchemaVersion returns the schema version of the documents written by this code
+func CurrentSchemaVersion(docType string) int {
+	version := 0
+	for _, migration := range migrations {
+		if strings.EqualFold(migration.DocType, docType) && migration.Version > version {
+			version = migration.Version
+		}
+	}
+
+	return version
+}
+
+// UpgradeDocument runs the pending migrations of a document and stamps its schema version.
+// Values which are not json objects with a doc type are returned unchanged.
+func UpgradeDocument(value []byte) ([]byte, bool, error) {
+	var record map[string]interface{}
+	if json.Unmarshal(value, &record) != nil {
+		return value, false, nil
+	}
+
+	docType, found := recordDocType(record)
+	if !found {
+		return value, false, nil
+	}
+
+	version := recordSchemaVersion(record)
+	if version >= CurrentSchemaVersion(docType) {
+		return value, false, nil
+	}
+
+	for _, migration := range migrations {
+		if !strings.EqualFold(migration.DocType, docType) || migration.Version <= version {
+			continue
+		}
+
+		upgraded, err := migration.Upgrade(record)
+		if err != nil {
+			return value, false, errs.Wrapf(err, "Failed to run migration %s", migration.Name)
+		}
+
+		record = upgraded
+		version = migration.Version
+	}
+
+	record[models.SchemaVersionColumnName] = version
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return value, false, err
+	}
+
+	return data, true, nil
+}
+
+// StampSchemaVersion sets the current schema version of a document before it is written.
+// A document without version was built by this code and has the current schema, an older one is upgraded.
+func StampSchemaVersion(value []byte) ([]byte, error) {
+	var record map[string]interface{}
+	if json.Unmarshal(value, &record) != nil {
+		return value, nil
+	}
+
+	docType, found := recordDocType(record)
+	if !found {
+		return value, nil
+	}
+
+	if _, exists := record[models.SchemaVersionColumnName]; exists {
+		upgraded, _, err := UpgradeDocument(value)
+		return upgraded, err
+	}
+
+	// The field is appended to keep the json of the document as it was marshalled
+	trimmed := bytes.TrimSuffix(bytes.TrimSpace(value), []byte("}"))
+	stamped := string(trimmed) + `,"` + models.SchemaVersionColumnName + `":` + strconv.Itoa(CurrentSchemaVersion(docType)) + "}"
+
+	return []byte(stamped), nil
+}
+
+// PutDocument stores a document with its schema version
+func PutDocument(APIstub shim.ChaincodeStubInterface, key string, value []byte) error {
+	data, err := StampSchemaVersion(value)
+	if err != nil {
+		return err
+	}
+
+	return APIstub.PutState(key, data)
+}
+
+// CreateDocument stores a new document, it fails with ALREADY_EXISTS when a record, an archived one too, has the key.
+// It keeps an id derived from the content of a record from overwriting a stored record,
+// the records written by the transaction itself are not read back, so their content must differ.
+func CreateDocument(APIstub shim.ChaincodeStubInterface, key string, value []byte) error {
+	existing, err := APIstub.GetState(key)
+	if err != nil {
+		return errs.Wrapf(err, "Failed to create record %s", key)
+	}
+
+	if len(existing) > 0 {
+		return errs.Errorf(errs.AlreadyExists, "Failed to create record %s, the key exists already", key)
+	}
+
+	return PutDocument(APIstub, key, value)
+}
+
+// GetDocument reads a document and upgrades it lazily to the current schema version.
+// The upgrade is not written back, the document is stored upgraded when it is saved again.
+func GetDocument(APIstub shim.ChaincodeStubInterface, key string) ([]byte, error) {
+	value, err := APIstub.GetState(key)
+	if err != nil || len(value) == 0 {
+		return value, err
+	}
+
+	data, _, err := UpgradeDocument(value)
+
+	return data, err
+}
+
+// Migrate upgrades a batch of documents of the chaincode and writes them back.
+// The batch starts at the bookmark, or where the previous batch stopped when it is empty.
+// Once every document has been read the registered migrations are recorded as run.
+func Migrate(APIstub shim.ChaincodeStubInterface, bookmark string, pageSize int) (MigrationResult, error) {
+	result := MigrationResult{}
+
+	if pageSize < 1 {
+		return result, errs.New(errs.InvalidArgument, "The page size must be greater than 0")
+	}
+
+	state, err := getMigrationState(APIstub)
+	if err != nil {
+		return result, err
+	}
+
+	if bookmark == "" {
+		bookmark = state.Bookmark
+	}
+
+	resultsIterator, err := APIstub.GetStateByRange(bookmark, lastKey)
+	if err != nil {
+		return result, err
+	}
+	defer resultsIterator.Close()
+
+	read := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return result, err
+		}
+
+		if read == pageSize {
+			result.Bookmark = queryResponse.Key
+			break
+		}
+		read = read + 1
+
+		value, changed, err := UpgradeDocument(queryResponse.Value)
+		if err != nil {
+			return result, errs.Wrapf(err, "Failed to migrate record %s", queryResponse.Key)
+		}
+
+		if !changed {
+			continue
+		}
+
+		err = APIstub.PutState(queryResponse.Key, value)
+		if err != nil {
+			return result, errs.Wrapf(err, "Failed to migrate record %s", queryResponse.Key)
+		}
+
+		result.Migrated = result.Migrated + 1
+	}
+
+	state.Bookmark = result.Bookmark
+	state.Migrated = state.Migrated + result.Migrated
+
+	if result.Bookmark == "" {
+		result.Completed = true
+		logs.LogInfo("Migrated " + strconv.Itoa(state.Migrated) + " records")
+
+		err = recordMigrations(APIstub)
+		if err != nil {
+			return result, err
+		}
+
+		state = MigrationState{DocType: models.MigrationStateDocType}
+	}
+
+	return result, putMigrationState(APIstub, state)
+}
+
+// GetMigrationRecords returns the migrations which have run over every document of the chaincode
+func GetMigrationRecords(APIstub shim.ChaincodeStubInterface) ([]MigrationRecord, error) {
+	records := []MigrationRecord{}
+
+	resultsIterator, err := APIstub.GetStateByPartialCompositeKey(models.MigrationDocType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record MigrationRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// recordMigrations stores a record of every registered migration which has not been recorded yet
+func recordMigrations(APIstub shim.ChaincodeStubInterface) error {
+	for _, migration := range migrations {
+		key, err := APIstub.CreateCompositeKey(models.MigrationDocType, []string{strings.ToLower(migration.DocType), strconv.Itoa(migration.Version)})
+		if err != nil {
+			return err
+		}
+
+		existing, err := APIstub.GetState(key)
+		if err != nil {
+			return err
+		}
+
+		if len(existing) > 0 {
+			continue
+		}
+
+		record := MigrationRecord{Name: migration.Name, Target: migration.DocType, Version: migration.Version, TxID: APIstub.GetTxID(), DocType: models.MigrationDocType}
+		data, _ := json.Marshal(record)
+
+		err = APIstub.PutState(key, data)
+		if err != nil {
+			return errs.Wrapf(err, "Failed to record migration %s", migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func getMigrationState(APIstub shim.ChaincodeStubInterface) (MigrationState, error) {
+	state := MigrationState{DocType: models.MigrationStateDocType}
+
+	key, err := APIstub.CreateCompositeKey(models.MigrationStateDocType, []string{})
+	if err != nil {
+		return state, err
+	}
+
+	data, err := APIstub.GetState(key)
+	if err != nil || len(data) == 0 {
+		return state, err
+	}
+
+	err = json.Unmarshal(data, &state)
+
+	return state, err
+}
+
+func putMigrationState(APIstub shim.ChaincodeStubInterface, state MigrationState) error {
+	key, err := APIstub.CreateCompositeKey(models.MigrationStateDocType, []string{})
+	if err != nil {
+		return err
+	}
+
+	data, _ := json.Marshal(state)
+
+	return APIstub.PutState(key, data)
+}
+
+// DocTypeOf returns the doc type of a json document
+func DocTypeOf(value []byte) (string, bool) {
+	var record map[string]interface{}
+	if json.Unmarshal(value, &record) != nil {
+		return "", false
+	}
+
+	return recordDocType(record)
+}
+
+// recordDocType returns the doc type of a record, the doctype column is matched case insensitive
+func recordDocType(record map[string]interface{}) (string, bool) {
+	for key, value := range record {
+		docType, ok := value.(string)
+		if ok && strings.EqualFold(key, models.DocTypeColumnName) {
+			return docType, true
+		}
+	}
+
+	return "", false
+}
+
+// recordSchemaVersion returns the schema version of a record, 0 when it has none
+func recordSchemaVersion(record map[string]interface{}) int {
+	for key, value := range record {
+		if !strings.EqualFold(key, models.SchemaVersionColumnName) {
+			continue
+		}
+
+		switch version := value.(type) {
+		case float64:
+			return int(version)
+		case string:
+			number, _ := strconv.Atoi(version)
+			return number
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+)
+
+func init() {
+	RegisterMigration(Migration{Name: "add-level", DocType: "testdoc", Version: 2, Upgrade: func(record map[string]interface{}) (map[string]interface{}, error) {
+		record["level"] = "1"
+		return record, nil
+	}})
+}
+
+func newMigrationStub() *shim.MockStub {
+	stub := shim.NewMockStub("repository", nil)
+	stub.MockTransactionStart("tx1")
+
+	return stub
+}
+
+func decode(t *testing.T, value []byte) map[string]interface{} {
+	var record map[string]interface{}
+	if err := json.Unmarshal(value, &record); err != nil {
+		t.Fatalf("Invalid json %s", string(value))
+	}
+
+	return record
+}
+
+func TestUpgradeDocument(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		changed  bool
+		expected map[string]interface{}
+	}{
+		{"renames the columns of a record without version", `{"DocType":"Translation","LanguageID":"en"}`, true,
+			map[string]interface{}{"doctype": "translation", "languageid": "en", "schemaversion": float64(1)}},
+		{"runs every pending migration", `{"doctype":"testdoc","schemaversion":0}`, true,
+			map[string]interface{}{"doctype": "testdoc", "level": "1", "schemaversion": float64(2)}},
+		{"keeps a current record", `{"doctype":"testdoc","schemaversion":2}`, false, nil},
+		{"keeps a record without doc type", `{"groupid":"G1"}`, false, nil},
+		{"keeps a value which is not json", `abc`, false, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			value, changed, err := UpgradeDocument([]byte(c.value))
+			if err != nil {
+				t.Fatalf("Unexpected error %s", err)
+			}
+
+			if changed != c.changed {
+				t.Fatalf("Expected changed %v but got %s", c.changed, string(value))
+			}
+
+			if !changed {
+				if string(value) != c.value {
+					t.Errorf("Expected the record unchanged but got %s", string(value))
+				}
+				return
+			}
+
+			record := decode(t, value)
+			for key, expected := range c.expected {
+				if record[key] != expected {
+					t.Errorf("Expected %s %v but got %s", key, expected, string(value))
+				}
+			}
+		})
+	}
+}
+
+func TestStampSchemaVersion(t *testing.T) {
+	value, err := StampSchemaVersion([]byte(`{"roleid":"R1","rolename":"Users","doctype":"role"}`))
+	if err != nil || string(value) != `{"roleid":"R1","rolename":"Users","doctype":"role","schemaversion":1}` {
+		t.Errorf("Expected the current version appended but got %s, err %v", string(value), err)
+	}
+
+	value, err = StampSchemaVersion([]byte(`{"doctype":"testdoc","schemaversion":1}`))
+	if err != nil || decode(t, value)["level"] != "1" {
+		t.Errorf("Expected the old record upgraded but got %s, err %v", string(value), err)
+	}
+}
+
+func TestMigrateInBatches(t *testing.T) {
+	stub := newMigrationStub()
+	stub.PutState("A", []byte(`{"DocType":"Role","RoleID":"A"}`))
+	stub.PutState("B", []byte(`{"doctype":"testdoc"}`))
+	stub.PutState("C", []byte(`{"doctype":"role","roleid":"C","schemaversion":1}`))
+
+	result, err := Migrate(stub, "", 2)
+	if err != nil || result.Completed || result.Migrated != 2 || result.Bookmark != "C" {
+		t.Fatalf("Unexpected first batch %+v, err %v", result, err)
+	}
+
+	records, _ := GetMigrationRecords(stub)
+	if len(records) != 0 {
+		t.Errorf("Expected no migration recorded before the last batch but got %+v", records)
+	}
+
+	// The bookmark of the previous batch is resumed
+	result, err = Migrate(stub, "", 2)
+	if err != nil || !result.Completed || result.Migrated != 0 || result.Bookmark != "" {
+		t.Fatalf("Unexpected last batch %+v, err %v", result, err)
+	}
+
+	if record := decode(t, stub.State["A"]); record[models.RoleIDColumnName] != "A" || record[models.SchemaVersionColumnName] != float64(1) {
+		t.Errorf("Expected the record A migrated but got %s", string(stub.State["A"]))
+	}
+
+	records, err = GetMigrationRecords(stub)
+	if err != nil || len(records) != len(GetMigrations()) {
+		t.Errorf("Expected every migration recorded but got %+v, err %v", records, err)
+	}
+}
+
+func TestMigrateWithInvalidPageSize(t *testing.T) {
+	if _, err := Migrate(newMigrationStub(), "", 0); err == nil {
+		t.Errorf("Expected an error for the page size 0")
+	}
+}
+
+func TestCreateDocument(t *testing.T) {
+	stub := newMigrationStub()
+
+	if err := CreateDocument(stub, "A", []byte(`{"doctype":"testdoc"}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if record := decode(t, stub.State["A"]); record[models.SchemaVersionColumnName] != float64(2) {
+		t.Errorf("Expected the record stored with its schema version but got %s", string(stub.State["A"]))
+	}
+
+	if err := CreateDocument(stub, "A", []byte(`{"doctype":"testdoc","level":"2"}`)); !errs.Is(err, errs.AlreadyExists) {
+		t.Errorf("Expected the existing key to be rejected but got %v", err)
+	}
+
+	if record := decode(t, stub.State["A"]); record["level"] != nil {
+		t.Errorf("Expected the record A kept but got %s", string(stub.State["A"]))
+	}
+}
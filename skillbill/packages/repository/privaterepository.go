@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// PrivateRepo to store data into a private data collection
+type PrivateRepo struct {
+	Collection string
+}
+
+// InitPrivateRepo to create Repo for a collection
+func InitPrivateRepo(collection string) IPrivateRepo {
+	return PrivateRepo{Collection: collection}
+}
+
+// GetByKey is get private entity by key
+func (r PrivateRepo) GetByKey(APIstub shim.ChaincodeStubInterface, key string) ([]byte, error) {
+
+	value, err := APIstub.GetPrivateData(r.Collection, key)
+
+	if err != nil {
+		return nil, errs.Wrap(err, "Failed to get private record " + key)
+	}
+
+	if len(string(value)) == 0 {
+		return nil, errs.New(errs.NotFound, "The private record has been not found.")
+	}
+
+	return value, nil
+}
+
+// Save is store data into the collection and return the hash to keep on the public state
+func (r PrivateRepo) Save(APIstub shim.ChaincodeStubInterface, key string, value []byte) (string, error) {
+	err := APIstub.PutPrivateData(r.Collection, key, value)
+
+	return utils.HashData(value), err
+}
+
+// Delete is remove data in the collection
+func (r PrivateRepo) Delete(APIstub shim.ChaincodeStubInterface, key string) error {
+
+	if len(key) < 1 {
+		return errs.New(errs.InvalidArgument, "Entity Ids are empty. Please specify the entity id to delete.")
+	}
+
+	return APIstub.DelPrivateData(r.Collection, key)
+}
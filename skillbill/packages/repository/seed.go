@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+)
+
+// Seed is a record a chaincode needs to work, e.g. a feature or a role, with the key it is stored at
+type Seed struct {
+	Key    string
+	Record interface{}
+}
+
+// UpsertSeeds writes the seeds which are missing, archived or whose fields differ from the ledger, so seeding again changes nothing.
+// The fields of a seed are merged into the stored record and the other fields are kept, an archived seed is restored.
+// Returns the number of records written.
+func UpsertSeeds(APIstub shim.ChaincodeStubInterface, seeds []Seed) (int, error) {
+	written := 0
+
+	for _, seed := range seeds {
+		data, err := json.Marshal(seed.Record)
+		if err != nil {
+			return written, err
+		}
+
+		var fields map[string]interface{}
+		json.Unmarshal(data, &fields)
+
+		stored, err := GetDocument(APIstub, seed.Key)
+		if err != nil {
+			return written, err
+		}
+
+		record := map[string]interface{}{}
+		if len(stored) > 0 {
+			json.Unmarshal(stored, &record)
+		}
+
+		changed := len(stored) == 0
+		for column, value := range fields {
+			if !reflect.DeepEqual(record[column], value) {
+				record[column] = value
+				changed = true
+			}
+		}
+
+		for _, column := range []string{models.DeletedColumnName, models.DeletedByColumnName, models.DeletedAtColumnName} {
+			if _, ok := record[column]; ok {
+				delete(record, column)
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		data, _ = json.Marshal(record)
+		if err := PutDocument(APIstub, seed.Key, data); err != nil {
+			return written, err
+		}
+
+		written++
+	}
+
+	return written, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+)
+
+type seedRecord struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	DocType string `json:"doctype"`
+}
+
+func TestUpsertSeeds(t *testing.T) {
+	stub := newMigrationStub()
+	seeds := []Seed{
+		{Key: "S1", Record: seedRecord{ID: "S1", Name: "first", DocType: "testdoc"}},
+		{Key: "S2", Record: seedRecord{ID: "S2", Name: "second", DocType: "testdoc"}},
+	}
+
+	if written, err := UpsertSeeds(stub, seeds); err != nil || written != 2 {
+		t.Fatalf("Expected the seeds written but got %d, err %v", written, err)
+	}
+
+	if written, err := UpsertSeeds(stub, seeds); err != nil || written != 0 {
+		t.Errorf("Expected unchanged seeds not written again but got %d, err %v", written, err)
+	}
+
+	stub.PutState("S2", []byte(`{"id":"S2","name":"renamed","doctype":"testdoc","deleted":true,"schemaversion":2}`))
+
+	if written, err := UpsertSeeds(stub, seeds); err != nil || written != 1 {
+		t.Fatalf("Expected the changed seed written but got %d, err %v", written, err)
+	}
+
+	record := decode(t, stub.State["S2"])
+	if record["name"] != "second" || IsArchived(stub.State["S2"]) || record["schemaversion"] == nil {
+		t.Errorf("Expected the seed merged into the record and restored but got %s", string(stub.State["S2"]))
+	}
+
+	stub.PutState("S1", []byte(`{"id":"S1","name":"first","doctype":"testdoc","deleted":true,"deletedby":"admin","deletedat":"2018-06-01T00:00:00Z"}`))
+
+	if written, err := UpsertSeeds(stub, seeds); err != nil || written != 1 || IsArchived(stub.State["S1"]) {
+		t.Errorf("Expected the archived seed restored but got %d, err %v: %s", written, err, string(stub.State["S1"]))
+	}
+}
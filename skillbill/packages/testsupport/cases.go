@@ -0,0 +1,151 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/errs"
+)
+
+// InvokeCase is a row of a table-driven chaincode test.
+// The status shim.ERROR expects any failed response, a code expects a failed response with the code.
+type InvokeCase struct {
+	Name     string
+	Setup    func(stub *Stub)
+	Function string
+	Args     []string
+	Status   int32
+	Code     errs.Code
+	Check    func(t *testing.T, stub *Stub, res pb.Response)
+}
+
+// RunInvokeCases runs every case as a sub test on a new stub
+func RunInvokeCases(t *testing.T, newStub func(t *testing.T) *Stub, cases []InvokeCase) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			stub := newStub(t)
+			if c.Setup != nil {
+				c.Setup(stub)
+			}
+
+			res := stub.Invoke(c.Function, c.Args...)
+
+			if c.Status == shim.ERROR || c.Code != "" {
+				if res.Status < shim.ERRORTHRESHOLD {
+					t.Fatalf("%s: expected a failure but got status %d", c.Function, res.Status)
+				}
+
+				if code := errs.CodeOf(errs.FromResponse(res)); c.Code != "" && code != c.Code {
+					t.Fatalf("%s: expected the code %s but got %s, message: %s", c.Function, c.Code, code, res.Message)
+				}
+			} else {
+				status := c.Status
+				if status == 0 {
+					status = shim.OK
+				}
+
+				if res.Status != status {
+					t.Fatalf("%s: expected status %d but got %d, message: %s", c.Function, status, res.Status, res.Message)
+				}
+			}
+
+			if c.Check != nil {
+				c.Check(t, stub, res)
+			}
+		})
+	}
+}
+
+// InitArgs are the arguments of Init which trust the CA of Org1MSP to issue the certificate attributes
+var InitArgs = []string{"init", "Org1MSP"}
+
+// MustInit calls Init of the chaincode and fails the test on error
+func MustInit(t *testing.T, stub *Stub, args ...string) {
+	res := stub.Init(args...)
+	if res.Status != shim.OK {
+		t.Fatalf("Init of %s failed: %s", stub.Name, res.Message)
+	}
+}
+
+// MustInvoke calls a function of the chaincode and fails the test on error
+func MustInvoke(t *testing.T, stub *Stub, function string, args ...string) pb.Response {
+	res := stub.Invoke(function, args...)
+	if res.Status != shim.OK {
+		t.Fatalf("%s of %s failed: %s", function, stub.Name, res.Message)
+	}
+
+	return res
+}
+
+// ExpectPayload checks the payload of the response
+func ExpectPayload(expected string) func(t *testing.T, stub *Stub, res pb.Response) {
+	return func(t *testing.T, stub *Stub, res pb.Response) {
+		if string(res.Payload) != expected {
+			t.Errorf("Expected payload %s but got %s", expected, string(res.Payload))
+		}
+	}
+}
+
+// ExpectCount checks the number of entries of a json array payload
+func ExpectCount(expected int) func(t *testing.T, stub *Stub, res pb.Response) {
+	return func(t *testing.T, stub *Stub, res pb.Response) {
+		var items []interface{}
+		if err := json.Unmarshal(res.Payload, &items); err != nil {
+			t.Fatalf("Expected a json array but got %s", string(res.Payload))
+		}
+
+		if len(items) != expected {
+			t.Errorf("Expected %d items but got %d: %s", expected, len(items), string(res.Payload))
+		}
+	}
+}
+
+// ExpectState checks a key exists in the state of the stub
+func ExpectState(key string) func(t *testing.T, stub *Stub, res pb.Response) {
+	return func(t *testing.T, stub *Stub, res pb.Response) {
+		if len(stub.State[key]) == 0 {
+			t.Errorf("Expected the key %s in the state", key)
+		}
+	}
+}
+
+// ExpectNoState checks a key does not exist in the state of the stub
+func ExpectNoState(key string) func(t *testing.T, stub *Stub, res pb.Response) {
+	return func(t *testing.T, stub *Stub, res pb.Response) {
+		if len(stub.State[key]) != 0 {
+			t.Errorf("Expected the key %s to be removed from the state", key)
+		}
+	}
+}
+
+// ExpectPayloadState checks the payload is a key of the state, e.g. the id of a created entity
+func ExpectPayloadState() func(t *testing.T, stub *Stub, res pb.Response) {
+	return func(t *testing.T, stub *Stub, res pb.Response) {
+		if len(res.Payload) == 0 || len(stub.State[string(res.Payload)]) == 0 {
+			t.Errorf("Expected the created key %s in the state", string(res.Payload))
+		}
+	}
+}
+
+// ExpectEvent checks the last event set on the stub has the name, the events of the setup are skipped
+func ExpectEvent(name string) func(t *testing.T, stub *Stub, res pb.Response) {
+	return func(t *testing.T, stub *Stub, res pb.Response) {
+		var event *pb.ChaincodeEvent
+		for len(stub.ChaincodeEventsChannel) > 0 {
+			event = <-stub.ChaincodeEventsChannel
+		}
+
+		if event == nil || event.EventName != name {
+			t.Errorf("Expected the event %s but got %v", name, event)
+		}
+	}
+}
+
+// PutJSON to store an entity as json into the state, to prepare a test
+func PutJSON(stub *Stub, key string, value interface{}) {
+	data, _ := json.Marshal(value)
+	stub.PutRecord(key, data)
+}
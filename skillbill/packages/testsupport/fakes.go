@@ -0,0 +1,208 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+)
+
+// Handler handles a function of a fake chaincode
+type Handler func(stub shim.ChaincodeStubInterface, args []string) pb.Response
+
+// FakeChaincode is a peer chaincode with canned handlers, to be called through InvokeChaincode.
+// The chaincodes are main packages and can not be imported into the tests of another chaincode.
+type FakeChaincode struct {
+	Handlers map[string]Handler
+	Calls    []string
+}
+
+// NewFakeChaincode is constructor
+func NewFakeChaincode() *FakeChaincode {
+	return &FakeChaincode{Handlers: map[string]Handler{}}
+}
+
+// On to set the handler of a function
+func (f *FakeChaincode) On(function string, handler Handler) *FakeChaincode {
+	f.Handlers[function] = handler
+	return f
+}
+
+// Init method is called when the fake chaincode is instantiated
+func (f *FakeChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+// Invoke routes to the handler of the function and records the call
+func (f *FakeChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	f.Calls = append(f.Calls, function)
+
+	handler, ok := f.Handlers[function]
+	if !ok {
+		return shim.Error("Invalid Smart Contract function name: " + function)
+	}
+
+	return handler(stub, args)
+}
+
+// WithReferences adds the functions of the integrity layer, every lookup returns the keys
+func (f *FakeChaincode) WithReferences(keys ...string) *FakeChaincode {
+	return f.
+		On("GetReferences", ReturnsJSON(append([]string{}, keys...))).
+		On("DeleteReferences", Returns(nil)).
+		On("ClearReferences", Returns(nil))
+}
+
+// Called returns true when the function has been invoked
+func (f *FakeChaincode) Called(function string) bool {
+	for _, call := range f.Calls {
+		if call == function {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns is a handler with a fixed payload
+func Returns(payload []byte) Handler {
+	return func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+		return shim.Success(payload)
+	}
+}
+
+// ReturnsJSON is a handler with a fixed json payload
+func ReturnsJSON(value interface{}) Handler {
+	data, _ := json.Marshal(value)
+	return Returns(data)
+}
+
+// Fails is a handler returning an error
+func Fails(message string) Handler {
+	return func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+		return shim.Error(message)
+	}
+}
+
+// NewSecurityFake is a security chaincode knowing the users, every registered user can login and access every feature and user,
+// no user has a role besides the one they are registered with
+func NewSecurityFake(users ...models.User) *FakeChaincode {
+	findUser := func(stub shim.ChaincodeStubInterface) (models.User, bool) {
+		mspID, err := cid.GetMSPID(stub)
+		if err != nil {
+			return models.User{}, false
+		}
+
+		cert, err := cid.GetX509Certificate(stub)
+		if err != nil {
+			return models.User{}, false
+		}
+
+		// A user registered without MSP ID is found by the common name only
+		for _, user := range users {
+			if user.ADLogin == cert.Subject.CommonName && (user.MSPID == "" || user.MSPID == mspID) {
+				return user, true
+			}
+		}
+
+		return models.User{}, false
+	}
+
+	return NewFakeChaincode().
+		On("ValidateLogin", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			_, found := findUser(stub)
+			return shim.Success([]byte(strconv.FormatBool(found)))
+		}).
+		On("CheckUserPermission", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			_, found := findUser(stub)
+			return shim.Success([]byte(strconv.FormatBool(found)))
+		}).
+		On("CheckUserAccess", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			_, found := findUser(stub)
+			return shim.Success([]byte(strconv.FormatBool(found)))
+		}).
+		On("GetCurrentUser", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			user, found := findUser(stub)
+			if !found {
+				return errs.Fail(errs.NotFound, "Could not find the current user")
+			}
+
+			data, _ := json.Marshal(user)
+			return shim.Success(data)
+		}).
+		On("IsCallerEnabled", Returns([]byte("true"))).
+		On("GetCurrentRoles", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			user, found := findUser(stub)
+			if !found {
+				return errs.Fail(errs.NotFound, "Could not find the current user")
+			}
+
+			data, _ := json.Marshal([]string{user.RoleID})
+			return shim.Success(data)
+		}).
+		On("UserExists", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			for _, user := range users {
+				if len(args) > 0 && user.ADLogin == args[0] {
+					return shim.Success([]byte("true"))
+				}
+			}
+
+			return shim.Success([]byte("false"))
+		}).
+		On("GetScopedRoles", ReturnsJSON([]string{})).
+		On("GetAllUsers", ReturnsJSON(users))
+}
+
+// NewRoleFake is a role chaincode with the features assigned to roles
+func NewRoleFake(roleFeatures ...models.RoleFeature) *FakeChaincode {
+	return NewFakeChaincode().
+		On("getFeaturesByRoleIDs", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			result := []models.RoleFeature{}
+			if len(args) == 0 {
+				return shim.Error("The args is empty, please specify the role ids.")
+			}
+
+			for _, roleID := range strings.Split(args[0], ",") {
+				for _, roleFeature := range roleFeatures {
+					if roleFeature.RoleID == roleID {
+						result = append(result, roleFeature)
+					}
+				}
+			}
+
+			data, _ := json.Marshal(result)
+			return shim.Success(data)
+		})
+}
+
+// NewSkillFake is a skill chaincode with the skills and their acceptance criteria
+func NewSkillFake(skills []models.Skill, criteria ...models.SkillAcceptanceCriteria) *FakeChaincode {
+	return NewFakeChaincode().
+		On("getByID", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			for _, skill := range skills {
+				if len(args) > 0 && skill.SkillID == args[0] {
+					data, _ := json.Marshal(skill)
+					return shim.Success(data)
+				}
+			}
+
+			return shim.Success(nil)
+		}).
+		On("getAcceptanceCriteria", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			result := []models.SkillAcceptanceCriteria{}
+			for _, item := range criteria {
+				if len(args) > 0 && item.SkillID == args[0] {
+					result = append(result, item)
+				}
+			}
+
+			data, _ := json.Marshal(result)
+			return shim.Success(data)
+		})
+}
@@ -0,0 +1,95 @@
+package testsupport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// attributeOID is the certificate extension fabric-ca stores attributes in, read by the cid library
+var attributeOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Identity is a fake enrollment certificate of a client, used as creator of the mock transactions
+type Identity struct {
+	MSPID      string
+	CommonName string
+	Attributes map[string]string
+	CertPEM    []byte
+	Creator    []byte
+	key        *ecdsa.PrivateKey
+}
+
+// NewIdentity to create a self-signed ECDSA certificate with the CommonName and the fabric-ca attributes
+func NewIdentity(mspID string, commonName string, attributes map[string]string) (*Identity, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{mspID}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	if len(attributes) > 0 {
+		value, _ := json.Marshal(map[string]interface{}{"attrs": attributes})
+		template.ExtraExtensions = []pkix.Extension{{Id: attributeOID, Value: value}}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: certPEM})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{MSPID: mspID, CommonName: commonName, Attributes: attributes, CertPEM: certPEM, Creator: creator, key: key}, nil
+}
+
+// MustNewIdentity is NewIdentity for tests, it panics on error
+func MustNewIdentity(mspID string, commonName string, attributes map[string]string) *Identity {
+	identity, err := NewIdentity(mspID, commonName, attributes)
+	if err != nil {
+		panic(err)
+	}
+
+	return identity
+}
+
+// PublicKey returns the public key as it is registered in the security chaincode (hex of PKIX)
+func (i *Identity) PublicKey() string {
+	block, _ := pem.Decode(i.CertPEM)
+	cert, _ := x509.ParseCertificate(block.Bytes)
+	bytePublicKey, _ := x509.MarshalPKIXPublicKey(cert.PublicKey)
+
+	return hex.EncodeToString(bytePublicKey)
+}
+
+// Sign returns the hex of the ASN.1 ECDSA signature of the sha256 of message, made with the key of the certificate
+func (i *Identity) Sign(message []byte) string {
+	hash := sha256.Sum256(message)
+	r, s, _ := ecdsa.Sign(rand.Reader, i.key, hash[:])
+	signature, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+
+	return hex.EncodeToString(signature)
+}
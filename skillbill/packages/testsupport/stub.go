@@ -0,0 +1,193 @@
+package testsupport
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/mango"
+)
+
+// Stub wraps shim.MockStub for chaincode tests. It acts as a fake identity, routes InvokeChaincode
+// to registered peer stubs and answers rich queries from the in-memory state.
+// The chaincode is invoked with the Stub itself, so the overridden functions are used.
+// The creator, the transient data and the private data are kept here, the MockStub of Fabric 1.x does not implement them.
+type Stub struct {
+	*shim.MockStub
+	Creator      []byte
+	TransientMap map[string][]byte
+	// PrivateState keeps the private data per collection
+	PrivateState map[string]map[string][]byte
+	cc           shim.Chaincode
+	args         [][]byte
+	peers        map[string]*Stub
+	txNumber     int
+}
+
+// NewStub is constructor, the transactions are created by the identity
+func NewStub(name string, cc shim.Chaincode, identity *Identity) *Stub {
+	s := newStub(name, cc)
+	s.SetIdentity(identity)
+
+	return s
+}
+
+func newStub(name string, cc shim.Chaincode) *Stub {
+	return &Stub{MockStub: shim.NewMockStub(name, cc), PrivateState: map[string]map[string][]byte{}, cc: cc, peers: map[string]*Stub{}}
+}
+
+// SetIdentity to change the creator of the next transactions
+func (s *Stub) SetIdentity(identity *Identity) {
+	if identity != nil {
+		s.Creator = identity.Creator
+	}
+}
+
+// SetTransient to set the transient data of the next transactions
+func (s *Stub) SetTransient(transient map[string][]byte) {
+	s.TransientMap = transient
+}
+
+// RegisterPeer to make a chaincode callable through InvokeChaincode
+func (s *Stub) RegisterPeer(name string, peer *Stub) {
+	s.peers[name] = peer
+}
+
+// MockPeer to create a stub for a peer chaincode and register it
+func (s *Stub) MockPeer(name string, cc shim.Chaincode) *Stub {
+	peer := newStub(name, cc)
+	s.RegisterPeer(name, peer)
+
+	return peer
+}
+
+// Init to call Init of the chaincode
+func (s *Stub) Init(args ...string) pb.Response {
+	return s.MockInit(s.nextTxID(), toByteArgs(args))
+}
+
+// Invoke to call a function of the chaincode
+func (s *Stub) Invoke(function string, args ...string) pb.Response {
+	return s.MockInvoke(s.nextTxID(), toByteArgs(append([]string{function}, args...)))
+}
+
+// MockInit is shim.MockStub.MockInit with the Stub as chaincode stub
+func (s *Stub) MockInit(uuid string, args [][]byte) pb.Response {
+	s.args = args
+	s.MockTransactionStart(uuid)
+	res := s.cc.Init(s)
+	s.MockTransactionEnd(uuid)
+
+	return res
+}
+
+// MockInvoke is shim.MockStub.MockInvoke with the Stub as chaincode stub
+func (s *Stub) MockInvoke(uuid string, args [][]byte) pb.Response {
+	s.args = args
+	s.MockTransactionStart(uuid)
+	res := s.cc.Invoke(s)
+	s.MockTransactionEnd(uuid)
+
+	return res
+}
+
+// GetArgs returns the arguments of the current transaction
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+// GetStringArgs returns the arguments of the current transaction as strings
+func (s *Stub) GetStringArgs() []string {
+	strargs := make([]string, 0, len(s.args))
+	for _, barg := range s.args {
+		strargs = append(strargs, string(barg))
+	}
+
+	return strargs
+}
+
+// GetFunctionAndParameters returns the function and the parameters of the current transaction
+func (s *Stub) GetFunctionAndParameters() (function string, params []string) {
+	allargs := s.GetStringArgs()
+	function = ""
+	params = []string{}
+	if len(allargs) >= 1 {
+		function = allargs[0]
+		params = allargs[1:]
+	}
+
+	return
+}
+
+// GetCreator returns the serialized identity set by SetIdentity
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.Creator, nil
+}
+
+// GetTransient returns the transient data set by SetTransient
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.TransientMap, nil
+}
+
+// GetPrivateData returns the value of the key in the collection, nil if it does not exist
+func (s *Stub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.PrivateState[collection][key], nil
+}
+
+// PutPrivateData stores the value of the key in the collection
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if _, ok := s.PrivateState[collection]; !ok {
+		s.PrivateState[collection] = map[string][]byte{}
+	}
+
+	s.PrivateState[collection][key] = value
+
+	return nil
+}
+
+// DelPrivateData removes the key from the collection
+func (s *Stub) DelPrivateData(collection string, key string) error {
+	delete(s.PrivateState[collection], key)
+
+	return nil
+}
+
+// InvokeChaincode calls a registered peer with the identity and the transient data of the caller
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	peer, ok := s.peers[chaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("Chaincode %s is not registered in the mock stub %s", chaincodeName, s.Name))
+	}
+
+	peer.Creator = s.Creator
+	peer.TransientMap = s.TransientMap
+
+	return peer.MockInvoke(s.TxID, args)
+}
+
+// GetQueryResult answers a rich query from the state of the stub
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return mango.QueryState(s.MockStub, query)
+}
+
+// PutRecord to store an entity into the state outside of a chaincode function, to prepare a test
+func (s *Stub) PutRecord(key string, value []byte) {
+	txID := s.nextTxID()
+	s.MockTransactionStart(txID)
+	s.PutState(key, value)
+	s.MockTransactionEnd(txID)
+}
+
+func (s *Stub) nextTxID() string {
+	s.txNumber = s.txNumber + 1
+	return fmt.Sprintf("%s-tx%d", s.Name, s.txNumber)
+}
+
+func toByteArgs(args []string) [][]byte {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+
+	return bargs
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"github.com/skillbill/packages/errs"
+)
+
+// SortByDependencies orders the ids so that every id comes after the ids it depends on.
+// The ids keep their order otherwise, and the dependencies on ids which are not listed are ignored.
+func SortByDependencies(ids []string, dependsOn map[string][]string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	listed := map[string]bool{}
+	for _, id := range ids {
+		listed[id] = true
+	}
+
+	sorted := []string{}
+	state := map[string]int{}
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			return errs.Errorf(errs.Conflict, "The dependencies of %s are cyclic", id)
+		case visited:
+			return nil
+		}
+
+		state[id] = visiting
+		for _, dependency := range dependsOn[id] {
+			if !listed[dependency] {
+				continue
+			}
+
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+
+		state[id] = visited
+		sorted = append(sorted, id)
+
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortByDependencies(t *testing.T) {
+	cases := []struct {
+		name      string
+		ids       []string
+		dependsOn map[string][]string
+		expected  []string
+	}{
+		{"keeps the order without dependency", []string{"B", "A"}, nil, []string{"B", "A"}},
+		{"moves the dependencies first", []string{"C", "B", "A"}, map[string][]string{"C": {"B"}, "B": {"A"}}, []string{"A", "B", "C"}},
+		{"lists a shared dependency once", []string{"B", "C", "A"}, map[string][]string{"B": {"A"}, "C": {"A"}}, []string{"A", "B", "C"}},
+		{"ignores the ids which are not listed", []string{"B"}, map[string][]string{"B": {"A"}}, []string{"B"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sorted, err := SortByDependencies(c.ids, c.dependsOn)
+			if err != nil || !reflect.DeepEqual(sorted, c.expected) {
+				t.Errorf("Expected %v but got %v, err %v", c.expected, sorted, err)
+			}
+		})
+	}
+}
+
+func TestSortByCyclicDependencies(t *testing.T) {
+	_, err := SortByDependencies([]string{"A", "B"}, map[string][]string{"A": {"B"}, "B": {"A"}})
+	if err == nil {
+		t.Errorf("Expected an error for the cyclic dependencies")
+	}
+}
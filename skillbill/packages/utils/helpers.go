@@ -1,54 +1,137 @@
-package utils
-
-import (
-	"crypto/x509"
-	"encoding/pem"
-
-	"github.com/golang/protobuf/proto"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	"github.com/hyperledger/fabric/protos/msp"
-	"golang.org/x/crypto/bcrypt"
-)
-
-// HashPassword to hash the password with cost is 10
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
-}
-
-// CheckPasswordHash compare password and hashed password match or not
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
-// GetCurrentUser get username
-func GetCurrentUser(stub shim.ChaincodeStubInterface) (string, error) {
-	cert, err := GetCreatorCert(stub)
-
-	return cert.Subject.CommonName, err
-}
-
-// GetPublicKey to get the public key of current user from their certificate
-func GetPublicKey(stub shim.ChaincodeStubInterface) (string, error) {
-	cert, err := GetCreatorCert(stub)
-
-	bytePublicKey, _ := x509.MarshalPKIXPublicKey(cert.PublicKey)
-	return string(bytePublicKey), err
-}
-
-// GetCreatorCert to get certificate
-func GetCreatorCert(stub shim.ChaincodeStubInterface) (*x509.Certificate, error) {
-	creator, err := stub.GetCreator()
-	if err != nil {
-		return nil, err
-	}
-	id := &msp.SerializedIdentity{}
-	err = proto.Unmarshal(creator, id)
-	if err != nil {
-		return nil, err
-	}
-	block, _ := pem.Decode(id.IdBytes)
-	cert, err := x509.ParseCertificate(block.Bytes)
-	return cert, err
-}
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/skillbill/packages/errs"
+)
+
+// HashData to get the hex encoded sha256 of data, used to keep private data verifiable on the public state
+func HashData(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// GetTxTime to get the timestamp of the transaction in RFC3339, unlike the clock it is the same on every peer
+func GetTxTime(stub shim.ChaincodeStubInterface) (string, error) {
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC().Format(time.RFC3339), nil
+}
+
+// GetCurrentUser get username
+func GetCurrentUser(stub shim.ChaincodeStubInterface) (string, error) {
+	cert, err := GetCreatorCert(stub)
+	if err != nil {
+		return "", err
+	}
+
+	return cert.Subject.CommonName, nil
+}
+
+// GetCreatorIdentity to get the MSP ID and the CommonName of current user, together they identify the user
+func GetCreatorIdentity(stub shim.ChaincodeStubInterface) (string, string, error) {
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", "", err
+	}
+
+	cert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return "", "", err
+	}
+
+	return mspID, cert.Subject.CommonName, nil
+}
+
+// GetPublicKey to get the public key of current user from their certificate
+func GetPublicKey(stub shim.ChaincodeStubInterface) (string, error) {
+	cert, err := GetCreatorCert(stub)
+	if err != nil {
+		return "", err
+	}
+
+	bytePublicKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	return string(bytePublicKey), err
+}
+
+// GetCreatorCertPEM to get the PEM encoded certificate of current user
+func GetCreatorCertPEM(stub shim.ChaincodeStubInterface) (string, error) {
+	cert, err := GetCreatorCert(stub)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})), nil
+}
+
+// GetCreatorCert to get certificate
+func GetCreatorCert(stub shim.ChaincodeStubInterface) (*x509.Certificate, error) {
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return nil, err
+	}
+	id := &msp.SerializedIdentity{}
+	err = proto.Unmarshal(creator, id)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(id.IdBytes)
+	if block == nil {
+		return nil, errs.New(errs.InvalidArgument, "The identity of the creator is not a PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	return cert, err
+}
+
+// VerifySignature to check the hex encoded ECDSA signature of the sha256 of message,
+// the public key is hex of PKIX as it is registered in the security chaincode
+func VerifySignature(publicKey string, message []byte, signature string) error {
+	der, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return errs.Errorf(errs.InvalidArgument, "Invalid public key, err %s", err)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return errs.Errorf(errs.InvalidArgument, "Invalid public key, err %s", err)
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return errs.New(errs.InvalidArgument, "The public key is not an ECDSA key")
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return errs.Errorf(errs.InvalidArgument, "Invalid signature, err %s", err)
+	}
+
+	var rs struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+		return errs.New(errs.InvalidArgument, "Invalid signature, it is not ASN.1 encoded")
+	}
+
+	hash := sha256.Sum256(message)
+	if !ecdsa.Verify(ecdsaKey, hash[:], rs.R, rs.S) {
+		return errs.New(errs.PermissionDenied, "The signature does not match the public key")
+	}
+
+	return nil
+}
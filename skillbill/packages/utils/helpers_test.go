@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/skillbill/packages/testsupport"
+)
+
+func TestGetCurrentUser(t *testing.T) {
+	stub := testsupport.NewStub("utils", nil, testsupport.MustNewIdentity("Org1MSP", "alice", nil))
+
+	user, err := GetCurrentUser(stub)
+	if err != nil || user != "alice" {
+		t.Errorf("Expected the user alice but got %s, err %v", user, err)
+	}
+
+	stub.Creator, _ = proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("not a certificate")})
+
+	if _, err := GetCurrentUser(stub); err == nil {
+		t.Errorf("Expected an error for a creator without certificate")
+	}
+}
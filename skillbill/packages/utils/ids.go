@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// NameID derives a stable id in the format of a guid from names, e.g. the doc type and the name of a seeded role.
+// Every peer derives the same id, unlike a random guid which makes the endorsements diverge.
+func NameID(names ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(names, "\x00")))
+
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", hash[0:4], hash[4:6], hash[6:8], hash[8:10], hash[10:16]))
+}
+
+// NewID derives a unique id from the transaction id and the content of a record, e.g. its doc type and the ids it refers to.
+// Every endorsing peer derives the same id, the content tells apart the records created by the same transaction.
+func NewID(stub shim.ChaincodeStubInterface, content ...string) string {
+	return NameID(append([]string{stub.GetTxID()}, content...)...)
+}
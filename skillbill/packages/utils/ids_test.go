@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestNewID(t *testing.T) {
+	stub := shim.NewMockStub("utils", nil)
+
+	stub.MockTransactionStart("tx1")
+	first := NewID(stub, "role", "Managers")
+	other := NewID(stub, "role", "Reviewers")
+
+	stub.MockTransactionStart("tx2")
+	next := NewID(stub, "role", "Managers")
+
+	if first != NewID(otherPeer("tx1"), "role", "Managers") {
+		t.Errorf("Expected the same id for the same transaction and content")
+	}
+
+	if first == other || first == next {
+		t.Errorf("Expected a new id for other content or another transaction but got %s, %s and %s", first, other, next)
+	}
+
+	if !regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{12}$`).MatchString(first) {
+		t.Errorf("Expected an id in the format of a guid but got %s", first)
+	}
+}
+
+// otherPeer is another peer endorsing the transaction
+func otherPeer(txID string) shim.ChaincodeStubInterface {
+	stub := shim.NewMockStub("utils", nil)
+	stub.MockTransactionStart(txID)
+
+	return stub
+}
@@ -1,13 +1,7 @@
-package main
-
-import (
-	"github.com/skillbill/services/core"
-)
-
-
-type Feature struct{
-	FeatureID		string	`json:"featureid"`
-	FeatureName		string	`json:"featurename"`
-	DocType			string	`json:"doctype"`
-	repo 			core.EntityChaincode 
+package main
+
+type Feature struct{
+	FeatureID		string	`json:"featureid"`
+	FeatureName		string	`json:"featurename"`
+	DocType			string	`json:"doctype"`
 }
\ No newline at end of file
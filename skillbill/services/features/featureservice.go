@@ -1,114 +1,168 @@
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-
-	"github.com/beevik/guid"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-	//repo "github.com/skillbill/services/core"
-)
-
-var ccInstance repo.IChaincode
-
-// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
-func (s *Feature) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	//ccInstance = repo.NewChaincode(APIstub)
-	return shim.Success(nil)
-}
-
-// Invoke method is called as a result of an application request to run the Smart Contract "Feature"
-func (s *Feature) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	
-
-	// Retrieve the requested Smart Contract function and arguments
-	function, args := APIstub.GetFunctionAndParameters()
-
-	// Route to the appropriate handler function to interact with the ledger appropriately
-	if function == "initData" {
-		return initData(APIstub)
-	} else if function == "getByQuery" {
-		return s.getByQuery(APIstub, args)
-	} else if function == "createFeature" {
-		return createFeature(APIstub, args)
-	} else if function == "deleteFeature" {
-		return deleteFeature(APIstub, args)
-	}
-
-	return shim.Error("Invalid Smart Contract function name: " + function)
-}
-
-func initData(APIstub shim.ChaincodeStubInterface) sc.Response {
-	features := []Feature{
-		Feature{ FeatureID: "025D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "SkillPlanManagement", DocType: "feature" },
-		Feature{ FeatureID: "035D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "SkillManagement", DocType: "feature" },
-		Feature{ FeatureID: "045D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "TrackManagement", DocType: "feature" },
-		Feature{ FeatureID: "055D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "MilestoneManagement", DocType: "feature" },
-		Feature{ FeatureID: "065D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "UserManagement", DocType: "feature" },
-		Feature{ FeatureID: "075D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "RoleManagement", DocType: "feature" },
-		Feature{ FeatureID: "085D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "KnowledgeGroup", DocType: "feature" },
-		Feature{ FeatureID: "095D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "FeatureManagement", DocType: "feature" },
-		Feature{ FeatureID: "0A5D1E9A-9B52-E811-AA17-FCAA145000C2", FeatureName: "TranslationManagement", DocType: "feature" },
-	}
-
-	i := 0
-	for i < len(features) {
-		data, _ := json.Marshal(features[i])
-		ccInstance.Save(APIstub, features[i].FeatureID, data)
-
-		i = i + 1
-	}
-
-	return shim.Success(nil)
-}
-
-func (s *Feature) getByQuery(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-	var result = s.repo.GetByQuery(args)
-
-	return shim.Success([]byte(result))
-}
-
-func createFeature(APIstub shim.ChaincodeStubInterface, args[] string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	var feature = Feature{ FeatureID: guid.New().StringUpper(), FeatureName: args[0], DocType: "feature" }
-	data, _ := json.Marshal(feature)
-	err := ccInstance.Save(APIstub, feature.FeatureID, data)
-
-	if err != nil {
-		return shim.Error("Failed to create feature due to " + err.Error())
-	}
-	
-	return shim.Success([]byte(feature.FeatureID))
-}
-
-func deleteFeature(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	var featureId = args[0]
-	err := ccInstance.Delete(APIstub, featureId)
-
-	if err != nil {
-		shim.Error("Failed to delete feature " + featureId + " due to " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
-
-func main() {
-	
-	err := shim.Start(new(Feature))
-	
-	if err != nil {
-		fmt.Printf("Error creating new Skill Chaincode: %s", err)
-	}
-}
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+var ccInstance repository.IRepo
+
+// integrity of the features, the role features of the role chaincode point to them
+var integrity = core.Integrity{References: map[string][]core.Reference{
+	models.FeatureDocType: {
+		{Chaincode: "role", DocType: models.RoleFeatureDocType, Column: models.RoleFeatureFeatureIDColumnName},
+	},
+}}
+
+// schemas of the arguments of the functions, they are checked before the functions are called
+var schemas = core.Schemas{
+	"getByQuery":    {{Name: "filter", Type: core.ArgPair, Repeated: true}},
+	"createFeature": {{Name: "feature name", Required: true}},
+	"deleteFeature": {{Name: "feature id", Required: true}, {Name: "delete mode"}},
+}
+
+// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
+func (s *Feature) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	ccInstance = repository.InitRepo("feature")
+
+	if err := core.InitAttributeMSPIDs(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to configure the MSPs trusted to issue the certificate attributes"))
+	}
+
+	// Init runs on instantiate and on every upgrade, the seeds are reconciled each time
+	if _, err := seedFeatures(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to seed the features"))
+	}
+
+	return shim.Success(nil)
+}
+
+// Invoke method is called as a result of an application request to run the Smart Contract "Feature"
+func (s *Feature) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	
+
+	// Retrieve the requested Smart Contract function and arguments
+	function, args := APIstub.GetFunctionAndParameters()
+
+	// Check the arguments against the schema of the function, so the handlers can index them
+	if err := schemas.Validate(function, args); err != nil {
+		return errs.Response(err)
+	}
+
+	// Route to the appropriate handler function to interact with the ledger appropriately
+	if function == "getByQuery" {
+		return s.getByQuery(APIstub, args)
+	} else if function == "createFeature" {
+		return createFeature(APIstub, args)
+	} else if function == "deleteFeature" {
+		return deleteFeature(APIstub, args)
+	} else if function == "Migrate" {
+		return core.CreateBase().Migrate(APIstub, args)
+	} else if function == "GetMigrations" {
+		return core.CreateBase().GetMigrations(APIstub)
+	} else if function == "Restore" {
+		return core.CreateBase().Restore(APIstub, args, canDeleteFeature)
+	} else if function == "ListArchived" {
+		return core.CreateBase().ListArchived(APIstub, args, canDeleteFeature, models.FeatureDocType)
+	} else if function == "Purge" {
+		return core.CreateBase().Purge(APIstub, args)
+	}
+
+	return errs.Fail(errs.InvalidArgument, "Invalid Smart Contract function name: " + function)
+}
+
+// args[0].. args[n] are pair column and value
+// e.g: args['doctype,feature', 'featureid,025D1E9A-9B52-E811-AA17-FCAA145000C2', ....]
+func (s *Feature) getByQuery(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var b bytes.Buffer
+	i := 0
+	for i < len(args) {
+		var params = strings.Split(args[i], ",")
+		b.WriteString("\""+ models.CanonicalColumn(params[0]) +"\":\""+ params[1] +"\"")
+		i = i + 1
+		if i != len(args){
+			b.WriteString(",");
+		}
+	}
+
+	var query = `{"selector":{`+ b.String() +`}}`
+
+	result, err := ccInstance.GetByQuery(APIstub, query)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to query feature"))
+	}
+
+	return shim.Success(result)
+}
+
+func createFeature(APIstub shim.ChaincodeStubInterface, args[] string) sc.Response {
+
+	err := core.NewAccessControl().CheckPermission(APIstub, core.FeatureManagementFeatureID, "1")
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	var feature = Feature{ FeatureID: utils.NewID(APIstub, models.FeatureDocType, args[0]), FeatureName: args[0], DocType: "feature" }
+	data, _ := json.Marshal(feature)
+	err = ccInstance.Create(APIstub, feature.FeatureID, data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create feature"))
+	}
+	
+	return shim.Success([]byte(feature.FeatureID))
+}
+
+func deleteFeature(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var featureId = args[0]
+
+	mode, err := core.GetDeleteMode(args, 1)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = canDeleteFeature(APIstub, featureId)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = integrity.Delete(APIstub, featureId, mode)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete feature " + featureId))
+	}
+
+	return shim.Success(nil)
+}
+
+// canDeleteFeature checks the caller can delete, restore or list the archived features
+func canDeleteFeature(APIstub shim.ChaincodeStubInterface, featureId string) error {
+	return core.NewAccessControl().CheckPermission(APIstub, core.FeatureManagementFeatureID, "1")
+}
+
+func main() {
+	
+	err := shim.Start(new(Feature))
+	
+	if err != nil {
+		fmt.Printf("Error creating new Skill Chaincode: %s", err)
+	}
+}
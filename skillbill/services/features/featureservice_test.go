@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+func newFeatureStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("feature", new(Feature), testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": "Administrators"}))
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("security", testsupport.NewSecurityFake())
+	stub.MockPeer("role", newRoleFake())
+
+	return stub
+}
+
+// newRoleFake grants the feature management to the administrators, the role features of the keys reference the features
+func newRoleFake(keys ...string) *testsupport.FakeChaincode {
+	return testsupport.NewRoleFake(
+		models.RoleFeature{ID: "1", RoleID: "Administrators", FeatureID: core.FeatureManagementFeatureID, AccessLevel: models.ReadWrite}).
+		WithReferences(keys...)
+}
+
+func putFeature(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "F1", Feature{FeatureID: "F1", FeatureName: "Reporting", DocType: "feature"})
+}
+
+func TestFeatureSeeds(t *testing.T) {
+	stub := newFeatureStub(t)
+	if stub.Keys.Len() != 10 {
+		t.Fatalf("Expected 9 features and the trusted MSPs but got %d records", stub.Keys.Len())
+	}
+
+	testsupport.PutJSON(stub, core.SkillManagementFeatureID, Feature{FeatureID: core.SkillManagementFeatureID, FeatureName: "Skills", DocType: "feature"})
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	var feature Feature
+	json.Unmarshal(stub.State[core.SkillManagementFeatureID], &feature)
+	if stub.Keys.Len() != 10 || feature.FeatureName != "SkillManagement" {
+		t.Errorf("Expected the 9 features reconciled by a second Init but got %d records and %s", stub.Keys.Len(), string(stub.State[core.SkillManagementFeatureID]))
+	}
+}
+
+func TestFeatureWithoutTrustedMSPs(t *testing.T) {
+	stub := testsupport.NewStub("feature", new(Feature), testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": "Administrators"}))
+	stub.MockPeer("security", testsupport.NewSecurityFake())
+	stub.MockPeer("role", newRoleFake())
+
+	if res := stub.Init("init"); res.Status == shim.OK {
+		t.Fatalf("Expected Init to fail without the trusted MSPs")
+	}
+
+	putFeature(stub)
+	res := stub.Invoke("deleteFeature", "F1")
+	if code := errs.CodeOf(errs.FromResponse(res)); code != errs.Internal || len(stub.State["F1"]) == 0 {
+		t.Errorf("Expected the caller denied without the trusted MSPs but got %s, message: %s", code, res.Message)
+	}
+
+	// An upgrade without the MSPs keeps the ones of the instantiation
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+	testsupport.MustInit(t, stub, "init")
+	testsupport.MustInvoke(t, stub, "deleteFeature", "F1")
+}
+
+func TestFeatureInvoke(t *testing.T) {
+	roles := newRoleFake("RF1")
+
+	testsupport.RunInvokeCases(t, newFeatureStub, []testsupport.InvokeCase{
+		{Name: "getByQuery filters by the columns", Function: "getByQuery", Args: []string{"doctype,feature", "featurename,Reporting"}, Setup: putFeature,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getByQuery without match", Function: "getByQuery", Args: []string{"featurename,Auditing"}, Setup: putFeature,
+			Check: testsupport.ExpectCount(0)},
+		{Name: "getByQuery with a filter without value", Function: "getByQuery", Args: []string{"featurename"}, Code: errs.InvalidArgument},
+		{Name: "createFeature stores the feature", Function: "createFeature", Args: []string{"Auditing"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "createFeature with missing arguments", Function: "createFeature", Code: errs.InvalidArgument},
+		{Name: "createFeature by a caller who is not an administrator", Function: "createFeature", Args: []string{"Auditing"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "deleteFeature removes the feature", Function: "deleteFeature", Args: []string{"F1"}, Setup: putFeature,
+			Check: testsupport.ExpectNoState("F1")},
+		{Name: "deleteFeature of a feature assigned to a role", Function: "deleteFeature", Args: []string{"F1"},
+			Setup: func(stub *testsupport.Stub) {
+				putFeature(stub)
+				stub.MockPeer("role", newRoleFake("RF1"))
+			},
+			Code: errs.Conflict},
+		{Name: "deleteFeature cascades to the role features", Function: "deleteFeature", Args: []string{"F1", "cascade"},
+			Setup: func(stub *testsupport.Stub) {
+				putFeature(stub)
+				stub.MockPeer("role", roles)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["F1"]) > 0 || !roles.Called("DeleteReferences") {
+					t.Errorf("Expected the feature deleted with its role features")
+				}
+			}},
+		{Name: "deleteFeature by a caller who is not an administrator", Function: "deleteFeature", Args: []string{"F1"},
+			Setup: func(stub *testsupport.Stub) {
+				putFeature(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "deleteFeature by an administrator of an MSP not trusted with the role attribute", Function: "deleteFeature", Args: []string{"F1"},
+			Setup: func(stub *testsupport.Stub) {
+				putFeature(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org2MSP", "admin", map[string]string{core.RoleAttribute: "Administrators"}))
+			}, Code: errs.PermissionDenied},
+		{Name: "deleteFeature with an invalid mode", Function: "deleteFeature", Args: []string{"F1", "force"}, Setup: putFeature, Status: shim.ERROR},
+		{Name: "deleteFeature with missing arguments", Function: "deleteFeature", Code: errs.InvalidArgument},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
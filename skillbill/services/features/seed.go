@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/repository"
+)
+
+// featureSeeds are the features the chaincode is instantiated with, the access control refers to their ids
+var featureSeeds = []Feature{
+	{FeatureID: core.SkillPlanManagementFeatureID, FeatureName: "SkillPlanManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.SkillManagementFeatureID, FeatureName: "SkillManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.TrackManagementFeatureID, FeatureName: "TrackManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.MilestoneManagementFeatureID, FeatureName: "MilestoneManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.UserManagementFeatureID, FeatureName: "UserManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.RoleManagementFeatureID, FeatureName: "RoleManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.KnowledgeGroupFeatureID, FeatureName: "KnowledgeGroup", DocType: models.FeatureDocType},
+	{FeatureID: core.FeatureManagementFeatureID, FeatureName: "FeatureManagement", DocType: models.FeatureDocType},
+	{FeatureID: core.TranslationManagementFeatureID, FeatureName: "TranslationManagement", DocType: models.FeatureDocType},
+}
+
+// seedFeatures reconciles the seeded features with the ledger, a feature renamed on the ledger gets its name back
+func seedFeatures(APIstub shim.ChaincodeStubInterface) (int, error) {
+	seeds := []repository.Seed{}
+	for _, feature := range featureSeeds {
+		seeds = append(seeds, repository.Seed{Key: feature.FeatureID, Record: feature})
+	}
+
+	return repository.UpsertSeeds(APIstub, seeds)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+)
+
+// UserGroup is a group of a user, the membership is on the group itself or on the group above it which the group inherits it from
+type UserGroup struct {
+	GroupID    string `json:"groupid"`
+	GroupName  string `json:"groupname"`
+	MemberType string `json:"membertype"`
+	MemberOf   string `json:"memberof"`
+}
+
+// RemoveMember removes a user from a group, the caller must administer the group
+// args[0] is group id, args[1] is user id
+func (s *KnowledgeGroupChaincode) RemoveMember(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var groupID = args[0]
+	var userID = args[1]
+
+	member, err := s.getChangedMember(APIstub, groupID, userID)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to remove member " + userID))
+	}
+
+	err = s.repo.DeleteRecord(APIstub, member.ID, core.DeleteRestrict)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to remove member " + userID))
+	}
+
+	return shim.Success(nil)
+}
+
+// ChangeMemberType changes the member type of a user in a group, the caller must administer the group
+// args[0] is group id, args[1] is user id, args[2] is member type
+func (s *KnowledgeGroupChaincode) ChangeMemberType(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var groupID = args[0]
+	var userID = args[1]
+
+	memberType, err := getMemberType(args[2])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to change member " + userID))
+	}
+
+	member, err := s.getChangedMember(APIstub, groupID, userID)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to change member " + userID))
+	}
+
+	err = s.repo.UpdateMemberType(APIstub, member.ID, memberType)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to change member " + userID))
+	}
+
+	return shim.Success(nil)
+}
+
+// GetGroupsForUser returns the groups the user is a member of, followed by the groups below them the membership is inherited by
+// args[0] is user id
+func (s *KnowledgeGroupChaincode) GetGroupsForUser(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var userID = args[0]
+
+	if err := checkUser(APIstub, userID); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the groups of user " + userID))
+	}
+
+	data, err := s.repo.GetMembersByUserID(APIstub, userID)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the groups of user " + userID))
+	}
+
+	var members []models.KnowledgeGroupMember
+	json.Unmarshal(data, &members)
+
+	subgroups := map[string][]models.KnowledgeGroup{}
+	for _, member := range members {
+		subgroups[member.ID], err = s.getSubgroups(APIstub, member.GroupID)
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to get the groups of user " + userID))
+		}
+	}
+
+	groups := []UserGroup{}
+	found := map[string]bool{}
+	addGroup := func(group models.KnowledgeGroup, member models.KnowledgeGroupMember) {
+		if key := group.GroupID + "," + member.MemberType; !found[key] {
+			found[key] = true
+			groups = append(groups, UserGroup{GroupID: group.GroupID, GroupName: group.GroupName, MemberType: member.MemberType, MemberOf: member.GroupID})
+		}
+	}
+
+	// The groups of the memberships come first, so an inherited membership never hides a direct one
+	for _, member := range members {
+		if tree := subgroups[member.ID]; len(tree) > 0 {
+			addGroup(tree[0], member)
+		}
+	}
+
+	for _, member := range members {
+		for _, group := range subgroups[member.ID] {
+			addGroup(group, member)
+		}
+	}
+
+	data, _ = json.Marshal(groups)
+
+	return shim.Success(data)
+}
+
+// getChangedMember returns the membership of the user in the group, after checking the caller can change it
+func (s *KnowledgeGroupChaincode) getChangedMember(APIstub shim.ChaincodeStubInterface, groupID string, userID string) (*models.KnowledgeGroupMember, error) {
+
+	if err := s.checkMemberChange(APIstub, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	member, err := s.getMembership(APIstub, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member == nil {
+		return nil, errs.Errorf(errs.NotFound, "The user %s is not a member of the group %s", userID, groupID)
+	}
+
+	return member, nil
+}
+
+// checkMemberChange returns an error unless the group exists, the caller administers it and the user is registered
+func (s *KnowledgeGroupChaincode) checkMemberChange(APIstub shim.ChaincodeStubInterface, groupID string, userID string) error {
+
+	if _, err := s.getGroup(APIstub, groupID); err != nil {
+		return err
+	}
+
+	if err := s.checkGroupAdministrator(APIstub, groupID); err != nil {
+		return err
+	}
+
+	return checkUser(APIstub, userID)
+}
+
+// getMembership returns the membership of the user in the group itself, nil when the user is not a member
+func (s *KnowledgeGroupChaincode) getMembership(APIstub shim.ChaincodeStubInterface, groupID string, userID string) (*models.KnowledgeGroupMember, error) {
+
+	data, err := s.repo.GetMembersByUserID(APIstub, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []models.KnowledgeGroupMember
+	json.Unmarshal(data, &members)
+
+	for _, member := range members {
+		if member.GroupID == groupID {
+			return &member, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// checkUser returns an error unless the user is registered in the security chaincode
+func checkUser(APIstub shim.ChaincodeStubInterface, userID string) error {
+
+	response := core.InvokeChaincode(APIstub, "security", "UserExists", userID)
+	if response.Status != shim.OK {
+		return errs.Wrapf(errs.FromResponse(response), "Could not check the user %s", userID)
+	}
+
+	if string(response.Payload) != "true" {
+		return errs.Errorf(errs.NotFound, "Could not find any user with name %s", userID)
+	}
+
+	return nil
+}
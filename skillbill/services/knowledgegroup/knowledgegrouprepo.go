@@ -1,119 +1,158 @@
-package main
-
-import (
-	"fmt"
-	"bytes"
-	"strings"
-	"encoding/json"
-	"github.com/beevik/guid"
-	"github.com/skillbill/models"
-	"github.com/skillbill/packages/repository"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-
-	logs "github.com/skillbill/packages/logs"
-)
-
-type KnowledgeGroupRepo struct {
-	repo	repository.BaseRepo
-}
-
-func InitKnowledgeGrpRepo() IKnowledgeGrp {
-	return KnowledgeGroupRepo{}
-}
-
-func (k KnowledgeGroupRepo) GetByQuery(APIstub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-
-	logs.LogInfo("Calling GetByQuery in the knowledgegrp repo.")
-
-	var b bytes.Buffer
-	i := 0
-	for i < len(args) {
-
-		var params = strings.Split(args[i], ",")
-		b.WriteString("\""+params[0]+"\":\""+ params[1]+"\"")
-		i = i + 1
-		if i != len(args){
-			b.WriteString(",");
-		}
-	}
-
-	var query = `{"selector":{`+ b.String() +`}}`
-
-	logs.LogInfo("Query string: " + query)
-
-	return k.repo.GetByQuery(APIstub, query)
-}
-
-func (k KnowledgeGroupRepo) GetByKey(APIstub shim.ChaincodeStubInterface, key string) ([]byte, error) {
-
-	value, err := k.repo.GetByKey(APIstub, key)
-	
-	if err != nil {
-		return nil, fmt.Errorf("Failed to get record %s", key) 
-	}
-
-	return value, nil
-}
-
-// func (k KnowledgeGroupRepo) CheckIsExisted(APIstub shim.ChaincodeStubInterface, id string, doctype string)	(bool, error) {
-// 	var query = `{"selector":{"GroupID":"` + groupID + `", "DocType": "knowledgegroupmember"}, "fields": ["UserID", "MemberType"]}`
-
-// 	result, err := k.repo.Query()
-// }
-
-func (k KnowledgeGroupRepo) CreateKnowledgeGrp(APIstub shim.ChaincodeStubInterface, groupName string) (string, error) {
-
-	var kgroup = models.KnowledgeGroup{ GroupID: guid.New().StringUpper(), GroupName: groupName, DocType: "knowledgegroup" }
-
-	data, _ := json.Marshal(kgroup)
-
-	err := k.repo.Save(APIstub, kgroup.GroupID, data)
-
-	return kgroup.GroupID, err
-}
-
-func (k KnowledgeGroupRepo) UpdateKnowledgeGrp(APIstub shim.ChaincodeStubInterface, params []string) error {
-
-	value, err := k.GetByKey(APIstub, params[0])
-
-	if err != nil{
-		return fmt.Errorf("Failed to update knowledge group %s: %s", params[0], err.Error())
-	}
-
-	if len(string(value)) == 0 {
-		return fmt.Errorf("Failed to update knowledge group, because the knowledge group does not exist.")
-	}
-
-	kngroup := models.KnowledgeGroup{}
-	json.Unmarshal(value, &kngroup)
-	kngroup.GroupName = params[1]
-	logs.LogInfo(string(value))
-	value, _ = json.Marshal(kngroup)
-
-	return k.repo.Save(APIstub, params[0], value)
-}
-
-func (k KnowledgeGroupRepo) DeleteRecord(APIstub shim.ChaincodeStubInterface, groupID string) error {
-	return k.repo.Delete(APIstub, groupID)
-}
-
-func (k KnowledgeGroupRepo) AddMembersToKnowledgeGrp(APIstub shim.ChaincodeStubInterface, groupID string, memberType string, userID string) (string, error) {
-	var kngroupMem = models.KnowledgeGroupMember { 
-		ID: guid.New().StringUpper(), 
-		GroupID: groupID, 
-		MemberType: memberType, 
-		UserID: userID, 
-		DocType: "knowledgegroupmember",
-	}
-	dataAsByte, _ := json.Marshal(kngroupMem)
-	err := k.repo.Save(APIstub, kngroupMem.ID, dataAsByte)
-
-	return kngroupMem.ID, err
-}
-
-func (k KnowledgeGroupRepo) GetMembersByGroupID(APIstub shim.ChaincodeStubInterface, groupID string) ([]byte, error) {
-	var query = `{"selector":{"GroupID":"` + groupID + `", "DocType": "knowledgegroupmember"}, "fields": ["UserID", "MemberType"]}`
-
-	return k.repo.GetByQuery(APIstub, query)
-}
-
+package main
+
+import (
+	"bytes"
+	"strings"
+	"encoding/json"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+
+	logs "github.com/skillbill/packages/logs"
+)
+
+type KnowledgeGroupRepo struct {
+	repo	repository.BaseRepo
+}
+
+func InitKnowledgeGrpRepo() IKnowledgeGrp {
+	return KnowledgeGroupRepo{}
+}
+
+func (k KnowledgeGroupRepo) GetByQuery(APIstub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+	logs.LogInfo("Calling GetByQuery in the knowledgegrp repo.")
+
+	var b bytes.Buffer
+	i := 0
+	for i < len(args) {
+
+		var params = strings.Split(args[i], ",")
+		b.WriteString("\""+models.CanonicalColumn(params[0])+"\":\""+ params[1]+"\"")
+		i = i + 1
+		if i != len(args){
+			b.WriteString(",");
+		}
+	}
+
+	var query = `{"selector":{`+ b.String() +`}}`
+
+	logs.LogInfo("Query string: " + query)
+
+	return k.repo.GetByQuery(APIstub, query)
+}
+
+func (k KnowledgeGroupRepo) GetByKey(APIstub shim.ChaincodeStubInterface, key string) ([]byte, error) {
+
+	value, err := k.repo.GetByKey(APIstub, key)
+	
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// func (k KnowledgeGroupRepo) CheckIsExisted(APIstub shim.ChaincodeStubInterface, id string, doctype string)	(bool, error) {
+// 	var query = `{"selector":{"GroupID":"` + groupID + `", "DocType": "knowledgegroupmember"}, "fields": ["UserID", "MemberType"]}`
+
+// 	result, err := k.repo.Query()
+// }
+
+// CreateKnowledgeGrp adds a group below the parent group, an empty parent id adds a group at the top
+func (k KnowledgeGroupRepo) CreateKnowledgeGrp(APIstub shim.ChaincodeStubInterface, groupName string, parentID string) (string, error) {
+
+	var kgroup = models.KnowledgeGroup{ GroupID: utils.NewID(APIstub, models.KnowledgeGroupDocType, groupName, parentID), GroupName: groupName, ParentID: parentID, DocType: "knowledgegroup" }
+
+	data, _ := json.Marshal(kgroup)
+
+	err := k.repo.Create(APIstub, kgroup.GroupID, data)
+
+	return kgroup.GroupID, err
+}
+
+// UpdateKnowledgeGrp renames a group, params[0] is group id, params[1] is group name, params[2] is the optional parent id
+func (k KnowledgeGroupRepo) UpdateKnowledgeGrp(APIstub shim.ChaincodeStubInterface, params []string) error {
+
+	value, err := k.GetByKey(APIstub, params[0])
+
+	if err != nil{
+		return errs.Wrapf(err, "Failed to update knowledge group %s", params[0])
+	}
+
+	if len(string(value)) == 0 {
+		return errs.New(errs.NotFound, "Failed to update knowledge group, because the knowledge group does not exist.")
+	}
+
+	kngroup := models.KnowledgeGroup{}
+	json.Unmarshal(value, &kngroup)
+	kngroup.GroupName = params[1]
+
+	// The optional params[2] moves the group below another parent
+	if len(params) > 2 {
+		kngroup.ParentID = params[2]
+	}
+	logs.LogInfo(string(value))
+	value, _ = json.Marshal(kngroup)
+
+	return k.repo.Save(APIstub, params[0], value)
+}
+
+// DeleteRecord checks the members and the skills of a group before the delete, depending on the delete mode
+func (k KnowledgeGroupRepo) DeleteRecord(APIstub shim.ChaincodeStubInterface, id string, mode string) error {
+	return integrity.Delete(APIstub, id, mode)
+}
+
+func (k KnowledgeGroupRepo) AddMembersToKnowledgeGrp(APIstub shim.ChaincodeStubInterface, groupID string, memberType string, userID string) (string, error) {
+	var kngroupMem = models.KnowledgeGroupMember { 
+		ID: utils.NewID(APIstub, models.KnowledgeGroupMemberDocType, groupID, userID), 
+		GroupID: groupID, 
+		MemberType: memberType, 
+		UserID: userID, 
+		DocType: "knowledgegroupmember",
+	}
+	dataAsByte, _ := json.Marshal(kngroupMem)
+	err := k.repo.Create(APIstub, kngroupMem.ID, dataAsByte)
+
+	return kngroupMem.ID, err
+}
+
+// GetMembersByGroupIDs returns the members of the groups
+func (k KnowledgeGroupRepo) GetMembersByGroupIDs(APIstub shim.ChaincodeStubInterface, groupIDs []string) ([]byte, error) {
+	selector := map[string]interface{}{
+		models.KnowledgeGroupMemberGroupIDColumnName: map[string]interface{}{"$in": groupIDs},
+		models.DocTypeColumnName: models.KnowledgeGroupMemberDocType}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	return k.repo.GetByQuery(APIstub, string(query))
+}
+
+
+// GetMembersByUserID returns the memberships of the user
+func (k KnowledgeGroupRepo) GetMembersByUserID(APIstub shim.ChaincodeStubInterface, userID string) ([]byte, error) {
+	selector := map[string]interface{}{
+		models.KnowledgeGroupMemberUserIDColumnName: userID,
+		models.DocTypeColumnName: models.KnowledgeGroupMemberDocType}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	return k.repo.GetByQuery(APIstub, string(query))
+}
+
+// UpdateMemberType changes the member type of a membership
+func (k KnowledgeGroupRepo) UpdateMemberType(APIstub shim.ChaincodeStubInterface, id string, memberType string) error {
+
+	value, err := k.GetByKey(APIstub, id)
+
+	if err != nil {
+		return errs.Wrapf(err, "Failed to update member %s", id)
+	}
+
+	member := models.KnowledgeGroupMember{}
+	json.Unmarshal(value, &member)
+	member.MemberType = memberType
+	value, _ = json.Marshal(member)
+
+	return k.repo.Save(APIstub, id, value)
+}
@@ -1,176 +1,327 @@
-package main
-
-
-import (
-	"fmt"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc 	"github.com/hyperledger/fabric/protos/peer"
-	logs "github.com/skillbill/packages/logs"
-)
-
-// RoleChaincode define the Smart Contract structure
-type KnowledgeGroupChaincode struct {
-	repo 	KnowledgeGroupRepo
-}
-
-// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
-func (s *KnowledgeGroupChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
-	logs.SetUpLogging("var/log/knowledge.log")
-	InitKnowledgeGrpRepo()
-
-	return shim.Success(nil)
-}
-
-// Invoke method is called as a result of an application request to run the Smart Contract "Feature"
-func (s *KnowledgeGroupChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	// Retrieve the requested Smart Contract function and arguments
-	function, args := APIstub.GetFunctionAndParameters()
-
-	// Route to the appropriate handler function to interact with the ledger appropriately
-	if function == "GetByQuery" {
-		return s.GetByQuery(APIstub, args)
-	} else if function == "CreateGroup" {
-		return s.CreateGroup(APIstub, args)
-	} else if function == "UpdateGroup" {
-		return s.UpdateGroup(APIstub, args)
-	} else if function == "Delete" {
-		return s.DeleteKnowledgeGrpOrGrpMember(APIstub, args)
-	} else if function == "AddMembersToGroup" {
-		return s.AddMembersToGroup(APIstub, args)
-	} else if function == "GetMemberByGroupID" {
-		return s.GetMemberByGroupID(APIstub, args)
-	}
-
-	return shim.Error("Invalid Smart Contract function name.")
-}
-
-// args[0].. args[n] are pair column and value
-// e.g: args['DocType,knowledgegroup', 'GroupID,E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', ....]
-func (s *KnowledgeGroupChaincode) GetByQuery(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	logs.LogInfo("Before call GetByQuery in the knowledgegrp repo.")
-	result, err := s.repo.GetByQuery(APIstub, args)
-
-	if err != nil {
-		return shim.Error("Failed to query knowledge group due to " + err.Error())
-	}
-
-	return shim.Success(result)
-}
-
-func (s *KnowledgeGroupChaincode) CreateGroup(APIstub shim.ChaincodeStubInterface, args[] string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	id, err := s.repo.CreateKnowledgeGrp(APIstub, args[0])
-
-	if err != nil {
-		return shim.Error("Failed to create knowledge group " + args[0] + " due to " + err.Error())
-	}
-
-	return shim.Success([]byte(id))
-}
-
-func (s *KnowledgeGroupChaincode) DeleteKnowledgeGrpOrGrpMember(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	var id = args[0]
-	value, err := s.repo.GetByKey(APIstub, id)
-	
-	if err != nil {
-		return shim.Error("Failed to delete record " + id + " due to " + err.Error())
-	}
-
-	if string(value) == "" {
-		return shim.Error("Failed to delete record " + id + ", beacause it does not exist.")
-	}
-
-	err = s.repo.DeleteRecord(APIstub, id)
-
-	if err != nil{
-		return shim.Error("Failed to delete record " + id + " due to " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
-
-func (s *KnowledgeGroupChaincode) UpdateGroup(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2")
-	}
-
-	err := s.repo.UpdateKnowledgeGrp(APIstub, args)
-
-	if err != nil {
-		return shim.Error("Failed to update the knowledge group due to")
-	}
-
-	return shim.Success(nil)
-}
-
-// args[0] is group id, args[1] is member type, args[2] is user id (member)
-// e.x: ['groupId', 'Professional','userId']
-func (s *KnowledgeGroupChaincode) AddMembersToGroup(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-	
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
-	}
-
-	memberType, err := getMemberType(args[1])
-
-	if err != nil {
-		return shim.Error("Failed to add member " + args[2] + "due to " + err.Error())
-	}
-
-	id, err := s.repo.AddMembersToKnowledgeGrp(APIstub, args[0], memberType, args[1])
-
-	return shim.Success([]byte(id))
-}
-
-func (s *KnowledgeGroupChaincode) GetMemberByGroupID(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	data, err := s.repo.GetMembersByGroupID(APIstub, args[0])
-
-	if err != nil {
-		return shim.Error("Failed to get list members by group id "+ args[0] + " due to " + err.Error())
-	}
-
-	return shim.Success(data)
-}
-
-const (
-	Professional	string = "Professional"
-	Assessor		string = "Assessor"
-)
-
-func getMemberType(input string) (string, error){
-
-	switch input {
-	case Professional:
-		return Professional, nil
-
-	case Assessor:
-		return Assessor, nil
-
-	default:
-		return "", fmt.Errorf("Invalid member type " + input)
-	}
-}
-
-func main() {
-	err := shim.Start(new(KnowledgeGroupChaincode))
-	if err != nil {
-		fmt.Printf("Error creating new knowledge group Chaincode: %s", err)
-	}
-}
+package main
+
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc 	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	logs "github.com/skillbill/packages/logs"
+	"github.com/skillbill/packages/repository"
+)
+
+// RoleChaincode define the Smart Contract structure
+type KnowledgeGroupChaincode struct {
+	repo 	KnowledgeGroupRepo
+}
+
+// integrity of the knowledge groups, the subgroups and the members of a group and the skills of the skill chaincode point to it.
+// A skill keeps no knowledge group when its group is deleted by cascade, the subgroups are deleted with it.
+var integrity = core.Integrity{References: map[string][]core.Reference{
+	models.KnowledgeGroupDocType: {
+		{DocType: models.KnowledgeGroupDocType, Column: models.KnowledgeGroupParentIDColumnName},
+		{DocType: models.KnowledgeGroupMemberDocType, Column: models.KnowledgeGroupMemberGroupIDColumnName},
+		{Chaincode: "skill", DocType: models.SkillDocType, Column: models.SkillKnowledgeGroupIDColumnName, Clear: true},
+	},
+}}
+
+// schemas of the arguments of the functions, they are checked before the functions are called
+var schemas = core.Schemas{
+	"GetByQuery":              {{Name: "filter", Type: core.ArgPair, Repeated: true}},
+	"CreateGroup":             {{Name: "group name", Required: true}, {Name: "parent group id"}},
+	"UpdateGroup":             {{Name: "group id", Required: true}, {Name: "group name", Required: true}, {Name: "parent group id"}},
+	"Delete":                  {{Name: "key", Required: true}, {Name: "delete mode"}},
+	"AddMembersToGroup":       {{Name: "group id", Required: true}, {Name: "member type", Required: true, Enum: memberTypes}, {Name: "user id", Required: true}},
+	"GetMemberByGroupID":      {{Name: "group id", Required: true}},
+	"RemoveMember":            {{Name: "group id", Required: true}, {Name: "user id", Required: true}},
+	"ChangeMemberType":        {{Name: "group id", Required: true}, {Name: "user id", Required: true}, {Name: "member type", Required: true, Enum: memberTypes}},
+	"GetGroupsForUser":        {{Name: "user id", Required: true}},
+	"GetSubgroups":            {{Name: "group id", Required: true}},
+	"IsAssessor":              {{Name: "user id", Required: true}, {Name: "group id", Required: true}},
+	"CheckGroupAdministrator": {{Name: "group id", Required: true}, {Name: "feature id"}},
+	"MigrateFieldNames":       {},
+}
+
+// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
+func (s *KnowledgeGroupChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
+	logs.SetUpLogging("var/log/knowledge.log")
+	InitKnowledgeGrpRepo()
+
+	if err := core.InitAttributeMSPIDs(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to configure the MSPs trusted to issue the certificate attributes"))
+	}
+
+	return shim.Success(nil)
+}
+
+// Invoke method is called as a result of an application request to run the Smart Contract "Feature"
+func (s *KnowledgeGroupChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	// Retrieve the requested Smart Contract function and arguments
+	function, args := APIstub.GetFunctionAndParameters()
+
+	// Check the arguments against the schema of the function, so the handlers can index them
+	if err := schemas.Validate(function, args); err != nil {
+		return errs.Response(err)
+	}
+
+	// Route to the appropriate handler function to interact with the ledger appropriately
+	if function == "GetByQuery" {
+		return s.GetByQuery(APIstub, args)
+	} else if function == "CreateGroup" {
+		return s.CreateGroup(APIstub, args)
+	} else if function == "UpdateGroup" {
+		return s.UpdateGroup(APIstub, args)
+	} else if function == "Delete" {
+		return s.DeleteKnowledgeGrpOrGrpMember(APIstub, args)
+	} else if function == "AddMembersToGroup" {
+		return s.AddMembersToGroup(APIstub, args)
+	} else if function == "GetMemberByGroupID" {
+		return s.GetMemberByGroupID(APIstub, args)
+	} else if function == "RemoveMember" {
+		return s.RemoveMember(APIstub, args)
+	} else if function == "ChangeMemberType" {
+		return s.ChangeMemberType(APIstub, args)
+	} else if function == "GetGroupsForUser" {
+		return s.GetGroupsForUser(APIstub, args)
+	} else if function == "GetSubgroups" {
+		return s.GetSubgroups(APIstub, args)
+	} else if function == "IsAssessor" {
+		return s.IsAssessor(APIstub, args)
+	} else if function == "CheckGroupAdministrator" {
+		return s.CheckGroupAdministrator(APIstub, args)
+	} else if function == "MigrateFieldNames" {
+		return s.MigrateFieldNames(APIstub)
+	} else if function == "GetReferences" {
+		return integrity.GetReferences(APIstub, args)
+	} else if function == "DeleteReferences" {
+		return integrity.DeleteReferences(APIstub, args)
+	} else if function == "ClearReferences" {
+		return integrity.ClearReferences(APIstub, args)
+	} else if function == "Migrate" {
+		return core.CreateBase().Migrate(APIstub, args)
+	} else if function == "GetMigrations" {
+		return core.CreateBase().GetMigrations(APIstub)
+	} else if function == "Restore" {
+		return core.CreateBase().Restore(APIstub, args, s.canDeleteRecord)
+	} else if function == "ListArchived" {
+		return core.CreateBase().ListArchived(APIstub, args, s.canDeleteRecord, models.KnowledgeGroupDocType, models.KnowledgeGroupMemberDocType)
+	} else if function == "Purge" {
+		return core.CreateBase().Purge(APIstub, args)
+	}
+
+	return errs.Fail(errs.InvalidArgument, "Invalid Smart Contract function name.")
+}
+
+// args[0].. args[n] are pair column and value
+// e.g: args['DocType,knowledgegroup', 'GroupID,E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', ....]
+func (s *KnowledgeGroupChaincode) GetByQuery(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	logs.LogInfo("Before call GetByQuery in the knowledgegrp repo.")
+	result, err := s.repo.GetByQuery(APIstub, args)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to query knowledge group"))
+	}
+
+	return shim.Success(result)
+}
+
+// CreateGroup adds a group below a parent group, which only the administrators of the parent can.
+// Only an administrator can add a group at the top.
+// args[0] is group name, args[1] is the optional parent group id
+func (s *KnowledgeGroupChaincode) CreateGroup(APIstub shim.ChaincodeStubInterface, args[] string) sc.Response {
+
+	var parentID = ""
+	if len(args) == 2 {
+		parentID = args[1]
+	}
+
+	err := s.checkParent(APIstub, "", parentID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create knowledge group " + args[0]))
+	}
+
+	id, err := s.repo.CreateKnowledgeGrp(APIstub, args[0], parentID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create knowledge group " + args[0]))
+	}
+
+	return shim.Success([]byte(id))
+}
+
+// DeleteKnowledgeGrpOrGrpMember removes a group or a member, the caller must administer the group
+func (s *KnowledgeGroupChaincode) DeleteKnowledgeGrpOrGrpMember(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var id = args[0]
+
+	mode, err := core.GetDeleteMode(args, 1)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = s.canDeleteRecord(APIstub, id)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete record " + id))
+	}
+
+	err = s.repo.DeleteRecord(APIstub, id, mode)
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to delete record " + id))
+	}
+
+	return shim.Success(nil)
+}
+
+// canDeleteRecord checks the caller administers the group of a group or a member,
+// to delete, restore or list it among the archived records
+func (s *KnowledgeGroupChaincode) canDeleteRecord(APIstub shim.ChaincodeStubInterface, id string) error {
+
+	value, err := s.repo.GetByKey(APIstub, id)
+
+	if err != nil {
+		return err
+	}
+
+	if string(value) == "" {
+		return errs.Errorf(errs.NotFound, "The record %s does not exist.", id)
+	}
+
+	// The group id of a group is its own id, of a member the id of its group
+	var record models.KnowledgeGroupMember
+	json.Unmarshal(value, &record)
+
+	return s.checkGroupAdministrator(APIstub, record.GroupID)
+}
+
+// UpdateGroup renames a group and moves it below another parent, the caller must administer the group and the new parent
+// args[0] is group id, args[1] is group name, args[2] is the optional parent group id, empty for the top
+func (s *KnowledgeGroupChaincode) UpdateGroup(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	err := s.checkGroupAdministrator(APIstub, args[0])
+
+	if err == nil && len(args) == 3 {
+		err = s.checkParent(APIstub, args[0], args[2])
+	}
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update the knowledge group " + args[0]))
+	}
+
+	err = s.repo.UpdateKnowledgeGrp(APIstub, args)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update the knowledge group"))
+	}
+
+	return shim.Success(nil)
+}
+
+// AddMembersToGroup adds a registered user to a group they are not a member of yet
+// args[0] is group id, args[1] is member type, args[2] is user id (member)
+// e.x: ['groupId', 'Professional','userId'], the caller must administer the group
+func (s *KnowledgeGroupChaincode) AddMembersToGroup(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+	
+	var groupID = args[0]
+	var userID = args[2]
+
+	memberType, err := getMemberType(args[1])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to add member " + userID))
+	}
+
+	err = s.checkMemberChange(APIstub, groupID, userID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to add member " + userID))
+	}
+
+	member, err := s.getMembership(APIstub, groupID, userID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to add member " + userID))
+	}
+
+	if member != nil {
+		return errs.Fail(errs.AlreadyExists, "Failed to add member " + userID + ", the user is a " + member.MemberType + " of the group " + groupID + " already")
+	}
+
+	id, err := s.repo.AddMembersToKnowledgeGrp(APIstub, groupID, memberType, userID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to add member " + userID))
+	}
+
+	return shim.Success([]byte(id))
+}
+
+// GetMemberByGroupID returns the members of a group and the members of the groups above it, who are members of the group too
+// args[0] is group id
+func (s *KnowledgeGroupChaincode) GetMemberByGroupID(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	members, err := s.getMembers(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get list members by group id "+ args[0]))
+	}
+
+	data, _ := json.Marshal(members)
+
+	return shim.Success(data)
+}
+
+// MigrateFieldNames rewrites the records stored with mis-cased field names
+func (s *KnowledgeGroupChaincode) MigrateFieldNames(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	count, err := repository.MigrateFieldNames(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to migrate field names"))
+	}
+
+	return shim.Success([]byte(strconv.Itoa(count)))
+}
+
+// Member types, an administrator manages the group and the groups below it
+const (
+	Professional	string = "Professional"
+	Assessor		string = "Assessor"
+	Administrator	string = "Administrator"
+)
+
+// memberTypes are the values of the member type arguments
+var memberTypes = []string{Professional, Assessor, Administrator}
+
+func getMemberType(input string) (string, error){
+
+	switch input {
+	case Professional:
+		return Professional, nil
+
+	case Assessor:
+		return Assessor, nil
+
+	case Administrator:
+		return Administrator, nil
+
+	default:
+		return "", errs.Errorf(errs.InvalidArgument, "Invalid member type %s", input)
+	}
+}
+
+func main() {
+	err := shim.Start(new(KnowledgeGroupChaincode))
+	if err != nil {
+		fmt.Printf("Error creating new knowledge group Chaincode: %s", err)
+	}
+}
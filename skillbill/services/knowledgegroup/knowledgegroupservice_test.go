@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+// groupAdministrator administers the group Backend and the groups below it, without being an administrator
+var groupAdministrator = testsupport.MustNewIdentity("Org1MSP", "carol", nil)
+
+// scopedAdministrator has a role assigned within the group Backend which can write the knowledge groups
+var scopedAdministrator = testsupport.MustNewIdentity("Org1MSP", "dave", nil)
+
+func newKnowledgeGroupStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("knowledgegroup", new(KnowledgeGroupChaincode), testsupport.MustNewIdentity("Org1MSP", "admin", nil))
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("skill", testsupport.NewFakeChaincode().WithReferences())
+	stub.MockPeer("security", testsupport.NewSecurityFake(
+		models.User{ADLogin: "admin", RoleID: "Administrators", DocType: "user"},
+		models.User{ADLogin: "carol", RoleID: "Users", DocType: "user"},
+		models.User{ADLogin: "alice", RoleID: "Users", DocType: "user"},
+		models.User{ADLogin: "bob", RoleID: "Users", DocType: "user"},
+		models.User{ADLogin: "dave", RoleID: "Users", DocType: "user"}).
+		On("GetScopedRoles", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			cert, _ := cid.GetX509Certificate(stub)
+			if cert.Subject.CommonName == "dave" && strings.Contains(","+args[0]+",", ",G1,") {
+				return shim.Success([]byte(`["ProfessionalGroupAdministrators"]`))
+			}
+
+			return shim.Success([]byte(`[]`))
+		}))
+	stub.MockPeer("role", testsupport.NewRoleFake(
+		models.RoleFeature{ID: "1", RoleID: "Administrators", FeatureID: core.FeatureManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "2", RoleID: "ProfessionalGroupAdministrators", FeatureID: core.KnowledgeGroupFeatureID, AccessLevel: models.ReadWrite}))
+
+	return stub
+}
+
+func putKnowledgeGroup(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "G1", models.KnowledgeGroup{GroupID: "G1", GroupName: "Backend", DocType: "knowledgegroup"})
+	testsupport.PutJSON(stub, "GM1", models.KnowledgeGroupMember{ID: "GM1", GroupID: "G1", MemberType: Assessor, UserID: "alice", DocType: "knowledgegroupmember"})
+}
+
+// putHierarchy adds Go below Backend, which carol administers, and Frontend at the top
+func putHierarchy(stub *testsupport.Stub) {
+	putKnowledgeGroup(stub)
+	testsupport.PutJSON(stub, "G2", models.KnowledgeGroup{GroupID: "G2", GroupName: "Go", ParentID: "G1", DocType: "knowledgegroup"})
+	testsupport.PutJSON(stub, "G3", models.KnowledgeGroup{GroupID: "G3", GroupName: "Frontend", DocType: "knowledgegroup"})
+	testsupport.PutJSON(stub, "GM2", models.KnowledgeGroupMember{ID: "GM2", GroupID: "G1", MemberType: Administrator, UserID: "carol", DocType: "knowledgegroupmember"})
+}
+
+// asGroupAdministrator adds the hierarchy and calls as carol
+func asGroupAdministrator(stub *testsupport.Stub) {
+	putHierarchy(stub)
+	stub.SetIdentity(groupAdministrator)
+}
+
+// expectParent checks the parent of the group stored with the key, or with the returned id when the key is empty
+// asScopedAdministrator adds the hierarchy and calls as dave
+func asScopedAdministrator(stub *testsupport.Stub) {
+	putHierarchy(stub)
+	stub.SetIdentity(scopedAdministrator)
+}
+
+func expectParent(key string, parentID string) func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+	return func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+		id := key
+		if id == "" {
+			id = string(res.Payload)
+		}
+
+		var group models.KnowledgeGroup
+		json.Unmarshal(stub.State[id], &group)
+		if group.ParentID != parentID {
+			t.Errorf("Expected the group below %s but got %s", parentID, string(stub.State[id]))
+		}
+	}
+}
+
+func TestKnowledgeGroupInvoke(t *testing.T) {
+	skills := testsupport.NewFakeChaincode().WithReferences("S1")
+
+	testsupport.RunInvokeCases(t, newKnowledgeGroupStub, []testsupport.InvokeCase{
+		{Name: "GetByQuery filters by the columns", Function: "GetByQuery", Args: []string{"doctype,knowledgegroup"}, Setup: putKnowledgeGroup,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "CreateGroup stores the group", Function: "CreateGroup", Args: []string{"Frontend"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "CreateGroup with missing arguments", Function: "CreateGroup", Code: errs.InvalidArgument},
+		{Name: "CreateGroup below a parent", Function: "CreateGroup", Args: []string{"Rust", "G1"}, Setup: putKnowledgeGroup,
+			Check: expectParent("", "G1")},
+		{Name: "CreateGroup below an unknown parent", Function: "CreateGroup", Args: []string{"Rust", "G9"}, Code: errs.NotFound},
+		{Name: "CreateGroup below a subgroup of the group administrator", Function: "CreateGroup", Args: []string{"Generics", "G2"}, Setup: asGroupAdministrator,
+			Check: expectParent("", "G2")},
+		{Name: "CreateGroup at the top by a group administrator", Function: "CreateGroup", Args: []string{"Design"}, Setup: asGroupAdministrator, Status: shim.ERROR},
+		{Name: "CreateGroup below a group the caller does not administer", Function: "CreateGroup", Args: []string{"React", "G3"}, Setup: asGroupAdministrator, Status: shim.ERROR},
+		{Name: "UpdateGroup renames the group", Function: "UpdateGroup", Args: []string{"G1", "Platform"}, Setup: putKnowledgeGroup,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var group models.KnowledgeGroup
+				json.Unmarshal(stub.State["G1"], &group)
+				if group.GroupName != "Platform" {
+					t.Errorf("Expected the group Platform but got %s", string(stub.State["G1"]))
+				}
+			}},
+		{Name: "UpdateGroup of an unknown group", Function: "UpdateGroup", Args: []string{"G9", "Platform"}, Code: errs.NotFound},
+		{Name: "UpdateGroup moves the group below another parent", Function: "UpdateGroup", Args: []string{"G2", "Go", "G3"}, Setup: putHierarchy,
+			Check: expectParent("G2", "G3")},
+		{Name: "UpdateGroup moves the group to the top", Function: "UpdateGroup", Args: []string{"G2", "Go", ""}, Setup: putHierarchy,
+			Check: expectParent("G2", "")},
+		{Name: "UpdateGroup below one of its subgroups", Function: "UpdateGroup", Args: []string{"G1", "Backend", "G2"}, Setup: putHierarchy, Code: errs.Conflict},
+		{Name: "UpdateGroup of a subgroup by the group administrator", Function: "UpdateGroup", Args: []string{"G2", "Golang"}, Setup: asGroupAdministrator,
+			Check: expectParent("G2", "G1")},
+		{Name: "UpdateGroup of a subgroup by a role assigned within the group", Function: "UpdateGroup", Args: []string{"G2", "Golang"}, Setup: asScopedAdministrator,
+			Check: expectParent("G2", "G1")},
+		{Name: "UpdateGroup outside the scope of the role", Function: "UpdateGroup", Args: []string{"G3", "Web"}, Setup: asScopedAdministrator, Status: shim.ERROR},
+		{Name: "UpdateGroup of a group the caller does not administer", Function: "UpdateGroup", Args: []string{"G3", "Web"}, Setup: asGroupAdministrator, Status: shim.ERROR},
+		{Name: "UpdateGroup moves a subgroup out of the groups of the group administrator", Function: "UpdateGroup", Args: []string{"G2", "Go", "G3"}, Setup: asGroupAdministrator, Status: shim.ERROR},
+		{Name: "Delete removes the member", Function: "Delete", Args: []string{"GM1"}, Setup: putKnowledgeGroup,
+			Check: testsupport.ExpectNoState("GM1")},
+		{Name: "Delete of a group with members", Function: "Delete", Args: []string{"G1"}, Setup: putKnowledgeGroup, Status: shim.ERROR},
+		{Name: "Delete cascades to the members and the skills", Function: "Delete", Args: []string{"G1", "cascade"},
+			Setup: func(stub *testsupport.Stub) {
+				putKnowledgeGroup(stub)
+				stub.MockPeer("skill", skills)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["G1"]) > 0 || len(stub.State["GM1"]) > 0 || !skills.Called("ClearReferences") {
+					t.Errorf("Expected the group deleted with its members and cleared from the skills")
+				}
+			}},
+		{Name: "Delete soft keeps the group flagged", Function: "Delete", Args: []string{"G1", "soft"}, Setup: putKnowledgeGroup,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var group map[string]interface{}
+				json.Unmarshal(stub.State["G1"], &group)
+				if group[models.DeletedColumnName] != true {
+					t.Errorf("Expected the group flagged as deleted but got %s", string(stub.State["G1"]))
+				}
+			}},
+		{Name: "Delete of an unknown record", Function: "Delete", Args: []string{"G9"}, Code: errs.NotFound},
+		{Name: "Delete of a group with subgroups", Function: "Delete", Args: []string{"G3"},
+			Setup: func(stub *testsupport.Stub) {
+				putHierarchy(stub)
+				testsupport.PutJSON(stub, "G4", models.KnowledgeGroup{GroupID: "G4", GroupName: "React", ParentID: "G3", DocType: "knowledgegroup"})
+			}, Status: shim.ERROR},
+		{Name: "Delete cascades to the subgroups", Function: "Delete", Args: []string{"G1", "cascade"}, Setup: putHierarchy,
+			Check: testsupport.ExpectNoState("G2")},
+		{Name: "Delete of a subgroup by the group administrator", Function: "Delete", Args: []string{"G2"}, Setup: asGroupAdministrator,
+			Check: testsupport.ExpectNoState("G2")},
+		{Name: "Delete of a group the caller does not administer", Function: "Delete", Args: []string{"G3"}, Setup: asGroupAdministrator, Status: shim.ERROR},
+		{Name: "AddMembersToGroup stores the member", Function: "AddMembersToGroup", Args: []string{"G1", Professional, "bob"}, Setup: putKnowledgeGroup,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var member models.KnowledgeGroupMember
+				json.Unmarshal(stub.State[string(res.Payload)], &member)
+				if member.UserID != "bob" || member.MemberType != Professional || member.GroupID != "G1" {
+					t.Errorf("Expected bob as professional of G1 but got %s", string(stub.State[string(res.Payload)]))
+				}
+			}},
+		{Name: "AddMembersToGroup of a member of the group", Function: "AddMembersToGroup", Args: []string{"G1", Professional, "alice"}, Setup: putKnowledgeGroup, Status: shim.ERROR},
+		{Name: "AddMembersToGroup of an unknown user", Function: "AddMembersToGroup", Args: []string{"G1", Professional, "nobody"}, Setup: putKnowledgeGroup, Code: errs.NotFound},
+		{Name: "AddMembersToGroup to an unknown group", Function: "AddMembersToGroup", Args: []string{"G9", Professional, "bob"}, Code: errs.NotFound},
+		{Name: "AddMembersToGroup with an invalid member type", Function: "AddMembersToGroup", Args: []string{"G1", "Guest", "bob"}, Code: errs.InvalidArgument},
+		{Name: "AddMembersToGroup with missing arguments", Function: "AddMembersToGroup", Args: []string{"G1"}, Code: errs.InvalidArgument},
+		{Name: "AddMembersToGroup to a group the caller does not administer", Function: "AddMembersToGroup", Args: []string{"G3", Professional, "bob"}, Setup: asGroupAdministrator, Status: shim.ERROR},
+		{Name: "RemoveMember removes the membership", Function: "RemoveMember", Args: []string{"G1", "alice"}, Setup: putKnowledgeGroup,
+			Check: testsupport.ExpectNoState("GM1")},
+		{Name: "RemoveMember of a user who is not a member", Function: "RemoveMember", Args: []string{"G1", "bob"}, Setup: putKnowledgeGroup, Status: shim.ERROR},
+		{Name: "RemoveMember of an unknown user", Function: "RemoveMember", Args: []string{"G1", "nobody"}, Setup: putKnowledgeGroup, Code: errs.NotFound},
+		{Name: "RemoveMember of a group the caller does not administer", Function: "RemoveMember", Args: []string{"G3", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				asGroupAdministrator(stub)
+				testsupport.PutJSON(stub, "GM3", models.KnowledgeGroupMember{ID: "GM3", GroupID: "G3", MemberType: Professional, UserID: "alice", DocType: "knowledgegroupmember"})
+			}, Status: shim.ERROR},
+		{Name: "RemoveMember with missing arguments", Function: "RemoveMember", Args: []string{"G1"}, Code: errs.InvalidArgument},
+		{Name: "ChangeMemberType changes the type of the membership", Function: "ChangeMemberType", Args: []string{"G1", "alice", Professional}, Setup: putKnowledgeGroup,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var member models.KnowledgeGroupMember
+				json.Unmarshal(stub.State["GM1"], &member)
+				if member.MemberType != Professional || member.UserID != "alice" {
+					t.Errorf("Expected alice as professional but got %s", string(stub.State["GM1"]))
+				}
+			}},
+		{Name: "ChangeMemberType with an invalid member type", Function: "ChangeMemberType", Args: []string{"G1", "alice", "Guest"}, Setup: putKnowledgeGroup, Code: errs.InvalidArgument},
+		{Name: "ChangeMemberType of a user who is not a member", Function: "ChangeMemberType", Args: []string{"G1", "bob", Assessor}, Setup: putKnowledgeGroup, Status: shim.ERROR},
+		{Name: "ChangeMemberType with missing arguments", Function: "ChangeMemberType", Args: []string{"G1", "alice"}, Code: errs.InvalidArgument},
+		{Name: "GetGroupsForUser returns the groups and the inherited subgroups", Function: "GetGroupsForUser", Args: []string{"carol"}, Setup: putHierarchy,
+			Check: testsupport.ExpectPayload(`[{"groupid":"G1","groupname":"Backend","membertype":"Administrator","memberof":"G1"},{"groupid":"G2","groupname":"Go","membertype":"Administrator","memberof":"G1"}]`)},
+		{Name: "GetGroupsForUser of a user of no group", Function: "GetGroupsForUser", Args: []string{"bob"}, Setup: putHierarchy,
+			Check: testsupport.ExpectPayload(`[]`)},
+		{Name: "GetGroupsForUser of an unknown user", Function: "GetGroupsForUser", Args: []string{"nobody"}, Code: errs.NotFound},
+		{Name: "GetMemberByGroupID returns the members", Function: "GetMemberByGroupID", Args: []string{"G1"}, Setup: putKnowledgeGroup,
+			Check: testsupport.ExpectPayload(`[{"userid":"alice","membertype":"Assessor","groupid":"G1"}]`)},
+		{Name: "GetMemberByGroupID includes the members of the parent groups", Function: "GetMemberByGroupID", Args: []string{"G2"},
+			Setup: func(stub *testsupport.Stub) {
+				putHierarchy(stub)
+				testsupport.PutJSON(stub, "GM3", models.KnowledgeGroupMember{ID: "GM3", GroupID: "G2", MemberType: Assessor, UserID: "alice", DocType: "knowledgegroupmember"})
+			},
+			Check: testsupport.ExpectPayload(`[{"userid":"alice","membertype":"Assessor","groupid":"G2"},{"userid":"carol","membertype":"Administrator","groupid":"G1"}]`)},
+		{Name: "GetMemberByGroupID with missing arguments", Function: "GetMemberByGroupID", Code: errs.InvalidArgument},
+		{Name: "GetSubgroups returns the group and the groups below it", Function: "GetSubgroups", Args: []string{"G1"}, Setup: putHierarchy,
+			Check: testsupport.ExpectCount(2)},
+		{Name: "GetSubgroups of an unknown group", Function: "GetSubgroups", Args: []string{"G9"}, Code: errs.NotFound},
+		{Name: "IsAssessor of a subgroup of the group the user assesses", Function: "IsAssessor", Args: []string{"alice", "G2"}, Setup: putHierarchy,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "IsAssessor of another group", Function: "IsAssessor", Args: []string{"alice", "G3"}, Setup: putHierarchy,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "IsAssessor with missing arguments", Function: "IsAssessor", Args: []string{"alice"}, Code: errs.InvalidArgument},
+		{Name: "CheckGroupAdministrator of a subgroup of the group administrator", Function: "CheckGroupAdministrator", Args: []string{"G2"}, Setup: asGroupAdministrator,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckGroupAdministrator of a feature the role within the group can not write", Function: "CheckGroupAdministrator", Args: []string{"G2", core.SkillManagementFeatureID}, Setup: asScopedAdministrator,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckGroupAdministrator of the feature the role within the group can write", Function: "CheckGroupAdministrator", Args: []string{"G2", core.KnowledgeGroupFeatureID}, Setup: asScopedAdministrator,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckGroupAdministrator of a group the caller does not administer", Function: "CheckGroupAdministrator", Args: []string{"G3"}, Setup: asGroupAdministrator,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "MigrateFieldNames rewrites mis-cased records", Function: "MigrateFieldNames",
+			Setup: func(stub *testsupport.Stub) {
+				putKnowledgeGroup(stub)
+				stub.PutRecord("G2", []byte(`{"GroupID":"G2","GroupName":"Frontend","DocType":"KnowledgeGroup"}`))
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if string(res.Payload) != "1" || string(stub.State["G2"]) != `{"doctype":"knowledgegroup","groupid":"G2","groupname":"Frontend"}` {
+					t.Errorf("Expected the migrated group but got %s", string(stub.State["G2"]))
+				}
+			}},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
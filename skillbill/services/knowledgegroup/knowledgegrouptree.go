@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// GroupMember is a member of a knowledge group, the group is the one the membership is inherited from
+type GroupMember struct {
+	UserID     string `json:"userid"`
+	MemberType string `json:"membertype"`
+	GroupID    string `json:"groupid"`
+}
+
+// GetSubgroups returns the group followed by the groups below it
+// args[0] is group id
+func (s *KnowledgeGroupChaincode) GetSubgroups(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if _, err := s.getGroup(APIstub, args[0]); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the subgroups of " + args[0]))
+	}
+
+	groups, err := s.getSubgroups(APIstub, args[0])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the subgroups of " + args[0]))
+	}
+
+	data, _ := json.Marshal(groups)
+
+	return shim.Success(data)
+}
+
+// IsAssessor is true when the user assesses the group or one of the groups above it
+// args[0] is user id, args[1] is group id
+func (s *KnowledgeGroupChaincode) IsAssessor(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	members, err := s.getMembers(APIstub, args[1])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the assessors of " + args[1]))
+	}
+
+	return shim.Success([]byte(strconv.FormatBool(hasMembership(members, args[0], Assessor))))
+}
+
+// CheckGroupAdministrator is true when the caller is an administrator, administers the group or one of the groups above it,
+// or has a role assigned within one of them which can write the feature
+// args[0] is group id, args[1] is the optional feature id, the knowledge group feature by default
+func (s *KnowledgeGroupChaincode) CheckGroupAdministrator(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var featureID = core.KnowledgeGroupFeatureID
+	if len(args) == 2 && args[1] != "" {
+		featureID = args[1]
+	}
+
+	if _, err := s.getGroup(APIstub, args[0]); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to check the administrators of " + args[0]))
+	}
+
+	err := s.checkGroupPermission(APIstub, args[0], featureID)
+
+	return shim.Success([]byte(strconv.FormatBool(err == nil)))
+}
+
+// checkGroupAdministrator returns an error unless the caller can manage the group, its members and the groups below it
+func (s *KnowledgeGroupChaincode) checkGroupAdministrator(APIstub shim.ChaincodeStubInterface, groupID string) error {
+	return s.checkGroupPermission(APIstub, groupID, core.KnowledgeGroupFeatureID)
+}
+
+// checkGroupPermission returns an error unless the caller is an administrator, administers the group or one of the groups above it,
+// or can write the feature within the group or one of the groups above it
+func (s *KnowledgeGroupChaincode) checkGroupPermission(APIstub shim.ChaincodeStubInterface, groupID string, featureID string) error {
+
+	if err := core.CreateBase().CheckAdministrator(APIstub); err == nil {
+		return nil
+	}
+
+	groupIDs, err := s.getAncestors(APIstub, groupID)
+	if err != nil {
+		return err
+	}
+
+	if err := core.NewAccessControl().CheckPermission(APIstub, featureID, "1", groupIDs...); err == nil {
+		return nil
+	}
+
+	_, userID, err := utils.GetCreatorIdentity(APIstub)
+	if err != nil {
+		return errs.Wrap(err, "Could not get the caller")
+	}
+
+	members, err := s.getMembers(APIstub, groupID)
+	if err != nil {
+		return err
+	}
+
+	if !hasMembership(members, userID, Administrator) {
+		return errs.Errorf(errs.PermissionDenied, "Permission denied, the caller does not administer the knowledge group %s", groupID)
+	}
+
+	return nil
+}
+
+// checkParent returns an error unless the caller can add a group below the parent, only an administrator can add a group at the top.
+// A group can not be moved below itself or one of its subgroups.
+func (s *KnowledgeGroupChaincode) checkParent(APIstub shim.ChaincodeStubInterface, groupID string, parentID string) error {
+
+	if parentID == "" {
+		return core.CreateBase().CheckAdministrator(APIstub)
+	}
+
+	if _, err := s.getGroup(APIstub, parentID); err != nil {
+		return err
+	}
+
+	if err := s.checkGroupAdministrator(APIstub, parentID); err != nil {
+		return err
+	}
+
+	if groupID == "" {
+		return nil
+	}
+
+	subgroups, err := s.getSubgroups(APIstub, groupID)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range subgroups {
+		if group.GroupID == parentID {
+			return errs.Errorf(errs.Conflict, "The group %s is below the group %s", parentID, groupID)
+		}
+	}
+
+	return nil
+}
+
+// getMembers returns the members of the group followed by the members inherited from the groups above it.
+// A user is listed once per member type, with the nearest group they are a member of.
+func (s *KnowledgeGroupChaincode) getMembers(APIstub shim.ChaincodeStubInterface, groupID string) ([]GroupMember, error) {
+
+	groupIDs, err := s.getAncestors(APIstub, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.repo.GetMembersByGroupIDs(APIstub, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.KnowledgeGroupMember
+	json.Unmarshal(data, &records)
+
+	byGroup := map[string][]models.KnowledgeGroupMember{}
+	for _, record := range records {
+		byGroup[record.GroupID] = append(byGroup[record.GroupID], record)
+	}
+
+	members := []GroupMember{}
+	found := map[string]bool{}
+	for _, id := range groupIDs {
+		for _, record := range byGroup[id] {
+			if key := record.UserID + "," + record.MemberType; !found[key] {
+				found[key] = true
+				members = append(members, GroupMember{UserID: record.UserID, MemberType: record.MemberType, GroupID: id})
+			}
+		}
+	}
+
+	return members, nil
+}
+
+func hasMembership(members []GroupMember, userID string, memberType string) bool {
+	for _, member := range members {
+		if member.UserID == userID && member.MemberType == memberType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAncestors returns the group followed by the groups above it, up to the top
+func (s *KnowledgeGroupChaincode) getAncestors(APIstub shim.ChaincodeStubInterface, groupID string) ([]string, error) {
+	ancestors := []string{}
+	visited := map[string]bool{}
+
+	for current := groupID; current != ""; {
+		if visited[current] {
+			return nil, errs.Errorf(errs.Conflict, "The parents of the group %s are cyclic", groupID)
+		}
+		visited[current] = true
+
+		group, err := s.getGroup(APIstub, current)
+		if err != nil {
+			return nil, err
+		}
+
+		ancestors = append(ancestors, current)
+		current = group.ParentID
+	}
+
+	return ancestors, nil
+}
+
+// getSubgroups returns the group followed by the groups below it
+func (s *KnowledgeGroupChaincode) getSubgroups(APIstub shim.ChaincodeStubInterface, groupID string) ([]models.KnowledgeGroup, error) {
+	data, err := s.repo.GetByQuery(APIstub, []string{models.DocTypeColumnName + "," + models.KnowledgeGroupDocType})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []models.KnowledgeGroup
+	json.Unmarshal(data, &groups)
+
+	children := map[string][]models.KnowledgeGroup{}
+	tree := []models.KnowledgeGroup{}
+	for _, group := range groups {
+		children[group.ParentID] = append(children[group.ParentID], group)
+
+		if group.GroupID == groupID {
+			tree = append(tree, group)
+		}
+	}
+
+	found := map[string]bool{groupID: true}
+	for i := 0; i < len(tree); i++ {
+		for _, child := range children[tree[i].GroupID] {
+			if !found[child.GroupID] {
+				found[child.GroupID] = true
+				tree = append(tree, child)
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+func (s *KnowledgeGroupChaincode) getGroup(APIstub shim.ChaincodeStubInterface, groupID string) (*models.KnowledgeGroup, error) {
+	var group *models.KnowledgeGroup
+
+	payload, err := s.repo.GetByKey(APIstub, groupID)
+	if err != nil || len(payload) == 0 {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any knowledge group with id %s", groupID)
+	}
+
+	err = json.Unmarshal(payload, &group)
+	if err != nil || group.DocType != models.KnowledgeGroupDocType {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any knowledge group with id %s", groupID)
+	}
+
+	return group, nil
+}
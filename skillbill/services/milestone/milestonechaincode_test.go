@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/testsupport"
+)
+
+func newMilestoneStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("milestone", new(MilestoneChaincode), testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": "Administrators"}))
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("security", testsupport.NewSecurityFake())
+	stub.MockPeer("role", testsupport.NewRoleFake(
+		models.RoleFeature{ID: "1", RoleID: "Administrators", FeatureID: core.TrackManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "2", RoleID: "Administrators", FeatureID: core.MilestoneManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "3", RoleID: "MilestoneManagers", FeatureID: core.MilestoneManagementFeatureID, AccessLevel: models.ReadWrite}))
+
+	return stub
+}
+
+// asMilestoneManager makes bob the caller, who is assigned the role MilestoneManagers within the track T1 only.
+// The milestone M3 of the track T2 is archived.
+func asMilestoneManager(stub *testsupport.Stub) {
+	putMilestones(stub)
+	stub.PutRecord("M3", []byte(`{"milestoneid":"M3","trackid":"T2","version":"1","doctype":"milestone","deleted":true}`))
+
+	stub.MockPeer("security", testsupport.NewSecurityFake(models.User{ADLogin: "bob", MSPID: "Org1MSP", RoleID: "Users"}).
+		On("GetScopedRoles", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			roles := []string{}
+			for _, scopeID := range strings.Split(args[0], ",") {
+				if scopeID == "T1" {
+					roles = append(roles, "MilestoneManagers")
+				}
+			}
+
+			data, _ := json.Marshal(roles)
+			return shim.Success(data)
+		}))
+	stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+}
+
+func putMilestones(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "M1", models.Milestone{MilestoneID: "M1", MilestoneTranslationID: "TRANS1", TrackID: "T1", Version: "1", DocType: MilestoneDocType})
+	testsupport.PutJSON(stub, "M2", models.Milestone{MilestoneID: "M2", MilestoneTranslationID: "TRANS2", TrackID: "T1", Version: "1", DocType: MilestoneDocType})
+	testsupport.PutJSON(stub, "D1", models.MilestoneDependency{ID: "D1", DependingMilestone: "M2", MilestoneID: "M1", DocType: MilestoneDependencyDocType})
+}
+
+func TestMilestoneInvoke(t *testing.T) {
+	testsupport.RunInvokeCases(t, newMilestoneStub, []testsupport.InvokeCase{
+		{Name: "GetAllByQuery returns the milestones", Function: "GetAllByQuery", Setup: putMilestones,
+			Check: testsupport.ExpectCount(2)},
+		{Name: "CreateMilestone stores the milestone", Function: "CreateMilestone", Args: []string{"TRANS3", "T1", "1"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "CreateMilestone with missing arguments", Function: "CreateMilestone", Args: []string{"TRANS3"}, Code: errs.InvalidArgument},
+		{Name: "GetMilestoneByID returns the milestone", Function: "GetMilestoneByID", Args: []string{"M1"}, Setup: putMilestones,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var milestone models.Milestone
+				json.Unmarshal(res.Payload, &milestone)
+				if milestone.MilestoneID != "M1" {
+					t.Errorf("Expected the milestone M1 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetMilestoneByID of an unknown milestone", Function: "GetMilestoneByID", Args: []string{"M9"}, Code: errs.NotFound},
+		{Name: "GetMilestoneByID with missing arguments", Function: "GetMilestoneByID", Code: errs.InvalidArgument},
+		{Name: "UpdateMilestone changes the milestone", Function: "UpdateMilestone", Args: []string{"M1", "TRANS9", "T2", "2"}, Setup: putMilestones,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var milestone models.Milestone
+				json.Unmarshal(stub.State["M1"], &milestone)
+				if milestone.TrackID != "T2" || milestone.Version != "2" {
+					t.Errorf("Expected the updated milestone but got %s", string(stub.State["M1"]))
+				}
+			}},
+		{Name: "UpdateMilestone of an unknown milestone", Function: "UpdateMilestone", Args: []string{"M9", "TRANS9", "T2", "2"}, Code: errs.NotFound},
+		{Name: "UpdateMilestone with missing arguments", Function: "UpdateMilestone", Args: []string{"M1", "TRANS9", "T2"}, Setup: putMilestones, Code: errs.InvalidArgument},
+		{Name: "UpdateMilestone with an invalid version", Function: "UpdateMilestone", Args: []string{"M1", "TRANS9", "T2", "v2"}, Setup: putMilestones, Code: errs.InvalidArgument},
+		{Name: "DeleteRecord removes the dependency", Function: "DeleteRecord", Args: []string{"D1"}, Setup: putMilestones,
+			Check: testsupport.ExpectNoState("D1")},
+		{Name: "DeleteRecord of a milestone with dependencies", Function: "DeleteRecord", Args: []string{"M1"}, Setup: putMilestones, Status: shim.ERROR},
+		{Name: "DeleteRecord cascades to the dependencies", Function: "DeleteRecord", Args: []string{"M1", "cascade"}, Setup: putMilestones,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["M1"]) > 0 || len(stub.State["D1"]) > 0 || len(stub.State["M2"]) == 0 {
+					t.Errorf("Expected the milestone M1 and its dependency deleted")
+				}
+			}},
+		{Name: "DeleteRecord with missing arguments", Function: "DeleteRecord", Code: errs.InvalidArgument},
+		{Name: "DeleteRecord of an unknown milestone", Function: "DeleteRecord", Args: []string{"M9"}, Code: errs.NotFound},
+		{Name: "CreateMilestoneDependency stores the dependency", Function: "CreateMilestoneDependency", Args: []string{"M1", "M2"}, Setup: putMilestones,
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "CreateMilestoneDependency twice", Function: "CreateMilestoneDependency", Args: []string{"M2", "M1"}, Setup: putMilestones, Code: errs.AlreadyExists},
+		{Name: "CreateMilestoneDependency of an unknown milestone", Function: "CreateMilestoneDependency", Args: []string{"M1", "M9"}, Setup: putMilestones, Code: errs.NotFound},
+		{Name: "CreateMilestoneDependency with missing arguments", Function: "CreateMilestoneDependency", Args: []string{"M1"}, Code: errs.InvalidArgument},
+		{Name: "GetDependingsByID returns the dependencies", Function: "GetDependingsByID", Args: []string{"M2"}, Setup: putMilestones,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetDependingsByID with missing arguments", Function: "GetDependingsByID", Code: errs.InvalidArgument},
+		{Name: "UpdateMilestoneDependency changes the dependency", Function: "UpdateMilestoneDependency", Args: []string{"D1", "M1", "M2"}, Setup: putMilestones,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var dependency models.MilestoneDependency
+				json.Unmarshal(stub.State["D1"], &dependency)
+				if dependency.DependingMilestone != "M1" || dependency.MilestoneID != "M2" {
+					t.Errorf("Expected the updated dependency but got %s", string(stub.State["D1"]))
+				}
+			}},
+		{Name: "UpdateMilestoneDependency of an unknown dependency", Function: "UpdateMilestoneDependency", Args: []string{"D9", "M1", "M2"}, Code: errs.NotFound},
+		{Name: "GetTrackMilestones orders the milestones by dependency", Function: "GetTrackMilestones", Args: []string{"T1"},
+			Setup: func(stub *testsupport.Stub) {
+				putMilestones(stub)
+				testsupport.PutJSON(stub, "MS1", models.MilestoneSkill{ID: "MS1", MilestoneID: "M2", SkillID: "S2", DocType: MilestoneSkillDocType})
+				testsupport.PutJSON(stub, "MS2", models.MilestoneSkill{ID: "MS2", MilestoneID: "M1", SkillID: "S1", DocType: MilestoneSkillDocType})
+			},
+			Check: testsupport.ExpectPayload(`[{"milestoneid":"M1","order":1,"skillids":["S1"]},{"milestoneid":"M2","order":2,"skillids":["S2"]}]`)},
+		{Name: "GetTrackMilestones of a track without milestones", Function: "GetTrackMilestones", Args: []string{"T9"}, Setup: putMilestones,
+			Check: testsupport.ExpectPayload(`[]`)},
+		{Name: "GetTrackMilestones with missing arguments", Function: "GetTrackMilestones", Code: errs.InvalidArgument},
+		{Name: "GetReferences returns the milestones of a track", Function: "GetReferences", Args: []string{MilestoneDocType, models.MilestoneTrackIDColumnName, "T1"}, Setup: putMilestones,
+			Check: testsupport.ExpectPayload(`["M1","M2"]`)},
+		{Name: "DeleteReferences deletes the milestones of a track", Function: "DeleteReferences", Args: []string{MilestoneDocType, models.MilestoneTrackIDColumnName, "T1"}, Setup: putMilestones,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["M1"]) > 0 || len(stub.State["M2"]) > 0 || len(stub.State["D1"]) > 0 {
+					t.Errorf("Expected the milestones of the track and their dependency deleted")
+				}
+			}},
+		{Name: "DeleteReferences without the track management feature", Function: "DeleteReferences", Args: []string{MilestoneDocType, models.MilestoneTrackIDColumnName, "T1"},
+			Setup: func(stub *testsupport.Stub) {
+				putMilestones(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "DeleteReferences of a column no chaincode references", Function: "DeleteReferences", Args: []string{MilestoneDocType, "version", "1"}, Setup: putMilestones,
+			Code: errs.InvalidArgument},
+		{Name: "CreateMilestone by a manager of the track", Function: "CreateMilestone", Args: []string{"TRANS3", "T1", "1"}, Setup: asMilestoneManager,
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "CreateMilestone by a manager of another track", Function: "CreateMilestone", Args: []string{"TRANS3", "T2", "1"}, Setup: asMilestoneManager,
+			Code: errs.PermissionDenied},
+		{Name: "UpdateMilestone moving the milestone to a track the caller does not manage", Function: "UpdateMilestone", Args: []string{"M1", "TRANS9", "T2", "2"},
+			Setup: asMilestoneManager, Code: errs.PermissionDenied},
+		{Name: "DeleteRecord of a dependency by a manager of the track", Function: "DeleteRecord", Args: []string{"D1"}, Setup: asMilestoneManager,
+			Check: testsupport.ExpectNoState("D1")},
+		{Name: "Restore of a milestone of a track the caller does not manage", Function: "Restore", Args: []string{"M3"}, Setup: asMilestoneManager,
+			Code: errs.PermissionDenied},
+		{Name: "ListArchived skips the milestones of the tracks the caller does not manage", Function: "ListArchived", Args: []string{MilestoneDocType},
+			Setup: asMilestoneManager, Check: testsupport.ExpectCount(0)},
+		{Name: "Restore brings back the archived milestone", Function: "Restore", Args: []string{"M3"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.PutRecord("M3", []byte(`{"milestoneid":"M3","trackid":"T2","version":"1","doctype":"milestone","deleted":true}`))
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if repository.IsArchived(stub.State["M3"]) {
+					t.Errorf("Expected the milestone restored but got %s", string(stub.State["M3"]))
+				}
+			}},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
@@ -1,94 +1,189 @@
-package main
-
-import (
-	"encoding/json"
-
-	"github.com/beevik/guid"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-	"github.com/skillbill/models"
-)
-
-func (m MilestoneChaincode) GetAllMilestones(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	result, err := milestoneRepo.GetAll(APIstub)
-
-	if err != nil {
-		return shim.Error("Failed to query milestone due to " + err.Error())
-	}
-	return shim.Success(result)
-}
-
-func (m MilestoneChaincode) GetMilestoneByID(APIstub shim.ChaincodeStubInterface, key string) sc.Response {
-	value, err := milestoneRepo.GetByKey(APIstub, key)
-
-	if err != nil {
-		return shim.Error("Failed to get milestone due to: " + err.Error())
-	}
-
-	if string(value) == "" {
-		return shim.Error("Failed to get milestone because the milestone " + key + " does not exist.")
-	}
-
-	return shim.Success(value)
-}
-
-func (m MilestoneChaincode) CreateMilestone(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
-	}
-
-	var mst = models.Milestone{
-		MilestoneID:            guid.New().StringUpper(),
-		MilestoneTranslationID: args[0],
-		TrackID:                args[1],
-		Version:                args[2],
-		DocType:                MilestoneDocType}
-
-	data, _ := json.Marshal(mst)
-	err := milestoneRepo.Save(APIstub, mst.MilestoneID, data)
-
-	if err != nil {
-		return shim.Error("Failed to create milestone due to: " + err.Error())
-	}
-
-	return shim.Success([]byte(mst.MilestoneID))
-}
-
-func (m MilestoneChaincode) UpdateMilestone(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-	data, err := milestoneRepo.GetByKey(APIstub, args[0])
-
-	if err != nil {
-		return shim.Error("Failed to update the milestone: " + args[0] + "due to: " + err.Error())
-	}
-
-	if len(string(data)) == 0 {
-		return shim.Error("Failed to update the milestone, because it does not exist.")
-	}
-
-	mst := models.Milestone{}
-	json.Unmarshal(data, &mst)
-	mst.MilestoneTranslationID = args[1]
-	mst.TrackID = args[2]
-	mst.Version = args[3]
-	data, _ = json.Marshal(mst)
-
-	err = milestoneRepo.Save(APIstub, mst.MilestoneID, data)
-
-	if err != nil {
-		return shim.Error("Failed to update the milestone: " + args[0] + "due to: " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
-
-func (m MilestoneChaincode) DeleteRecord(APIstub shim.ChaincodeStubInterface, key string) sc.Response {
-	err := milestoneRepo.Delete(APIstub, key)
-
-	if err != nil {
-		return shim.Error("Failed to delete record with key : " + key + " due to " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+func (m MilestoneChaincode) GetAllMilestones(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	result, err := milestoneRepo.GetAll(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to query milestone"))
+	}
+	return shim.Success(result)
+}
+
+func (m MilestoneChaincode) GetMilestoneByID(APIstub shim.ChaincodeStubInterface, key string) sc.Response {
+	value, err := milestoneRepo.GetByKey(APIstub, key)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get milestone"))
+	}
+
+	if string(value) == "" {
+		return errs.Fail(errs.NotFound, "Failed to get milestone because the milestone " + key + " does not exist.")
+	}
+
+	return shim.Success(value)
+}
+
+func (m MilestoneChaincode) CreateMilestone(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	err := checkTrackPermission(APIstub, args[1])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create milestone"))
+	}
+
+	var mst = models.Milestone{
+		MilestoneID:            utils.NewID(APIstub, MilestoneDocType, args[0], args[1], args[2]),
+		MilestoneTranslationID: args[0],
+		TrackID:                args[1],
+		Version:                args[2],
+		DocType:                MilestoneDocType}
+
+	data, _ := json.Marshal(mst)
+	err = milestoneRepo.Create(APIstub, mst.MilestoneID, data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create milestone"))
+	}
+
+	return shim.Success([]byte(mst.MilestoneID))
+}
+
+func (m MilestoneChaincode) UpdateMilestone(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+	data, err := milestoneRepo.GetByKey(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update the milestone " + args[0]))
+	}
+
+	if len(string(data)) == 0 {
+		return errs.Fail(errs.NotFound, "Failed to update the milestone, because it does not exist.")
+	}
+
+	mst := models.Milestone{}
+	json.Unmarshal(data, &mst)
+
+	// The milestone is moved from its track to the new one, both must be managed by the caller
+	for _, trackID := range []string{mst.TrackID, args[2]} {
+		if err := checkTrackPermission(APIstub, trackID); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to update the milestone " + args[0]))
+		}
+	}
+
+	mst.MilestoneTranslationID = args[1]
+	mst.TrackID = args[2]
+	mst.Version = args[3]
+	data, _ = json.Marshal(mst)
+
+	err = milestoneRepo.Save(APIstub, mst.MilestoneID, data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update the milestone " + args[0]))
+	}
+
+	return shim.Success(nil)
+}
+
+// args[0] is the key of the record, args[1] is the optional delete mode: restrict (default), cascade or soft
+func (m MilestoneChaincode) DeleteRecord(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+	var key = args[0]
+
+	mode, err := core.GetDeleteMode(args, 1)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = canDeleteRecord(APIstub, key)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete record with key : " + key))
+	}
+
+	err = integrity.Delete(APIstub, key, mode)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete record with key : " + key))
+	}
+
+	return shim.Success(nil)
+}
+
+// canDeleteRecord checks the caller can manage the milestones of the track of a milestone, a dependency or a milestone skill,
+// to delete, restore or list it among the archived records
+func canDeleteRecord(APIstub shim.ChaincodeStubInterface, key string) error {
+	return checkRecordPermission(APIstub, key)
+}
+
+// checkRecordPermission returns an error unless the caller can manage the milestones of the tracks of the records
+func checkRecordPermission(APIstub shim.ChaincodeStubInterface, keys ...string) error {
+	for _, key := range keys {
+		trackIDs, err := getTrackIDs(APIstub, key)
+
+		if err != nil {
+			return err
+		}
+
+		for _, trackID := range trackIDs {
+			if err := checkTrackPermission(APIstub, trackID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTrackPermission returns an error unless the caller can manage the milestones, globally or within the track
+func checkTrackPermission(APIstub shim.ChaincodeStubInterface, trackID string) error {
+	return core.NewAccessControl().CheckPermission(APIstub, core.MilestoneManagementFeatureID, "1", trackID)
+}
+
+// getTrackIDs returns the track of a milestone, or the tracks of the milestones of a dependency or a milestone skill
+func getTrackIDs(APIstub shim.ChaincodeStubInterface, key string) ([]string, error) {
+	data, err := milestoneRepo.GetByKey(APIstub, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var record struct {
+		models.Milestone
+		DependingMilestone string `json:"dependingmilestone"`
+	}
+	json.Unmarshal(data, &record)
+
+	if record.DocType == MilestoneDocType {
+		return []string{record.TrackID}, nil
+	}
+
+	trackIDs := []string{}
+	for _, milestoneID := range []string{record.MilestoneID, record.DependingMilestone} {
+		if milestoneID == "" {
+			continue
+		}
+
+		data, err := milestoneRepo.GetByKey(APIstub, milestoneID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mst := models.Milestone{}
+		json.Unmarshal(data, &mst)
+		trackIDs = append(trackIDs, mst.TrackID)
+	}
+
+	return trackIDs, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// GetTrackMilestones returns the milestones of a track with their skills, a milestone comes after the milestones it depends on
+// arg[0] : track id
+func (m MilestoneChaincode) GetTrackMilestones(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+	var trackID = args[0]
+	var query = `{"selector":{"` + models.DocTypeColumnName + `":"` + MilestoneDocType + `","` + models.MilestoneTrackIDColumnName + `":"` + trackID + `"}}`
+
+	data, err := milestoneRepo.GetByQuery(APIstub, query)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the milestones of track " + trackID))
+	}
+
+	var milestones []models.Milestone
+	json.Unmarshal(data, &milestones)
+
+	ids := []string{}
+	for _, milestone := range milestones {
+		ids = append(ids, milestone.MilestoneID)
+	}
+	sort.Strings(ids)
+
+	data, err = milestoneDependencyRepo.GetAll(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the milestone dependencies"))
+	}
+
+	var dependencies []models.MilestoneDependency
+	json.Unmarshal(data, &dependencies)
+
+	dependsOn := map[string][]string{}
+	for _, dependency := range dependencies {
+		dependsOn[dependency.DependingMilestone] = append(dependsOn[dependency.DependingMilestone], dependency.MilestoneID)
+	}
+
+	ordered, err := utils.SortByDependencies(ids, dependsOn)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to order the milestones of track " + trackID))
+	}
+
+	selector := map[string]interface{}{models.DocTypeColumnName: MilestoneSkillDocType, models.MilestoneIDColumnName: map[string]interface{}{"$in": ids}}
+	skillQuery, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	data, err = milestoneSkillRepo.GetByQuery(APIstub, string(skillQuery))
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the skills of track " + trackID))
+	}
+
+	var milestoneSkills []models.MilestoneSkill
+	json.Unmarshal(data, &milestoneSkills)
+
+	skillIDs := map[string][]string{}
+	for _, milestoneSkill := range milestoneSkills {
+		skillIDs[milestoneSkill.MilestoneID] = append(skillIDs[milestoneSkill.MilestoneID], milestoneSkill.SkillID)
+	}
+
+	result := []models.TrackMilestone{}
+	for i, id := range ordered {
+		skills := append([]string{}, skillIDs[id]...)
+		sort.Strings(skills)
+
+		result = append(result, models.TrackMilestone{MilestoneID: id, Order: i + 1, SkillIDs: skills})
+	}
+
+	data, _ = json.Marshal(result)
+
+	return shim.Success(data)
+}
@@ -1,7 +1,7 @@
-package main
-
-type Right struct {
-	RightID   string `json:"rightid"`
-	RightName string `json:"rightname"`
-	DocType   string `json:"doctype"`
-}
+package main
+
+type Right struct {
+	RightID   string `json:"rightid"`
+	RightName string `json:"rightname"`
+	DocType   string `json:"doctype"`
+}
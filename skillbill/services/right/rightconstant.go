@@ -1,3 +1,7 @@
-package main
-
-const RightTableName string = "right"
+package main
+
+import (
+	"github.com/skillbill/models"
+)
+
+const RightTableName string = models.RightDocType
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+func newRightStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("right", new(RightService), testsupport.MustNewIdentity("Org1MSP", "admin", nil))
+	testsupport.MustInit(t, stub)
+
+	return stub
+}
+
+func TestRightInvoke(t *testing.T) {
+	testsupport.RunInvokeCases(t, newRightStub, []testsupport.InvokeCase{
+		{Name: "addRight stores the right", Function: "addRight", Args: []string{"1", "Read"},
+			Check: testsupport.ExpectState(RightTableName + "1")},
+		{Name: "addRight with missing arguments", Function: "addRight", Args: []string{"1"}, Code: errs.InvalidArgument},
+		{Name: "getAllRights returns the rights", Function: "getAllRights",
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, RightTableName+"1", Right{RightID: "1", RightName: "Read", DocType: RightTableName})
+			},
+			Check: testsupport.ExpectCount(1)},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
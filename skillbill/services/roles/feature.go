@@ -1,7 +1,7 @@
-package main
-
-type Feature struct{
-	FeatureID		string	`json:"featureid"`
-	FeatureName		string	`json:"featurename"`
-	DocType			string	`json:"doctype"`
+package main
+
+type Feature struct{
+	FeatureID		string	`json:"featureid"`
+	FeatureName		string	`json:"featurename"`
+	DocType			string	`json:"doctype"`
 }
\ No newline at end of file
@@ -1,8 +1,8 @@
-package main
-
-// Role model
-type Role struct {
-	RoleID			string	`json:"roleid"`
-	RoleName		string	`json:"rolename"`
-	DocType			string	`json:"doctype"`
+package main
+
+// Role model
+type Role struct {
+	RoleID			string	`json:"roleid"`
+	RoleName		string	`json:"rolename"`
+	DocType			string	`json:"doctype"`
 }
\ No newline at end of file
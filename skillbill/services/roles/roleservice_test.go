@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/testsupport"
+	"github.com/skillbill/packages/utils"
+)
+
+// administrator holds the seeded Administrators role, granted every feature by the role features of the ledger
+var administrator = testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": utils.NameID(models.RoleDocType, "Administrators")})
+
+func newRoleStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("role", new(Role), administrator)
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("security", testsupport.NewSecurityFake().WithReferences())
+
+	return stub
+}
+
+func putRoleWithFeature(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "R1", Role{RoleID: "R1", RoleName: "Reviewers", DocType: "role"})
+	testsupport.PutJSON(stub, "RF1", RoleFeature{ID: "RF1", AccessLevel: ReadWrite, RoleID: "R1", FeatureID: "F1", DocType: "rolefeature"})
+}
+
+// seededRecords is the number of records stored by Init, the roles, the role features and the trusted MSPs
+const seededRecords int = 16
+
+func TestRoleSeeds(t *testing.T) {
+	stub := newRoleStub(t)
+	if stub.Keys.Len() != seededRecords {
+		t.Fatalf("Expected 4 roles, 11 role features and the trusted MSPs but got %d records", stub.Keys.Len())
+	}
+
+	seeded := map[string]string{}
+	for key, value := range stub.State {
+		seeded[key] = string(value)
+	}
+
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+	for key, value := range stub.State {
+		if seeded[key] != string(value) {
+			t.Errorf("Expected the seeds unchanged by a second Init but %s is %s", key, string(value))
+		}
+	}
+
+	if stub.Keys.Len() != seededRecords {
+		t.Errorf("Expected no duplicate seeds after a second Init but got %d records", stub.Keys.Len())
+	}
+}
+
+func TestRoleSeedsReconcileEarlierSeeding(t *testing.T) {
+	stub := testsupport.NewStub("role", new(Role), testsupport.MustNewIdentity("Org1MSP", "admin", nil))
+	testsupport.PutJSON(stub, "U1", Role{RoleID: "U1", RoleName: "Users", DocType: "role"})
+	testsupport.PutJSON(stub, "RF1", RoleFeature{ID: "RF1", AccessLevel: ReadWrite, RoleID: "U1", FeatureID: core.KnowledgeGroupFeatureID, DocType: "rolefeature"})
+
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	if stub.Keys.Len() != seededRecords {
+		t.Errorf("Expected the role Users and its feature to be kept but got %d records", stub.Keys.Len())
+	}
+
+	var roleFeature RoleFeature
+	json.Unmarshal(stub.State["RF1"], &roleFeature)
+	if roleFeature.RoleID != "U1" || roleFeature.AccessLevel != ReadOnly {
+		t.Errorf("Expected the feature of U1 reconciled to read only but got %s", string(stub.State["RF1"]))
+	}
+}
+
+func TestRoleSeedsRestoreArchivedSeeds(t *testing.T) {
+	stub := testsupport.NewStub("role", new(Role), testsupport.MustNewIdentity("Org1MSP", "admin", nil))
+	stub.PutRecord("U1", []byte(`{"roleid":"U1","rolename":"Users","doctype":"role","deleted":true,"deletedby":"admin"}`))
+	stub.PutRecord("RF1", []byte(`{"id":"RF1","accesslevel":0,"roleid":"U1","featureid":"`+core.KnowledgeGroupFeatureID+`","doctype":"rolefeature","deleted":true}`))
+
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	if stub.Keys.Len() != seededRecords {
+		t.Errorf("Expected the archived role Users and its feature to be matched but got %d records", stub.Keys.Len())
+	}
+
+	if repository.IsArchived(stub.State["U1"]) || repository.IsArchived(stub.State["RF1"]) {
+		t.Errorf("Expected the seeds restored but got %s and %s", string(stub.State["U1"]), string(stub.State["RF1"]))
+	}
+}
+
+func TestRoleInvoke(t *testing.T) {
+	users := testsupport.NewSecurityFake().WithReferences("alice")
+
+	testsupport.RunInvokeCases(t, newRoleStub, []testsupport.InvokeCase{
+		{Name: "getAllByQuery filters by the columns", Function: "getAllByQuery", Args: []string{"doctype,role", "rolename,Reviewers"}, Setup: putRoleWithFeature,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "createRole stores the role", Function: "createRole", Args: []string{"Managers"},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var role Role
+				json.Unmarshal(stub.State[string(res.Payload)], &role)
+				if role.RoleName != "Managers" {
+					t.Errorf("Expected the role Managers but got %s", string(stub.State[string(res.Payload)]))
+				}
+			}},
+		{Name: "createRole with missing arguments", Function: "createRole", Code: errs.InvalidArgument},
+		{Name: "createRole by a caller who can not manage the roles", Function: "createRole", Args: []string{"Managers"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "deleteRole removes the role", Function: "deleteRole", Args: []string{"R2"},
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, "R2", Role{RoleID: "R2", RoleName: "Guests", DocType: "role"})
+			},
+			Check: testsupport.ExpectNoState("R2")},
+		{Name: "deleteRole of a role with features", Function: "deleteRole", Args: []string{"R1"}, Setup: putRoleWithFeature, Code: errs.Conflict},
+		{Name: "deleteRole cascades to the features and the users", Function: "deleteRole", Args: []string{"R1", "cascade"},
+			Setup: func(stub *testsupport.Stub) {
+				putRoleWithFeature(stub)
+				stub.MockPeer("security", users)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["R1"]) > 0 || len(stub.State["RF1"]) > 0 || !users.Called("ClearReferences") {
+					t.Errorf("Expected the role deleted with its features and cleared from the users")
+				}
+			}},
+		{Name: "deleteRole of an unknown role", Function: "deleteRole", Args: []string{"R2"}, Code: errs.NotFound},
+		{Name: "deleteRole without the role management feature", Function: "deleteRole", Args: []string{"R1"},
+			Setup: func(stub *testsupport.Stub) {
+				putRoleWithFeature(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "assignFeature stores the role feature", Function: "assignFeature", Args: []string{"1", "R1", "F2"}, Setup: putRoleWithFeature,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if stub.Keys.Len() != seededRecords+3 {
+					t.Errorf("Expected a new role feature but got %d records", stub.Keys.Len())
+				}
+			}},
+		{Name: "assignFeature with an invalid access level", Function: "assignFeature", Args: []string{"5", "R1", "F2"}, Code: errs.InvalidArgument},
+		{Name: "assignFeature with an access level which is not a number", Function: "assignFeature", Args: []string{"write", "R1", "F2"}, Code: errs.InvalidArgument},
+		{Name: "assignFeature with missing arguments", Function: "assignFeature", Args: []string{"1"}, Code: errs.InvalidArgument},
+		{Name: "assignFeature by a caller who can not manage the roles", Function: "assignFeature", Args: []string{"1", "R1", "F2"},
+			Setup: func(stub *testsupport.Stub) {
+				putRoleWithFeature(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "removeFeature removes the role feature", Function: "removeFeature", Args: []string{"R1", "F1"}, Setup: putRoleWithFeature,
+			Check: testsupport.ExpectNoState("RF1")},
+		{Name: "removeFeature with missing arguments", Function: "removeFeature", Args: []string{"R1"}, Code: errs.InvalidArgument},
+		{Name: "removeFeature by a caller who can not manage the roles", Function: "removeFeature", Args: []string{"R1", "F1"},
+			Setup: func(stub *testsupport.Stub) {
+				putRoleWithFeature(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Check: testsupport.ExpectState("RF1"), Code: errs.PermissionDenied},
+		{Name: "getFeaturesByRoleIDs returns the features of the roles", Function: "getFeaturesByRoleIDs", Args: []string{"R1,R2"}, Setup: putRoleWithFeature,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getFeaturesByRoleIDs with missing arguments", Function: "getFeaturesByRoleIDs", Code: errs.InvalidArgument},
+		{Name: "migrateFieldNames rewrites mis-cased records", Function: "migrateFieldNames",
+			Setup: func(stub *testsupport.Stub) {
+				stub.PutRecord("RF2", []byte(`{"ID":"RF2","AccessLevel":0,"RoleID":"R1","FeatureID":"F2","DocType":"rolefeature"}`))
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if string(res.Payload) != "1" || !strings.Contains(string(stub.State["RF2"]), `"roleid":"R1"`) {
+					t.Errorf("Expected the migrated role feature but got %s", string(stub.State["RF2"]))
+				}
+			}},
+		{Name: "GetReferences returns the role features of a feature", Function: "GetReferences", Args: []string{"rolefeature", "featureid", "F1"}, Setup: putRoleWithFeature,
+			Check: testsupport.ExpectPayload(`["RF1"]`)},
+		{Name: "DeleteReferences removes the role features of a feature", Function: "DeleteReferences", Args: []string{"rolefeature", "featureid", "F1"}, Setup: putRoleWithFeature,
+			Check: testsupport.ExpectNoState("RF1")},
+		{Name: "DeleteReferences without the feature management feature", Function: "DeleteReferences", Args: []string{"rolefeature", "featureid", "F1"},
+			Setup: func(stub *testsupport.Stub) {
+				putRoleWithFeature(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "DeleteReferences of a column no chaincode references", Function: "DeleteReferences", Args: []string{"role", "rolename", "Reviewers"}, Setup: putRoleWithFeature,
+			Code: errs.InvalidArgument},
+		{Name: "GetReferences with missing arguments", Function: "GetReferences", Args: []string{"rolefeature"}, Code: errs.InvalidArgument},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+// roleSeeds are the roles the chaincode is instantiated with
+var roleSeeds = []string{"Administrators", "ProfessionalGroupAdministrators", "SkillAdministartors", "Users"}
+
+// roleFeatureSeeds are the features of the seeded roles, by role name
+var roleFeatureSeeds = []struct {
+	RoleName    string
+	FeatureID   string
+	AccessLevel int
+}{
+	{"Administrators", core.SkillPlanManagementFeatureID, ReadWrite},
+	{"Administrators", core.SkillManagementFeatureID, ReadWrite},
+	{"Administrators", core.TrackManagementFeatureID, ReadWrite},
+	{"Administrators", core.MilestoneManagementFeatureID, ReadWrite},
+	{"Administrators", core.UserManagementFeatureID, ReadWrite},
+	{"Administrators", core.RoleManagementFeatureID, ReadWrite},
+	{"Administrators", core.KnowledgeGroupFeatureID, ReadWrite},
+	{"Administrators", core.FeatureManagementFeatureID, ReadWrite},
+	{"Administrators", core.TranslationManagementFeatureID, ReadWrite},
+	// The users read the roles and the knowledge groups, the groups are managed by the roles assigned within them
+	{"Users", core.RoleManagementFeatureID, ReadOnly},
+	{"Users", core.KnowledgeGroupFeatureID, ReadOnly},
+}
+
+// seedRoles reconciles the seeded roles and their features with the ledger.
+// A role is matched by its name and a role feature by its role and feature, so the records of an earlier seeding keep their ids
+// and the users keep their role, an archived record is matched as well and restored. A new record has an id derived from its names,
+// the same on every peer.
+func seedRoles(APIstub shim.ChaincodeStubInterface) (int, error) {
+	var roles []Role
+	err := getSeededRecords(APIstub, models.RoleDocType, &roles)
+	if err != nil {
+		return 0, err
+	}
+
+	roleIDs := map[string]string{}
+	for _, role := range roles {
+		if _, found := roleIDs[role.RoleName]; !found {
+			roleIDs[role.RoleName] = role.RoleID
+		}
+	}
+
+	var roleFeatures []RoleFeature
+	err = getSeededRecords(APIstub, models.RoleFeatureDocType, &roleFeatures)
+	if err != nil {
+		return 0, err
+	}
+
+	roleFeatureIDs := map[string]string{}
+	for _, roleFeature := range roleFeatures {
+		if key := roleFeature.RoleID + "," + roleFeature.FeatureID; roleFeatureIDs[key] == "" {
+			roleFeatureIDs[key] = roleFeature.ID
+		}
+	}
+
+	seeds := []repository.Seed{}
+	for _, name := range roleSeeds {
+		if roleIDs[name] == "" {
+			roleIDs[name] = utils.NameID(models.RoleDocType, name)
+		}
+
+		seeds = append(seeds, repository.Seed{Key: roleIDs[name], Record: Role{RoleID: roleIDs[name], RoleName: name, DocType: models.RoleDocType}})
+	}
+
+	for _, seed := range roleFeatureSeeds {
+		var roleID = roleIDs[seed.RoleName]
+		var id = roleFeatureIDs[roleID+","+seed.FeatureID]
+		if id == "" {
+			id = utils.NameID(models.RoleFeatureDocType, roleID, seed.FeatureID)
+		}
+
+		seeds = append(seeds, repository.Seed{Key: id, Record: RoleFeature{ID: id, AccessLevel: seed.AccessLevel, RoleID: roleID, FeatureID: seed.FeatureID, DocType: models.RoleFeatureDocType}})
+	}
+
+	return repository.UpsertSeeds(APIstub, seeds)
+}
+
+// getSeededRecords reads the records of a doc type, the active ones followed by the archived ones
+func getSeededRecords(APIstub shim.ChaincodeStubInterface, docType string, records interface{}) error {
+	active, err := ccInstance.GetByQuery(APIstub, `{"selector":{"`+models.DocTypeColumnName+`":"`+docType+`"}}`)
+	if err != nil {
+		return err
+	}
+
+	archived, err := repository.GetArchived(APIstub, docType)
+	if err != nil {
+		return err
+	}
+
+	var all []json.RawMessage
+	for _, data := range [][]byte{active, archived} {
+		var items []json.RawMessage
+		json.Unmarshal(data, &items)
+		all = append(all, items...)
+	}
+
+	data, _ := json.Marshal(all)
+
+	return json.Unmarshal(data, records)
+}
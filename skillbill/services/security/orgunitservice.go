@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// DirectReports is the optional argument of GetReports to skip the indirect reports
+const DirectReports string = "direct"
+
+// CreateOrgUnit adds a department, only the users who can manage the users can
+// args[0] is org unit name, args[1] is the optional id of the parent org unit
+func (s *SecurityChaincode) CreateOrgUnit(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	var orgUnit = models.OrgUnit{OrgUnitID: utils.NewID(APIstub, models.OrgUnitDocType, args[0]), OrgUnitName: args[0], DocType: models.OrgUnitDocType}
+
+	if len(args) == 2 && args[1] != "" {
+		if _, err := getOrgUnit(APIstub, args[1]); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to create org unit " + args[0]))
+		}
+
+		orgUnit.ParentID = args[1]
+	}
+
+	data, _ := json.Marshal(orgUnit)
+
+	err = orgUnitRepo.Create(APIstub, orgUnit.OrgUnitID, data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create org unit " + args[0]))
+	}
+
+	return shim.Success([]byte(orgUnit.OrgUnitID))
+}
+
+// GetOrgUnits returns every department, to the users who can read the users and to the managers
+func (s *SecurityChaincode) GetOrgUnits(APIstub shim.ChaincodeStubInterface) pb.Response {
+
+	reports, err := getVisibleReports(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get all org units"))
+	}
+
+	if reports != nil && len(reports) == 0 {
+		return errs.Fail(errs.PermissionDenied, "Permission denied, the caller can not see the org units")
+	}
+
+	orgUnits, err := orgUnitRepo.GetAll(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get all org units"))
+	}
+
+	return shim.Success(orgUnits)
+}
+
+// GetOrgUnitUsers returns the users of a department and of the departments below it.
+// The users who can read the users see all of them, a manager only sees their reports.
+// args[0] is org unit id
+func (s *SecurityChaincode) GetOrgUnitUsers(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var orgUnitID = args[0]
+
+	reports, err := getVisibleReports(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the users of org unit " + orgUnitID))
+	}
+
+	if reports != nil && len(reports) == 0 {
+		return errs.Fail(errs.PermissionDenied, "Permission denied, the caller can not see the users of org unit " + orgUnitID)
+	}
+
+	if _, err := getOrgUnit(APIstub, orgUnitID); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the users of org unit " + orgUnitID))
+	}
+
+	orgUnitIDs, err := getOrgUnitTree(APIstub, orgUnitID)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the users of org unit " + orgUnitID))
+	}
+
+	selector := map[string]interface{}{models.DocTypeColumnName: UserTableName, models.UserOrgUnitIDColumnName: map[string]interface{}{"$in": orgUnitIDs}}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	data, err := userRepo.GetByQuery(APIstub, string(query))
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the users of org unit " + orgUnitID))
+	}
+
+	var users []models.User
+	json.Unmarshal(data, &users)
+
+	result := []models.User{}
+	for _, user := range users {
+		if reports == nil || reports[user.ADLogin] {
+			result = append(result, withoutSecret(user))
+		}
+	}
+
+	data, _ = json.Marshal(result)
+
+	return shim.Success(data)
+}
+
+// AssignUser places a user in a department and under a manager, an empty value removes the department or the manager.
+// A user can not report to themselves or to one of their reports.
+// args[0] is ad login, args[1] is the optional org unit id, args[2] is the optional ad login of the manager
+func (s *SecurityChaincode) AssignUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+	var orgUnitID = optionalArg(args, 1)
+	var managerID = optionalArg(args, 2)
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	user, err := getUser(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign user " + adLogin))
+	}
+
+	if orgUnitID != "" {
+		if _, err := getOrgUnit(APIstub, orgUnitID); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to assign user " + adLogin))
+		}
+	}
+
+	if managerID != "" {
+		if _, err := getUser(APIstub, managerID); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to assign user " + adLogin))
+		}
+
+		// The user manages the new manager when the manager is the user or one of their reports
+		cyclic, err := isManagerOf(APIstub, adLogin, managerID)
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to assign user " + adLogin))
+		}
+
+		if cyclic || managerID == adLogin {
+			return errs.Fail(errs.Conflict, "Failed to assign user " + adLogin + ", the user " + managerID + " reports to " + adLogin)
+		}
+	}
+
+	user.OrgUnitID = orgUnitID
+	user.ManagerID = managerID
+
+	data, _ := json.Marshal(user)
+
+	err = userRepo.Save(APIstub, userKey(user.MSPID, user.ADLogin), data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign user " + adLogin))
+	}
+
+	return shim.Success(nil)
+}
+
+// GetReports returns the users who report to a manager, directly or through other managers.
+// The manager, the managers above them and the users who can read the users can list the reports.
+// args[0] is ad login of the manager, args[1] is optional "direct" to return the direct reports only
+func (s *SecurityChaincode) GetReports(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var managerID = args[0]
+
+	allowed, err := canAccessUser(APIstub, managerID, core.UserManagementFeatureID, "0")
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the reports of " + managerID))
+	}
+
+	if !allowed {
+		return errs.Fail(errs.PermissionDenied, "Permission denied, the caller can not see the reports of " + managerID)
+	}
+
+	reports, err := getReports(APIstub, managerID, len(args) == 1 || args[1] != DirectReports)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the reports of " + managerID))
+	}
+
+	for i := range reports {
+		reports[i] = withoutSecret(reports[i])
+	}
+
+	data, _ := json.Marshal(reports)
+
+	return shim.Success(data)
+}
+
+// CheckUserAccess is true when the caller is the user, one of their direct or indirect managers,
+// or can access the feature with the access level. It lets the chaincodes protect the data of a user, e.g. the skill plans.
+// args[0] is ad login of the user, args[1] is feature id, args[2] is access level (0- readonly, 1- write and read)
+func (s *SecurityChaincode) CheckUserAccess(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	allowed, err := canAccessUser(APIstub, args[0], args[1], args[2])
+	if err != nil {
+		return errs.Response(errs.Wrapf(err, "Could not check the access to user %s", args[0]))
+	}
+
+	return shim.Success([]byte(strconv.FormatBool(allowed)))
+}
+
+func canAccessUser(stub shim.ChaincodeStubInterface, adLogin string, featureID string, accessLevel string) (bool, error) {
+
+	// A caller who is not registered can still be granted the feature by the roles of their certificate
+	if caller, err := getCurrentUser(stub); err == nil {
+		if caller.ADLogin == adLogin {
+			return true, nil
+		}
+
+		managed, err := isManagerOf(stub, caller.ADLogin, adLogin)
+		if err != nil || managed {
+			return managed, err
+		}
+	}
+
+	response := accessControl().CheckUserPermission(stub, featureID, accessLevel)
+	if response.Status != shim.OK {
+		return false, errs.FromResponse(response)
+	}
+
+	return string(response.Payload) == "true", nil
+}
+
+// getVisibleReports returns nil when the caller can read the users, otherwise the logins of their direct and indirect reports.
+// A caller who is not registered has no reports.
+func getVisibleReports(stub shim.ChaincodeStubInterface) (map[string]bool, error) {
+
+	response := accessControl().CheckUserPermission(stub, core.UserManagementFeatureID, "0")
+	if response.Status != shim.OK {
+		return nil, errs.FromResponse(response)
+	}
+
+	if string(response.Payload) == "true" {
+		return nil, nil
+	}
+
+	visible := map[string]bool{}
+
+	caller, err := getCurrentUser(stub)
+	if err != nil {
+		return visible, nil
+	}
+
+	reports, err := getReports(stub, caller.ADLogin, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, report := range reports {
+		visible[report.ADLogin] = true
+	}
+
+	return visible, nil
+}
+
+// isManagerOf is true when the user reports to the manager, directly or through other managers
+func isManagerOf(stub shim.ChaincodeStubInterface, managerID string, adLogin string) (bool, error) {
+	visited := map[string]bool{adLogin: true}
+
+	for current := adLogin; ; {
+		// A user who is not registered reports to nobody
+		user, err := getUser(stub, current)
+		if err != nil {
+			return false, nil
+		}
+
+		if user.ManagerID == "" {
+			return false, nil
+		}
+
+		if user.ManagerID == managerID {
+			return true, nil
+		}
+
+		if visited[user.ManagerID] {
+			return false, errs.Errorf(errs.Conflict, "The managers of %s are cyclic", adLogin)
+		}
+
+		visited[user.ManagerID] = true
+		current = user.ManagerID
+	}
+}
+
+// getReports returns the direct reports of the manager, followed by the indirect ones when asked for
+func getReports(stub shim.ChaincodeStubInterface, managerID string, indirect bool) ([]models.User, error) {
+	reports := []models.User{}
+	found := map[string]bool{managerID: true}
+
+	pending := []string{managerID}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		query := `{"selector":{"` + models.DocTypeColumnName + `":"` + UserTableName + `","` + models.UserManagerIDColumnName + `":"` + current + `"}}`
+		data, err := userRepo.GetByQuery(stub, query)
+		if err != nil {
+			return nil, err
+		}
+
+		var users []models.User
+		json.Unmarshal(data, &users)
+
+		for _, user := range users {
+			if found[user.ADLogin] {
+				continue
+			}
+
+			found[user.ADLogin] = true
+			reports = append(reports, user)
+
+			if indirect {
+				pending = append(pending, user.ADLogin)
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+// getOrgUnitTree returns the org unit followed by the org units below it
+func getOrgUnitTree(stub shim.ChaincodeStubInterface, orgUnitID string) ([]string, error) {
+	data, err := orgUnitRepo.GetAll(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgUnits []models.OrgUnit
+	json.Unmarshal(data, &orgUnits)
+
+	children := map[string][]string{}
+	for _, orgUnit := range orgUnits {
+		children[orgUnit.ParentID] = append(children[orgUnit.ParentID], orgUnit.OrgUnitID)
+	}
+
+	tree := []string{orgUnitID}
+	found := map[string]bool{orgUnitID: true}
+	for i := 0; i < len(tree); i++ {
+		for _, child := range children[tree[i]] {
+			if !found[child] {
+				found[child] = true
+				tree = append(tree, child)
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+// userKey is the key of a user document, the users of several MSPs can have the same common name
+func userKey(mspID string, adLogin string) string {
+	return utils.NameID(UserTableName, mspID, adLogin)
+}
+
+// getUserByIdentity returns the user registered with the MSP ID and the common name of a certificate
+func getUserByIdentity(stub shim.ChaincodeStubInterface, mspID string, adLogin string) (*models.User, error) {
+	var user *models.User
+
+	payload, err := userRepo.GetByKey(stub, userKey(mspID, adLogin))
+	if errs.Is(err, errs.NotFound) {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any user with name %s of %s", adLogin, mspID)
+	}
+
+	if err != nil {
+		return nil, errs.Wrapf(err, "Could not get user %s of %s", adLogin, mspID)
+	}
+
+	err = json.Unmarshal(payload, &user)
+	if err != nil {
+		return nil, errs.Errorf(errs.Internal, "Could not parse json to user %s of %s, err %s", adLogin, mspID, err)
+	}
+
+	if user.DocType != UserTableName {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any user with name %s of %s", adLogin, mspID)
+	}
+
+	return user, nil
+}
+
+// getUser returns the user with the ad login, which the other chaincodes refer to the user by.
+// An ad login registered by the users of several MSPs is a conflict.
+func getUser(stub shim.ChaincodeStubInterface, adLogin string) (*models.User, error) {
+	return findUser(stub, adLogin, map[string]interface{}{})
+}
+
+// findUser returns the only user with the ad login who matches the filters
+func findUser(stub shim.ChaincodeStubInterface, adLogin string, filters map[string]interface{}) (*models.User, error) {
+	filters[models.DocTypeColumnName] = UserTableName
+	filters[models.UserADLoginColumnName] = adLogin
+
+	query, _ := json.Marshal(map[string]interface{}{"selector": filters})
+
+	data, err := userRepo.GetByQuery(stub, string(query))
+	if err != nil {
+		return nil, errs.Wrapf(err, "Could not get user %s", adLogin)
+	}
+
+	var users []*models.User
+	json.Unmarshal(data, &users)
+
+	if len(users) == 0 {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any user with name %s", adLogin)
+	}
+
+	if len(users) > 1 {
+		return nil, errs.Errorf(errs.Conflict, "The user name %s is registered by the users of several MSPs", adLogin)
+	}
+
+	return users[0], nil
+}
+
+func getOrgUnit(stub shim.ChaincodeStubInterface, orgUnitID string) (*models.OrgUnit, error) {
+	var orgUnit *models.OrgUnit
+
+	payload, err := orgUnitRepo.GetByKey(stub, orgUnitID)
+	if err != nil {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any org unit with id %s", orgUnitID)
+	}
+
+	err = json.Unmarshal(payload, &orgUnit)
+	if err != nil || orgUnit.DocType != models.OrgUnitDocType {
+		return nil, errs.Errorf(errs.NotFound, "Could not find any org unit with id %s", orgUnitID)
+	}
+
+	return orgUnit, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// ApproveRegistration lets a registered user login, only the users who can manage the users can.
+// The role of the user can be elevated, the event "UserApproved" is set with the user.
+// args[0] is ad login, args[1] is the optional role id,
+// args[2] is the optional MSP ID, needed when the users of several MSPs registered the ad login
+func (s *SecurityChaincode) ApproveRegistration(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	user, err := getPendingUser(APIstub, adLogin, optionalArg(args, 2))
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to approve user " + adLogin))
+	}
+
+	if len(args) >= 2 && args[1] != "" && args[1] != user.RoleID {
+		if err := checkRole(APIstub, args[1]); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to approve user " + adLogin))
+		}
+
+		user.RoleID = args[1]
+	}
+
+	approvedBy, err := utils.GetCurrentUser(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get the caller"))
+	}
+
+	user.Pending = false
+	user.ApprovedBy = approvedBy
+
+	response := saveUser(APIstub, user, "approve")
+	if response.Status != shim.OK {
+		return response
+	}
+
+	event, _ := json.Marshal(withoutSecret(*user))
+
+	err = APIstub.SetEvent(UserApprovedEvent, event)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to approve user " + adLogin))
+	}
+
+	return response
+}
+
+// RejectRegistration removes a registered user and their secret, only the users who can manage the users can.
+// The user can register again, the event "UserRejected" is set with the user.
+// args[0] is ad login, args[1] is the optional MSP ID, needed when the users of several MSPs registered the ad login
+func (s *SecurityChaincode) RejectRegistration(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	user, err := getPendingUser(APIstub, adLogin, optionalArg(args, 1))
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to reject user " + adLogin))
+	}
+
+	if user.SecretHash != "" {
+		err = userSecretRepo.Delete(APIstub, userKey(user.MSPID, user.ADLogin))
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to reject user " + adLogin))
+		}
+	}
+
+	err = userRepo.Delete(APIstub, userKey(user.MSPID, user.ADLogin))
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to reject user " + adLogin))
+	}
+
+	event, _ := json.Marshal(withoutSecret(*user))
+
+	err = APIstub.SetEvent(UserRejectedEvent, event)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to reject user " + adLogin))
+	}
+
+	return shim.Success(nil)
+}
+
+// ListPendingRegistrations returns the users who registered and wait for the approval, only the users who can read the users can
+func (s *SecurityChaincode) ListPendingRegistrations(APIstub shim.ChaincodeStubInterface) pb.Response {
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "0")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	query := `{"selector":{"` + models.DocTypeColumnName + `":"` + UserTableName + `","` + models.UserPendingColumnName + `":true}}`
+	data, err := userRepo.GetByQuery(APIstub, query)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the pending registrations"))
+	}
+
+	var users []models.User
+	json.Unmarshal(data, &users)
+
+	result := []models.User{}
+	for _, user := range users {
+		result = append(result, withoutSecret(user))
+	}
+
+	data, _ = json.Marshal(result)
+
+	return shim.Success(data)
+}
+
+// getPendingUser returns the user with the ad login whose registration is pending, of the MSP when it is not empty
+func getPendingUser(stub shim.ChaincodeStubInterface, adLogin string, mspID string) (*models.User, error) {
+	filters := map[string]interface{}{models.UserPendingColumnName: true}
+	if mspID != "" {
+		filters[models.UserMSPIDColumnName] = mspID
+	}
+
+	user, err := findUser(stub, adLogin, filters)
+	if !errs.Is(err, errs.NotFound) {
+		return user, err
+	}
+
+	// The user is registered but has been approved
+	delete(filters, models.UserPendingColumnName)
+	if _, registered := findUser(stub, adLogin, filters); !errs.Is(registered, errs.NotFound) {
+		return nil, errs.Errorf(errs.Conflict, "The registration of user %s is not pending", adLogin)
+	}
+
+	return nil, err
+}
+
+// optionalArg returns the argument at the index, empty when it is not passed
+func optionalArg(args []string, index int) string {
+	if len(args) > index {
+		return args[index]
+	}
+
+	return ""
+}
+
+// getDefaultRoleID to get the id of the role the users get when they register, the role is seeded by the role chaincode
+func getDefaultRoleID(stub shim.ChaincodeStubInterface) (string, error) {
+	response := core.InvokeChaincode(stub, "role", "getAllByQuery", models.DocTypeColumnName+","+models.RoleDocType, models.RoleNameColumnName+","+DefaultRoleName)
+	if response.Status != shim.OK {
+		return "", errs.Wrapf(errs.FromResponse(response), "Could not get the role %s", DefaultRoleName)
+	}
+
+	var roles []models.Role
+	json.Unmarshal(response.Payload, &roles)
+
+	if len(roles) == 0 {
+		return "", errs.Errorf(errs.NotFound, "Could not find any role with name %s", DefaultRoleName)
+	}
+
+	return roles[0].RoleID, nil
+}
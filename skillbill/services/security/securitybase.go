@@ -1,62 +1,192 @@
-package main
-
-import (
-	"fmt"
-
-	"github.com/skillbill/packages/repository"
-
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-)
-
-const UserTableName string = "user"
-
-const UserPublicKeyColumnName string = "publickey"
-
-const UserADLoginColumnName string = "publickey"
-
-// SecurityChaincode provides functions to manage authorization
-type SecurityChaincode struct {
-}
-
-var userRepo repository.IRepo
-
-// ============================================================================================================================
-// Base Functions - Invoke | Init
-// ============================================================================================================================
-
-// Init method is called when the Smart Contract is instantiated by the blockchain network
-func (s *SecurityChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
-	userRepo = repository.InitRepo("user")
-	return shim.Success(nil)
-}
-
-// Invoke method is called as a result of an application request to run the Smart Contract
-func (s *SecurityChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	// Retrieve the requested Smart Contract function and arguments
-	function, args := APIstub.GetFunctionAndParameters()
-
-	if function == "ValidateLogin" {
-		return s.ValidateLogin(APIstub, args)
-	} else if function == "CheckUserPermission" {
-		return s.CheckUserPermission(APIstub, args)
-	} else if function == "RegisterUser" {
-		return s.RegisterUser(APIstub, args)
-	} else if function == "AddUser" {
-		return s.AddUser(APIstub, args)
-	} else if function == "GetAllUsers" {
-		return s.GetAllUsers(APIstub)
-	} else if function == "GetUserByPublicKey" {
-		return s.GetUserByPublicKey(APIstub, args)
-	}
-
-	return shim.Error("Function with the name " + function + " does not exist.")
-}
-
-func main() {
-	err := shim.Start(new(SecurityChaincode))
-	if err != nil {
-		fmt.Printf("Error creating new Authorization Chaincode: %s", err)
-	}
-}
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const UserTableName string = models.UserDocType
+
+const UserSecretCollection string = "collectionUserSecrets"
+
+const SecondFactorTransientKey string = "secondfactor"
+
+// DefaultRoleName is the name of the role the users get when they register
+const DefaultRoleName string = "Users"
+
+// The events of the registrations, their payload is the user
+const (
+	UserApprovedEvent string = "UserApproved"
+	UserRejectedEvent string = "UserRejected"
+)
+
+// SecurityChaincode provides functions to manage authorization
+type SecurityChaincode struct {
+}
+
+var userRepo repository.IRepo
+var userSecretRepo repository.IPrivateRepo
+var orgUnitRepo repository.IRepo
+var userRoleRepo repository.IRepo
+
+// integrity of the users, the role assignments point to the users and to the roles of the role chaincode as the users do.
+// The cascades are checked by the access control of the chaincode, which does not call itself.
+var integrity = core.Integrity{References: map[string][]core.Reference{
+	models.UserDocType: {
+		{DocType: models.UserRoleDocType, Column: models.UserRoleUserIDColumnName},
+	},
+}, Inbound: []core.Inbound{
+	{DocType: models.UserDocType, Column: models.UserRoleIDColumnName, Clear: true, FeatureID: core.RoleManagementFeatureID},
+	{DocType: models.UserRoleDocType, Column: models.UserRoleRoleIDColumnName, FeatureID: core.RoleManagementFeatureID},
+}, Access: accessControl()}
+
+// accessLevels are the values of the access level arguments, 0 is read only, 1 is write and read
+var accessLevels = []string{"0", "1"}
+
+// schemas of the arguments of the functions, they are checked before the functions are called
+var schemas = core.Schemas{
+	"ValidateLogin":            {},
+	"CheckUserPermission":      {{Name: "feature id", Required: true}, {Name: "access level", Required: true, Enum: accessLevels}, {Name: "scope ids"}},
+	"RegisterUser":             {},
+	"ApproveRegistration":      {{Name: "ad login", Required: true}, {Name: "role id"}, {Name: "MSP ID"}},
+	"RejectRegistration":       {{Name: "ad login", Required: true}, {Name: "MSP ID"}},
+	"ListPendingRegistrations": {},
+	"AddUser":                  {{Name: "ad login", Required: true}, {Name: "MSP ID", Required: true}, {Name: "public key", Required: true}, {Name: "role id", Required: true}},
+	"GetAllUsers":              {},
+	"GetUserByPublicKey":       {{Name: "public key", Required: true}},
+	"GetUser":                  {{Name: "ad login", Required: true}},
+	"UpdateUser":               {{Name: "ad login", Required: true}, {Name: "role id"}},
+	"DisableUser":              {{Name: "ad login", Required: true}},
+	"EnableUser":               {{Name: "ad login", Required: true}},
+	"RotatePublicKey":          {{Name: "ad login", Required: true}, {Name: "public key", Required: true}, {Name: "signature"}},
+	"IsCallerEnabled":          {},
+	"UserExists":               {{Name: "ad login", Required: true}},
+	"GetCurrentUser":           {},
+	"CreateOrgUnit":            {{Name: "org unit name", Required: true}, {Name: "parent org unit id"}},
+	"GetOrgUnits":              {},
+	"GetOrgUnitUsers":          {{Name: "org unit id", Required: true}},
+	"AssignUser":               {{Name: "ad login", Required: true}, {Name: "org unit id"}, {Name: "manager"}},
+	"GetReports":               {{Name: "manager", Required: true}, {Name: "reports", Enum: []string{DirectReports}}},
+	"CheckUserAccess":          {{Name: "ad login", Required: true}, {Name: "feature id", Required: true}, {Name: "access level", Required: true, Enum: accessLevels}},
+	"AssignRole": {{Name: "ad login", Required: true}, {Name: "role id", Required: true}, {Name: "scope id"},
+		{Name: "valid from", Type: core.ArgDate, Format: time.RFC3339}, {Name: "valid to", Type: core.ArgDate, Format: time.RFC3339}},
+	"RevokeRole":      {{Name: "ad login", Required: true}, {Name: "role id", Required: true}, {Name: "scope id"}},
+	"GetUserRoles":    {{Name: "ad login", Required: true}, {Name: "roles", Enum: []string{ActiveRoles}}},
+	"GetCurrentRoles": {},
+	"GetScopedRoles":  {{Name: "scope ids"}},
+}
+
+// ============================================================================================================================
+// Base Functions - Invoke | Init
+// ============================================================================================================================
+
+// Init method is called when the Smart Contract is instantiated by the blockchain network
+func (s *SecurityChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
+	userRepo = repository.InitRepo("user")
+	userSecretRepo = repository.InitPrivateRepo(UserSecretCollection)
+	orgUnitRepo = repository.InitRepo(models.OrgUnitDocType)
+	userRoleRepo = repository.InitRepo(models.UserRoleDocType)
+
+	if err := core.InitAttributeMSPIDs(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to configure the MSPs trusted to issue the certificate attributes"))
+	}
+
+	return shim.Success(nil)
+}
+
+// Invoke method is called as a result of an application request to run the Smart Contract
+func (s *SecurityChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	// Retrieve the requested Smart Contract function and arguments
+	function, args := APIstub.GetFunctionAndParameters()
+
+	// Check the arguments against the schema of the function, so the handlers can index them
+	if err := schemas.Validate(function, args); err != nil {
+		return errs.Response(err)
+	}
+
+	if function == "ValidateLogin" {
+		return s.ValidateLogin(APIstub, args)
+	} else if function == "CheckUserPermission" {
+		return s.CheckUserPermission(APIstub, args)
+	} else if function == "RegisterUser" {
+		return s.RegisterUser(APIstub, args)
+	} else if function == "ApproveRegistration" {
+		return s.ApproveRegistration(APIstub, args)
+	} else if function == "RejectRegistration" {
+		return s.RejectRegistration(APIstub, args)
+	} else if function == "ListPendingRegistrations" {
+		return s.ListPendingRegistrations(APIstub)
+	} else if function == "AddUser" {
+		return s.AddUser(APIstub, args)
+	} else if function == "GetAllUsers" {
+		return s.GetAllUsers(APIstub)
+	} else if function == "GetUserByPublicKey" {
+		return s.GetUserByPublicKey(APIstub, args)
+	} else if function == "GetUser" {
+		return s.GetUser(APIstub, args)
+	} else if function == "UpdateUser" {
+		return s.UpdateUser(APIstub, args)
+	} else if function == "DisableUser" {
+		return s.DisableUser(APIstub, args)
+	} else if function == "EnableUser" {
+		return s.EnableUser(APIstub, args)
+	} else if function == "RotatePublicKey" {
+		return s.RotatePublicKey(APIstub, args)
+	} else if function == "IsCallerEnabled" {
+		return s.IsCallerEnabled(APIstub)
+	} else if function == "UserExists" {
+		return s.UserExists(APIstub, args)
+	} else if function == "GetCurrentUser" {
+		return s.GetCurrentUser(APIstub)
+	} else if function == "CreateOrgUnit" {
+		return s.CreateOrgUnit(APIstub, args)
+	} else if function == "GetOrgUnits" {
+		return s.GetOrgUnits(APIstub)
+	} else if function == "GetOrgUnitUsers" {
+		return s.GetOrgUnitUsers(APIstub, args)
+	} else if function == "AssignUser" {
+		return s.AssignUser(APIstub, args)
+	} else if function == "GetReports" {
+		return s.GetReports(APIstub, args)
+	} else if function == "CheckUserAccess" {
+		return s.CheckUserAccess(APIstub, args)
+	} else if function == "AssignRole" {
+		return s.AssignRole(APIstub, args)
+	} else if function == "RevokeRole" {
+		return s.RevokeRole(APIstub, args)
+	} else if function == "GetUserRoles" {
+		return s.GetUserRoles(APIstub, args)
+	} else if function == "GetCurrentRoles" {
+		return s.GetCurrentRoles(APIstub)
+	} else if function == "GetScopedRoles" {
+		return s.GetScopedRoles(APIstub, args)
+	} else if function == "GetReferences" {
+		return integrity.GetReferences(APIstub, args)
+	} else if function == "DeleteReferences" {
+		return integrity.DeleteReferences(APIstub, args)
+	} else if function == "ClearReferences" {
+		return integrity.ClearReferences(APIstub, args)
+	} else if function == "Migrate" {
+		return core.Base{Access: accessControl()}.Migrate(APIstub, args)
+	} else if function == "GetMigrations" {
+		return core.Base{Access: accessControl()}.GetMigrations(APIstub)
+	}
+
+	return errs.Fail(errs.InvalidArgument, "Function with the name " + function + " does not exist.")
+}
+
+func main() {
+	err := shim.Start(new(SecurityChaincode))
+	if err != nil {
+		fmt.Printf("Error creating new Authorization Chaincode: %s", err)
+	}
+}
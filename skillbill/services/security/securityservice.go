@@ -1,173 +1,152 @@
-package main
-
-import (
-	"crypto/ecdsa"
-	"crypto/x509"
-	"encoding/hex"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"reflect"
-	"strconv"
-	"strings"
-
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-	"github.com/skillbill/models"
-	"github.com/skillbill/packages/logs"
-	"github.com/skillbill/packages/utils"
-)
-
-// ============================================================================================================================
-// Internal Functions - Helpers, Utilities for the chaincode
-// ============================================================================================================================
-
-func toChaincodeArgs(args ...string) [][]byte {
-	bargs := make([][]byte, len(args))
-	for i, arg := range args {
-		bargs[i] = []byte(arg)
-	}
-	return bargs
-}
-
-// validateUser to get user that exists in the ledger by validationg the public key
-func validateUser(stub shim.ChaincodeStubInterface, payload []byte, ecdsaPublicKey *ecdsa.PublicKey, password string) (*models.User, error) {
-	var user *models.User
-
-	err := json.Unmarshal(payload, &user)
-	if reflect.DeepEqual(models.User{}, user) == false {
-		publicKeyParser, _ := x509.MarshalPKIXPublicKey(ecdsaPublicKey)
-		publicKey := hex.EncodeToString(publicKeyParser)
-		hasPassword := false
-		if password != "" {
-			hasPassword = utils.CheckPasswordHash(password, user.HashedPassword)
-		}
-
-		var matchPub = strings.Compare(publicKey, user.PublicKey)
-
-		if matchPub == 0 && hasPassword {
-			return user, err
-		}
-
-		err = errors.New(user.ADLogin + " matchPub: " + string(user.PublicKey) + " hasPassword" + user.HashedPassword)
-	}
-
-	return nil, err
-}
-
-func getFeaturesByRoleIDs(stub shim.ChaincodeStubInterface, roleID string) sc.Response {
-
-	channelName := ""
-
-	// Check user existing or not && get UserID from public key
-	chaincodeName := "role"
-	functionName := "getFeaturesByRoleIDs"
-	queryArgs := toChaincodeArgs(functionName, roleID)
-
-	response := stub.InvokeChaincode(chaincodeName, queryArgs, channelName)
-	return response
-}
-
-// ============================================================================================================================
-// ChainCode Functions - Define functions for the chaincode
-// ============================================================================================================================
-
-// ValidateLogin to check user can login (check public key of caller)
-func (s *SecurityChaincode) ValidateLogin(stub shim.ChaincodeStubInterface, args []string) sc.Response {
-	logs.LogInfo("Validate login")
-	password := args[0]
-	cert, err := utils.GetCreatorCert(stub)
-	ecdsaPublicKey := cert.PublicKey.(*ecdsa.PublicKey)
-
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Could not get Certificate, err %s", err))
-	}
-
-	// var query = strings.Join([]string{"adlogin", cert.Subject.CommonName}, ",")
-	// logs.LogInfo("Query: " + query)
-
-	response, err := userRepo.FirstOrDefault(stub, func(item interface{}) bool { return item.(models.User).ADLogin == cert.Subject.CommonName })
-
-	if err != nil {
-		errStr := fmt.Sprintf("Failed to get user. Got error: %s", err)
-		logs.LogError(errStr)
-		return shim.Error(errStr)
-	}
-
-	if response == nil {
-		return shim.Error("Could not find any user with name " + cert.Subject.CommonName)
-	}
-
-	currentUser, err := validateUser(stub, response, ecdsaPublicKey, password)
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Could not parse json to user object, err %s", err))
-	}
-
-	if currentUser == nil || reflect.DeepEqual(models.User{}, currentUser) {
-		return shim.Success([]byte(strconv.FormatBool(false)))
-	}
-
-	return shim.Success([]byte(strconv.FormatBool(true)))
-}
-
-// CheckUserPermission to check user can access feature
-// arg[0] : featureID, arg[1] : accessLevel (0- readonly, 1- write and read)
-func (s *SecurityChaincode) CheckUserPermission(stub shim.ChaincodeStubInterface, args []string) sc.Response {
-	//featureID := args[0]
-	//accessLevel := args[1]
-	cert, err := utils.GetCreatorCert(stub)
-	ecdsaPublicKey := cert.PublicKey.(*ecdsa.PublicKey)
-
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Could not get Certificate, err %s", err))
-	}
-
-	response, err := userRepo.FirstOrDefault(stub, func(item interface{}) bool { return item.(models.User).ADLogin == cert.Subject.CommonName })
-
-	if err != nil {
-		errStr := fmt.Sprintf("Failed to get user. Got error: %s", err)
-		logs.LogError(errStr)
-		return shim.Error(errStr)
-	}
-
-	if response == nil {
-		return shim.Error("Could not find any user with name " + cert.Subject.CommonName)
-	}
-
-	currentUser, err := validateUser(stub, response, ecdsaPublicKey, "")
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Could not parse json to user object, err %s", err))
-	}
-
-	if reflect.DeepEqual(models.User{}, currentUser) {
-		return shim.Success([]byte(strconv.FormatBool(false)))
-	}
-
-	// Check user can access feature : Get features role has
-	// response = getFeaturesByRoleIDs(stub, currentUser.RoleID)
-	// if response.Status != shim.OK {
-	// 	errStr := fmt.Sprintf("Failed to query chaincode. Got error: %s", response.Payload)
-	// 	fmt.Printf(errStr)
-	// 	return shim.Error(errStr)
-	// }
-
-	// roleFeatures := make([]models.RoleFeature, 0)
-	// err = json.Unmarshal(response.Payload, &roleFeatures)
-	// if err != nil {
-	// 	return shim.Error(fmt.Sprintf("Could not parse json to feature object, err %s", err))
-	// }
-
-	canAccess := false
-	// if reflect.DeepEqual([]models.RoleFeature{}, roleFeatures) == false {
-	// 	for _, roleFeature := range roleFeatures {
-	// 		accessLevelInt, err := strconv.Atoi(accessLevel)
-
-	// 		if err == nil && roleFeature.FeatureID == featureID && roleFeature.AccessLevel == accessLevelInt {
-	// 			canAccess = true
-	// 			break
-	// 		}
-	// 	}
-	// }
-
-	return shim.Success([]byte(strconv.FormatBool(canAccess)))
-}
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/logs"
+	"github.com/skillbill/packages/utils"
+)
+
+// ============================================================================================================================
+// Internal Functions - Helpers, Utilities for the chaincode
+// ============================================================================================================================
+
+// getCurrentUser to get the registered user of the caller.
+// The user is identified by the MSP ID and CommonName of the certificate, the public key must match the registered one
+// and the user must not be disabled nor pending approval.
+func getCurrentUser(stub shim.ChaincodeStubInterface) (*models.User, error) {
+	mspID, commonName, err := utils.GetCreatorIdentity(stub)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not get the identity of the caller")
+	}
+
+	publicKey, err := utils.GetPublicKey(stub)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not get Certificate")
+	}
+
+	user, err := getUserByIdentity(stub, mspID, commonName)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PublicKey != hex.EncodeToString([]byte(publicKey)) {
+		return nil, errs.New(errs.PermissionDenied, "The certificate of the caller does not match the user " + commonName)
+	}
+
+	if user.Disabled {
+		return nil, errs.New(errs.PermissionDenied, "The user " + commonName + " is disabled")
+	}
+
+	if user.Pending {
+		return nil, errs.New(errs.PermissionDenied, "The registration of user " + commonName + " is pending approval")
+	}
+
+	return user, nil
+}
+
+// checkSecondFactor to check the optional second factor of user, it is passed in the transient field "secondfactor"
+// so it is never written to the block. Users registered without a second factor pass.
+func checkSecondFactor(stub shim.ChaincodeStubInterface, user *models.User) (bool, error) {
+	if user.SecretHash == "" {
+		return true, nil
+	}
+
+	var secret *models.UserSecret
+
+	data, err := userSecretRepo.GetByKey(stub, userKey(user.MSPID, user.ADLogin))
+	if err != nil {
+		return false, err
+	}
+
+	if utils.HashData(data) != user.SecretHash {
+		return false, errs.New(errs.Conflict, "The secret of user " + user.ADLogin + " does not match its hash on the ledger")
+	}
+
+	err = json.Unmarshal(data, &secret)
+	if err != nil {
+		return false, err
+	}
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return false, err
+	}
+
+	secondFactor, ok := transient[SecondFactorTransientKey]
+	if !ok {
+		return false, nil
+	}
+
+	return hashSecondFactor(user.ADLogin, secondFactor) == secret.SecondFactorHash, nil
+}
+
+func hashSecondFactor(adLogin string, secondFactor []byte) string {
+	return utils.HashData(append([]byte(adLogin+":"), secondFactor...))
+}
+
+// ============================================================================================================================
+// ChainCode Functions - Define functions for the chaincode
+// ============================================================================================================================
+
+// ValidateLogin to check user can login (check the identity of caller and the optional second factor)
+func (s *SecurityChaincode) ValidateLogin(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	logs.LogInfo("Validate login")
+
+	currentUser, err := getCurrentUser(stub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get user"))
+	}
+
+	valid, err := checkSecondFactor(stub, currentUser)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not check the second factor"))
+	}
+
+	return shim.Success([]byte(strconv.FormatBool(valid)))
+}
+
+// CheckUserPermission to check user can access feature
+// arg[0] : featureID, arg[1] : accessLevel (0- readonly, 1- write and read),
+// arg[2] : optional scope ids separated by comma, the roles assigned within them are added to the roles of the user
+func (s *SecurityChaincode) CheckUserPermission(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) == 3 && args[2] != "" {
+		return accessControl().CheckUserPermission(stub, args[0], args[1], strings.Split(args[2], ",")...)
+	}
+
+	return accessControl().CheckUserPermission(stub, args[0], args[1])
+}
+
+// accessControl reads the user from this chaincode, invoking the security chaincode from itself is not possible
+func accessControl() core.AccessControl {
+	access := core.NewAccessControl()
+	access.Fallback = getCurrentRoles
+	access.Scoped = getScopedRoles
+	access.Enabled = isCallerEnabled
+
+	return access
+}
+
+// GetCurrentUser returns the registered user of the caller
+func (s *SecurityChaincode) GetCurrentUser(stub shim.ChaincodeStubInterface) sc.Response {
+
+	currentUser, err := getCurrentUser(stub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get user"))
+	}
+
+	data, _ := json.Marshal(withoutSecret(*currentUser))
+
+	return shim.Success(data)
+}
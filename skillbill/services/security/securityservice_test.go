@@ -0,0 +1,571 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+var admin = testsupport.MustNewIdentity("Org1MSP", "admin", nil)
+var manager = testsupport.MustNewIdentity("Org1MSP", "manager", map[string]string{"skillbill.role": "Managers"})
+var dave = testsupport.MustNewIdentity("Org1MSP", "dave", nil)
+var renewedDave = testsupport.MustNewIdentity("Org1MSP", "dave", nil)
+var otherDave = testsupport.MustNewIdentity("Org2MSP", "dave", nil)
+
+// The validity windows of the role assignments, before and after the time of the transactions
+const (
+	past   string = "2000-01-01T00:00:00Z"
+	future string = "2999-01-01T00:00:00Z"
+)
+
+func newSecurityStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("security", new(SecurityChaincode), admin)
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("role", testsupport.NewRoleFake(
+		models.RoleFeature{ID: "1", RoleID: "Administrators", FeatureID: "UserManagement", AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "2", RoleID: "Managers", FeatureID: "SkillPlan", AccessLevel: models.ReadOnly},
+		models.RoleFeature{ID: "3", RoleID: "Administrators", FeatureID: core.FeatureManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "4", RoleID: "Administrators", FeatureID: core.UserManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "5", RoleID: "SkillAdministrators", FeatureID: "SkillManagement", AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "6", RoleID: "Administrators", FeatureID: core.RoleManagementFeatureID, AccessLevel: models.ReadWrite}).
+		On("getAllByQuery", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			roles := []models.Role{}
+			for _, roleID := range []string{"SkillAdministrators", "Administrators", "Users"} {
+				if len(args) == 2 && (args[1] == models.RoleIDColumnName+","+roleID || args[1] == models.RoleNameColumnName+","+roleID) {
+					roles = append(roles, models.Role{RoleID: roleID, RoleName: roleID, DocType: models.RoleDocType})
+				}
+			}
+
+			data, _ := json.Marshal(roles)
+			return shim.Success(data)
+		}))
+
+	return stub
+}
+
+// putUser stores the user with the key of their MSP ID and ad login
+func putUser(stub *testsupport.Stub, user models.User) {
+	testsupport.PutJSON(stub, userKey(user.MSPID, user.ADLogin), user)
+}
+
+func putAdmin(stub *testsupport.Stub) {
+	putUser(stub, models.User{ADLogin: "admin", MSPID: "Org1MSP", PublicKey: admin.PublicKey(), RoleID: "Administrators", DocType: UserTableName})
+}
+
+// putOrganisation puts the department OU1 and OU2 below it, bob reports to the manager and carol of OU2 reports to bob
+func putOrganisation(stub *testsupport.Stub) {
+	putAdmin(stub)
+	testsupport.PutJSON(stub, "OU1", models.OrgUnit{OrgUnitID: "OU1", OrgUnitName: "Engineering", DocType: models.OrgUnitDocType})
+	testsupport.PutJSON(stub, "OU2", models.OrgUnit{OrgUnitID: "OU2", OrgUnitName: "Backend", ParentID: "OU1", DocType: models.OrgUnitDocType})
+	putUser(stub, models.User{ADLogin: "manager", MSPID: "Org1MSP", PublicKey: manager.PublicKey(), OrgUnitID: "OU1", DocType: UserTableName})
+	putUser(stub, models.User{ADLogin: "bob", MSPID: "Org1MSP", SecretHash: "5ec2e7", PublicKey: "b0b", OrgUnitID: "OU1", ManagerID: "manager", DocType: UserTableName})
+	putUser(stub, models.User{ADLogin: "carol", MSPID: "Org1MSP", PublicKey: "ca201", OrgUnitID: "OU2", ManagerID: "bob", DocType: UserTableName})
+}
+
+func asManager(stub *testsupport.Stub) {
+	putOrganisation(stub)
+	stub.SetIdentity(manager)
+}
+
+// putScopedRole makes the manager a skill administrator within the knowledge group G1
+func putScopedRole(stub *testsupport.Stub) {
+	putOrganisation(stub)
+	testsupport.PutJSON(stub, "UR1", models.UserRole{ID: "UR1", UserID: "manager", RoleID: "SkillAdministrators", ScopeID: "G1", ValidFrom: past, DocType: models.UserRoleDocType})
+}
+
+// putGlobalRoles registers dave as user, a skill administrator since 2000, an administrator who has expired and a manager to come
+func putGlobalRoles(stub *testsupport.Stub) {
+	putOrganisation(stub)
+	putUser(stub, models.User{ADLogin: "dave", MSPID: "Org1MSP", PublicKey: dave.PublicKey(), RoleID: "Users", DocType: UserTableName})
+	testsupport.PutJSON(stub, "UR2", models.UserRole{ID: "UR2", UserID: "dave", RoleID: "SkillAdministrators", ValidFrom: past, DocType: models.UserRoleDocType})
+	testsupport.PutJSON(stub, "UR3", models.UserRole{ID: "UR3", UserID: "dave", RoleID: "Administrators", ValidFrom: "1999-01-01T00:00:00Z", ValidTo: past, DocType: models.UserRoleDocType})
+	testsupport.PutJSON(stub, "UR4", models.UserRole{ID: "UR4", UserID: "dave", RoleID: "Managers", ValidFrom: future, DocType: models.UserRoleDocType})
+}
+
+func asDave(stub *testsupport.Stub) {
+	putGlobalRoles(stub)
+	stub.SetIdentity(dave)
+}
+
+func expectUserRole(key string, check func(userRole models.UserRole) bool) func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+	return func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+		id := key
+		if id == "" {
+			id = string(res.Payload)
+		}
+
+		var userRole models.UserRole
+		json.Unmarshal(stub.State[id], &userRole)
+		if !check(userRole) {
+			t.Errorf("Unexpected role assignment %s", string(stub.State[id]))
+		}
+	}
+}
+
+// putDisabledDave registers dave with the global roles and disables the user
+func putDisabledDave(stub *testsupport.Stub) {
+	putGlobalRoles(stub)
+	putUser(stub, models.User{ADLogin: "dave", MSPID: "Org1MSP", PublicKey: dave.PublicKey(), RoleID: "Users", Disabled: true, DocType: UserTableName})
+}
+
+func asDisabledDave(stub *testsupport.Stub) {
+	putDisabledDave(stub)
+	stub.SetIdentity(dave)
+}
+
+// putSecrets gives the admin and dave the hash of a secret
+func putSecrets(stub *testsupport.Stub) {
+	putGlobalRoles(stub)
+	putUser(stub, models.User{ADLogin: "admin", MSPID: "Org1MSP", PublicKey: admin.PublicKey(), RoleID: "Administrators", SecretHash: "5ec2e7", DocType: UserTableName})
+	putUser(stub, models.User{ADLogin: "dave", MSPID: "Org1MSP", PublicKey: dave.PublicKey(), RoleID: "Users", SecretHash: "5ec2e7", DocType: UserTableName})
+}
+
+// registerDave puts the organisation and dave registers, dave is the caller and is pending approval
+func registerDave(secondFactor string) func(stub *testsupport.Stub) {
+	return func(stub *testsupport.Stub) {
+		putOrganisation(stub)
+		stub.SetIdentity(dave)
+		stub.SetTransient(map[string][]byte{SecondFactorTransientKey: []byte(secondFactor)})
+		stub.Invoke("RegisterUser")
+		stub.SetTransient(nil)
+	}
+}
+
+// registeredDave registers dave, the admin is the caller
+func registeredDave(stub *testsupport.Stub) {
+	registerDave("")(stub)
+	stub.SetIdentity(admin)
+}
+
+// approveDave registers dave and the admin approves the registration, dave is the caller
+func approveDave(secondFactor string) func(stub *testsupport.Stub) {
+	return func(stub *testsupport.Stub) {
+		registerDave(secondFactor)(stub)
+		stub.SetIdentity(admin)
+		stub.Invoke("ApproveRegistration", "dave")
+		stub.SetIdentity(dave)
+	}
+}
+
+func expectUser(adLogin string, check func(user models.User) bool) func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+	return func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+		var user models.User
+		json.Unmarshal(stub.State[userKey("Org1MSP", adLogin)], &user)
+		if !check(user) {
+			t.Errorf("Unexpected user %s", string(stub.State[userKey("Org1MSP", adLogin)]))
+		}
+	}
+}
+
+func expectNoSecret(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+	if strings.Contains(string(res.Payload), "5ec2e7") {
+		t.Errorf("Expected the users without the secret but got %s", string(res.Payload))
+	}
+}
+
+func asScopedManager(stub *testsupport.Stub) {
+	putScopedRole(stub)
+	stub.SetIdentity(manager)
+}
+
+func TestSecurityInvoke(t *testing.T) {
+	testsupport.RunInvokeCases(t, newSecurityStub, []testsupport.InvokeCase{
+		{Name: "RegisterUser stores the caller pending approval", Function: "RegisterUser",
+			Check: expectUser("admin", func(user models.User) bool {
+				return user.MSPID == "Org1MSP" && user.PublicKey == admin.PublicKey() && user.RoleID == "Users" && user.Pending
+			})},
+		{Name: "RegisterUser twice", Function: "RegisterUser", Setup: putAdmin, Code: errs.AlreadyExists},
+		{Name: "RegisterUser with a role", Function: "RegisterUser", Args: []string{"Administrators"}, Status: shim.ERROR},
+		{Name: "AddUser stores the user", Function: "AddUser", Args: []string{"bob", "Org1MSP", "b0b", "Users"}, Setup: putAdmin,
+			Check: expectUser("bob", func(user models.User) bool { return user.MSPID == "Org1MSP" && user.PublicKey == "b0b" && !user.Pending })},
+		{Name: "RegisterUser with the name of a user of another MSP", Function: "RegisterUser",
+			Setup: func(stub *testsupport.Stub) {
+				putGlobalRoles(stub)
+				stub.SetIdentity(otherDave)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State[userKey("Org2MSP", "dave")]) == 0 || len(stub.State[userKey("Org1MSP", "dave")]) == 0 {
+					t.Errorf("Expected dave of both MSPs registered")
+				}
+			}},
+		{Name: "AddUser with a registered public key", Function: "AddUser", Args: []string{"bob", "Org1MSP", admin.PublicKey(), "Users"},
+			Setup: putAdmin, Code: errs.AlreadyExists},
+		{Name: "AddUser by a user who can not manage the users", Function: "AddUser", Args: []string{"dave", "Org1MSP", dave.PublicKey(), "Administrators"},
+			Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "AddUser with missing arguments", Function: "AddUser", Args: []string{"bob"}, Code: errs.InvalidArgument},
+		{Name: "ValidateLogin of a registered user", Function: "ValidateLogin", Setup: putAdmin,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "ValidateLogin of an unknown user", Function: "ValidateLogin", Code: errs.NotFound},
+		{Name: "ValidateLogin of a user pending approval", Function: "ValidateLogin", Setup: registerDave(""), Status: shim.ERROR},
+		{Name: "ValidateLogin with a second factor", Function: "ValidateLogin",
+			Setup: func(stub *testsupport.Stub) {
+				approveDave("123456")(stub)
+				stub.SetTransient(map[string][]byte{SecondFactorTransientKey: []byte("123456")})
+			},
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "ValidateLogin with a wrong second factor", Function: "ValidateLogin",
+			Setup: func(stub *testsupport.Stub) {
+				approveDave("123456")(stub)
+				stub.SetTransient(map[string][]byte{SecondFactorTransientKey: []byte("654321")})
+			},
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckUserPermission of a user pending approval", Function: "CheckUserPermission", Args: []string{"RoleManagement", "0"}, Setup: registerDave(""),
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "ApproveRegistration lets the user login", Function: "ApproveRegistration", Args: []string{"dave"}, Setup: registeredDave,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				expectUser("dave", func(user models.User) bool {
+					return !user.Pending && user.RoleID == "Users" && user.ApprovedBy == "admin"
+				})(t, stub, res)
+				testsupport.ExpectEvent(UserApprovedEvent)(t, stub, res)
+			}},
+		{Name: "ApproveRegistration elevates the role", Function: "ApproveRegistration", Args: []string{"dave", "SkillAdministrators"}, Setup: registeredDave,
+			Check: expectUser("dave", func(user models.User) bool { return !user.Pending && user.RoleID == "SkillAdministrators" })},
+		{Name: "ApproveRegistration with an unknown role", Function: "ApproveRegistration", Args: []string{"dave", "Unknown"}, Setup: registeredDave, Code: errs.NotFound},
+		{Name: "ApproveRegistration of a user who is not pending", Function: "ApproveRegistration", Args: []string{"bob"}, Setup: putOrganisation, Code: errs.Conflict},
+		{Name: "ApproveRegistration by the user", Function: "ApproveRegistration", Args: []string{"dave"}, Setup: registerDave(""), Status: shim.ERROR},
+		{Name: "ApproveRegistration by a user who can not manage the users", Function: "ApproveRegistration", Args: []string{"dave"},
+			Setup: func(stub *testsupport.Stub) {
+				registeredDave(stub)
+				stub.SetIdentity(manager)
+			}, Code: errs.PermissionDenied},
+		{Name: "RegisterUser keeps the secret in the private collection", Function: "RegisterUser",
+			Setup: func(stub *testsupport.Stub) {
+				putOrganisation(stub)
+				stub.SetIdentity(dave)
+				stub.SetTransient(map[string][]byte{SecondFactorTransientKey: []byte("123456")})
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if secret, _ := stub.GetPrivateData(UserSecretCollection, userKey("Org1MSP", "dave")); len(secret) == 0 {
+					t.Errorf("Expected the secret of dave in the collection %s", UserSecretCollection)
+				}
+			}},
+		{Name: "RejectRegistration removes the user and the secret", Function: "RejectRegistration", Args: []string{"dave"},
+			Setup: func(stub *testsupport.Stub) {
+				registerDave("123456")(stub)
+				stub.SetIdentity(admin)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				testsupport.ExpectNoState(userKey("Org1MSP", "dave"))(t, stub, res)
+				if secret, _ := stub.GetPrivateData(UserSecretCollection, userKey("Org1MSP", "dave")); len(secret) != 0 {
+					t.Errorf("Expected the secret of dave to be removed")
+				}
+				testsupport.ExpectEvent(UserRejectedEvent)(t, stub, res)
+			}},
+		{Name: "RejectRegistration of a name registered by two MSPs", Function: "RejectRegistration", Args: []string{"dave", "Org2MSP"},
+			Setup: func(stub *testsupport.Stub) {
+				registerDave("")(stub)
+				stub.SetIdentity(otherDave)
+				stub.Invoke("RegisterUser")
+				stub.SetIdentity(admin)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				testsupport.ExpectNoState(userKey("Org2MSP", "dave"))(t, stub, res)
+				if len(stub.State[userKey("Org1MSP", "dave")]) == 0 {
+					t.Errorf("Expected the registration of dave of Org1MSP kept")
+				}
+			}},
+		{Name: "RejectRegistration of a name registered by two MSPs without MSP ID", Function: "RejectRegistration", Args: []string{"dave"},
+			Setup: func(stub *testsupport.Stub) {
+				registerDave("")(stub)
+				stub.SetIdentity(otherDave)
+				stub.Invoke("RegisterUser")
+				stub.SetIdentity(admin)
+			}, Code: errs.Conflict},
+		{Name: "RejectRegistration of an unknown user", Function: "RejectRegistration", Args: []string{"dave"}, Setup: putAdmin, Code: errs.NotFound},
+		{Name: "RejectRegistration of a user who is not pending", Function: "RejectRegistration", Args: []string{"admin"}, Setup: putAdmin, Code: errs.Conflict},
+		{Name: "RejectRegistration by a user who can not manage the users", Function: "RejectRegistration", Args: []string{"dave"},
+			Setup: func(stub *testsupport.Stub) {
+				registeredDave(stub)
+				stub.SetIdentity(manager)
+			}, Code: errs.PermissionDenied},
+		{Name: "ListPendingRegistrations returns the users pending approval", Function: "ListPendingRegistrations", Setup: registeredDave,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "ListPendingRegistrations by a user who can not read the users", Function: "ListPendingRegistrations", Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "CheckUserPermission by the ledger role", Function: "CheckUserPermission", Args: []string{"UserManagement", "1"}, Setup: putAdmin,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserPermission by the certificate role", Function: "CheckUserPermission", Args: []string{"SkillPlan", "0"},
+			Setup: func(stub *testsupport.Stub) { stub.SetIdentity(manager) },
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserPermission above the access level", Function: "CheckUserPermission", Args: []string{"SkillPlan", "1"},
+			Setup: func(stub *testsupport.Stub) { stub.SetIdentity(manager) },
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckUserPermission with missing arguments", Function: "CheckUserPermission", Args: []string{"UserManagement"}, Code: errs.InvalidArgument},
+		{Name: "CheckUserPermission with an unknown access level", Function: "CheckUserPermission", Args: []string{"UserManagement", "2"}, Code: errs.InvalidArgument},
+		{Name: "GetUserRoles with an unknown filter", Function: "GetUserRoles", Args: []string{"dave", "expired"}, Code: errs.InvalidArgument},
+		{Name: "CheckUserPermission within the scope of a role", Function: "CheckUserPermission", Args: []string{"SkillManagement", "1", "G2,G1"}, Setup: asScopedManager,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserPermission outside the scope of a role", Function: "CheckUserPermission", Args: []string{"SkillManagement", "1", "G3"}, Setup: asScopedManager,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckUserPermission without scope ignores the scoped roles", Function: "CheckUserPermission", Args: []string{"SkillManagement", "1"}, Setup: asScopedManager,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckUserPermission with a role assigned without scope", Function: "CheckUserPermission", Args: []string{"SkillManagement", "1"}, Setup: asDave,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserPermission with an expired role", Function: "CheckUserPermission", Args: []string{"UserManagement", "0"}, Setup: asDave,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckUserPermission with a role to come", Function: "CheckUserPermission", Args: []string{"SkillPlan", "0"}, Setup: asDave,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "GetCurrentRoles returns the registered role and the active roles", Function: "GetCurrentRoles", Setup: asDave,
+			Check: testsupport.ExpectPayload(`["Users","SkillAdministrators"]`)},
+		{Name: "GetCurrentRoles of an unknown user", Function: "GetCurrentRoles", Code: errs.NotFound},
+		{Name: "GetUserRoles returns the history ordered by validity", Function: "GetUserRoles", Args: []string{"dave"}, Setup: putGlobalRoles,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var userRoles []models.UserRole
+				json.Unmarshal(res.Payload, &userRoles)
+				if len(userRoles) != 3 || userRoles[0].ID != "UR3" || userRoles[1].ID != "UR2" || userRoles[2].ID != "UR4" {
+					t.Errorf("Expected UR3, UR2 and UR4 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetUserRoles returns the active roles", Function: "GetUserRoles", Args: []string{"dave", "active"}, Setup: putGlobalRoles,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var userRoles []models.UserRole
+				json.Unmarshal(res.Payload, &userRoles)
+				if len(userRoles) != 1 || userRoles[0].ID != "UR2" {
+					t.Errorf("Expected UR2 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetUserRoles of the caller", Function: "GetUserRoles", Args: []string{"dave"}, Setup: asDave,
+			Check: testsupport.ExpectCount(3)},
+		{Name: "GetUserRoles of a user the caller can not see", Function: "GetUserRoles", Args: []string{"dave"},
+			Setup: func(stub *testsupport.Stub) {
+				putGlobalRoles(stub)
+				stub.SetIdentity(manager)
+			}, Code: errs.PermissionDenied},
+		{Name: "AssignRole stores the scoped role", Function: "AssignRole", Args: []string{"bob", "SkillAdministrators", "G1"}, Setup: putOrganisation,
+			Check: expectUserRole("", func(userRole models.UserRole) bool {
+				return userRole.UserID == "bob" && userRole.RoleID == "SkillAdministrators" && userRole.ScopeID == "G1" && userRole.ValidFrom != "" && userRole.AssignedBy == "admin"
+			})},
+		{Name: "AssignRole without scope stores the global role", Function: "AssignRole", Args: []string{"bob", "SkillAdministrators"}, Setup: putOrganisation,
+			Check: expectUserRole("", func(userRole models.UserRole) bool {
+				return userRole.UserID == "bob" && userRole.ScopeID == "" && userRole.ValidTo == ""
+			})},
+		{Name: "AssignRole with a validity window", Function: "AssignRole", Args: []string{"dave", "Administrators", "", "2001-06-01T00:00:00Z", "2002-01-01T00:00:00Z"}, Setup: putGlobalRoles,
+			Check: expectUserRole("", func(userRole models.UserRole) bool {
+				return userRole.ValidFrom == "2001-06-01T00:00:00Z" && userRole.ValidTo == "2002-01-01T00:00:00Z"
+			})},
+		{Name: "AssignRole overlapping an assignment of the role", Function: "AssignRole", Args: []string{"dave", "SkillAdministrators", "", future}, Setup: putGlobalRoles, Code: errs.AlreadyExists},
+		{Name: "AssignRole with a validity ending before it starts", Function: "AssignRole", Args: []string{"bob", "SkillAdministrators", "", future, past}, Setup: putOrganisation, Status: shim.ERROR},
+		{Name: "AssignRole with an invalid date", Function: "AssignRole", Args: []string{"bob", "SkillAdministrators", "", "01/01/2000"}, Setup: putOrganisation, Status: shim.ERROR},
+		{Name: "AssignRole of a role the user has within the scope", Function: "AssignRole", Args: []string{"manager", "SkillAdministrators", "G1"}, Setup: putScopedRole, Status: shim.ERROR},
+		{Name: "AssignRole of an unknown role", Function: "AssignRole", Args: []string{"bob", "Unknown", "G1"}, Setup: putOrganisation, Code: errs.NotFound},
+		{Name: "AssignRole to an unknown user", Function: "AssignRole", Args: []string{"nobody", "SkillAdministrators", "G1"}, Setup: putOrganisation, Code: errs.NotFound},
+		{Name: "AssignRole by a caller who can not manage the users", Function: "AssignRole", Args: []string{"bob", "SkillAdministrators", "G1"}, Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "AssignRole with missing arguments", Function: "AssignRole", Args: []string{"bob"}, Code: errs.InvalidArgument},
+		{Name: "RevokeRole ends the scoped role and keeps it", Function: "RevokeRole", Args: []string{"manager", "SkillAdministrators", "G1"}, Setup: putScopedRole,
+			Check: expectUserRole("UR1", func(userRole models.UserRole) bool {
+				return userRole.ValidTo > past && userRole.RevokedBy == "admin"
+			})},
+		{Name: "RevokeRole of a role to come", Function: "RevokeRole", Args: []string{"dave", "Managers"}, Setup: putGlobalRoles,
+			Check: expectUserRole("UR4", func(userRole models.UserRole) bool {
+				return userRole.ValidTo == future
+			})},
+		{Name: "RevokeRole of an expired role", Function: "RevokeRole", Args: []string{"dave", "Administrators"}, Setup: putGlobalRoles, Status: shim.ERROR},
+		{Name: "RevokeRole of a role the user does not have within the scope", Function: "RevokeRole", Args: []string{"manager", "SkillAdministrators", "G2"}, Setup: putScopedRole, Status: shim.ERROR},
+		{Name: "RevokeRole by a caller who can not manage the users", Function: "RevokeRole", Args: []string{"manager", "SkillAdministrators", "G1"}, Setup: asScopedManager, Code: errs.PermissionDenied},
+		{Name: "GetScopedRoles returns the roles of the caller within the scopes", Function: "GetScopedRoles", Args: []string{"G2,G1"}, Setup: asScopedManager,
+			Check: testsupport.ExpectPayload(`["SkillAdministrators"]`)},
+		{Name: "GetScopedRoles without the revoked roles", Function: "GetScopedRoles", Args: []string{"G1"},
+			Setup: func(stub *testsupport.Stub) {
+				asScopedManager(stub)
+				testsupport.PutJSON(stub, "UR1", models.UserRole{ID: "UR1", UserID: "manager", RoleID: "SkillAdministrators", ScopeID: "G1", ValidFrom: "1999-01-01T00:00:00Z", ValidTo: past, DocType: models.UserRoleDocType})
+			},
+			Check: testsupport.ExpectPayload(`[]`)},
+		{Name: "GetScopedRoles of other scopes", Function: "GetScopedRoles", Args: []string{"G3"}, Setup: asScopedManager,
+			Check: testsupport.ExpectPayload(`[]`)},
+		{Name: "GetScopedRoles of a caller who is not registered", Function: "GetScopedRoles", Args: []string{"G1"},
+			Check: testsupport.ExpectPayload(`[]`)},
+		{Name: "GetAllUsers returns the users", Function: "GetAllUsers", Setup: putAdmin,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetAllUsers hides the secrets", Function: "GetAllUsers", Setup: putSecrets, Check: expectNoSecret},
+		{Name: "GetAllUsers by a user who can not read the users", Function: "GetAllUsers", Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "GetUser hides the secret", Function: "GetUser", Args: []string{"dave"}, Setup: putSecrets,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				expectNoSecret(t, stub, res)
+				if !strings.Contains(string(res.Payload), `"adlogin":"dave"`) {
+					t.Errorf("Expected the user dave but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetUser of the caller", Function: "GetUser", Args: []string{"dave"}, Setup: asDave},
+		{Name: "GetUser of a user the caller can not see", Function: "GetUser", Args: []string{"admin"}, Setup: asDave, Code: errs.PermissionDenied},
+		{Name: "GetUser of an unknown user", Function: "GetUser", Args: []string{"nobody"}, Setup: putAdmin, Code: errs.NotFound},
+		{Name: "UpdateUser changes the role", Function: "UpdateUser", Args: []string{"dave", "SkillAdministrators"}, Setup: putSecrets,
+			Check: expectUser("dave", func(user models.User) bool {
+				return user.RoleID == "SkillAdministrators" && user.SecretHash == "5ec2e7"
+			})},
+		{Name: "UpdateUser replaces the secret", Function: "UpdateUser", Args: []string{"dave", "Users"},
+			Setup: func(stub *testsupport.Stub) {
+				putSecrets(stub)
+				stub.SetTransient(map[string][]byte{SecondFactorTransientKey: []byte("123456")})
+			},
+			Check: expectUser("dave", func(user models.User) bool { return user.SecretHash != "" && user.SecretHash != "5ec2e7" })},
+		{Name: "UpdateUser without role keeps the role", Function: "UpdateUser", Args: []string{"dave", ""},
+			Setup: func(stub *testsupport.Stub) {
+				putSecrets(stub)
+				stub.SetTransient(map[string][]byte{SecondFactorTransientKey: []byte("123456")})
+			},
+			Check: expectUser("dave", func(user models.User) bool { return user.RoleID == "Users" && user.SecretHash != "5ec2e7" })},
+		{Name: "UpdateUser with an unknown role", Function: "UpdateUser", Args: []string{"dave", "Unknown"}, Setup: putGlobalRoles, Code: errs.NotFound},
+		{Name: "UpdateUser by a user who can not manage the users", Function: "UpdateUser", Args: []string{"dave", "Administrators"}, Setup: asDave, Code: errs.PermissionDenied},
+		{Name: "DisableUser disables the user", Function: "DisableUser", Args: []string{"dave"}, Setup: putGlobalRoles,
+			Check: expectUser("dave", func(user models.User) bool { return user.Disabled })},
+		{Name: "DisableUser of the caller", Function: "DisableUser", Args: []string{"admin"}, Setup: putAdmin, Status: shim.ERROR},
+		{Name: "DisableUser by a user who can not manage the users", Function: "DisableUser", Args: []string{"bob"}, Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "EnableUser enables the user", Function: "EnableUser", Args: []string{"dave"}, Setup: putDisabledDave,
+			Check: expectUser("dave", func(user models.User) bool { return !user.Disabled })},
+		{Name: "EnableUser by the disabled user", Function: "EnableUser", Args: []string{"dave"}, Setup: asDisabledDave, Status: shim.ERROR},
+		{Name: "ValidateLogin of a disabled user", Function: "ValidateLogin", Setup: asDisabledDave, Status: shim.ERROR},
+		{Name: "CheckUserPermission of a disabled user", Function: "CheckUserPermission", Args: []string{"SkillManagement", "1"}, Setup: asDisabledDave,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "IsCallerEnabled of a disabled user", Function: "IsCallerEnabled", Setup: asDisabledDave,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "IsCallerEnabled of an enabled user", Function: "IsCallerEnabled", Setup: asDave,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "IsCallerEnabled of a caller who is not registered", Function: "IsCallerEnabled",
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "IsCallerEnabled of a caller whose record can not be read", Function: "IsCallerEnabled",
+			Setup: func(stub *testsupport.Stub) {
+				asDave(stub)
+				stub.PutRecord(userKey("Org1MSP", "dave"), []byte("{"))
+			},
+			Code: errs.Internal},
+		{Name: "IsCallerEnabled of a caller whose name is pending in another MSP", Function: "IsCallerEnabled",
+			Setup: func(stub *testsupport.Stub) {
+				putOrganisation(stub)
+				stub.SetIdentity(otherDave)
+				stub.Invoke("RegisterUser")
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "dave", map[string]string{"skillbill.role": "Managers"}))
+			},
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "RotatePublicKey signed with the old key", Function: "RotatePublicKey",
+			Args: []string{"dave", renewedDave.PublicKey(), dave.Sign([]byte("dave:" + dave.PublicKey() + ":" + renewedDave.PublicKey()))}, Setup: asDave,
+			Check: expectUser("dave", func(user models.User) bool { return user.PublicKey == renewedDave.PublicKey() })},
+		{Name: "RotatePublicKey signed with another key", Function: "RotatePublicKey",
+			Args: []string{"dave", renewedDave.PublicKey(), renewedDave.Sign([]byte("dave:" + dave.PublicKey() + ":" + renewedDave.PublicKey()))}, Setup: asDave, Status: shim.ERROR},
+		{Name: "RotatePublicKey signed for another key", Function: "RotatePublicKey",
+			Args: []string{"dave", renewedDave.PublicKey(), dave.Sign([]byte("dave:" + dave.PublicKey() + ":b0b"))}, Setup: asDave, Status: shim.ERROR},
+		{Name: "RotatePublicKey signed without the old key", Function: "RotatePublicKey",
+			Args: []string{"dave", renewedDave.PublicKey(), dave.Sign([]byte("dave:" + renewedDave.PublicKey()))}, Setup: asDave, Status: shim.ERROR},
+		{Name: "RotatePublicKey of a disabled user", Function: "RotatePublicKey",
+			Args: []string{"dave", renewedDave.PublicKey(), dave.Sign([]byte("dave:" + dave.PublicKey() + ":" + renewedDave.PublicKey()))}, Setup: asDisabledDave, Status: shim.ERROR},
+		{Name: "RotatePublicKey by a user managing the users", Function: "RotatePublicKey", Args: []string{"dave", renewedDave.PublicKey()}, Setup: putDisabledDave,
+			Check: expectUser("dave", func(user models.User) bool { return user.PublicKey == renewedDave.PublicKey() })},
+		{Name: "RotatePublicKey to a key registered for another user", Function: "RotatePublicKey", Args: []string{"dave", "b0b"}, Setup: putGlobalRoles, Status: shim.ERROR},
+		{Name: "RotatePublicKey without signature by the user", Function: "RotatePublicKey", Args: []string{"dave", renewedDave.PublicKey()}, Setup: asDave, Status: shim.ERROR},
+		{Name: "RotatePublicKey with missing arguments", Function: "RotatePublicKey", Args: []string{"dave"}, Code: errs.InvalidArgument},
+		{Name: "GetUserByPublicKey returns the user", Function: "GetUserByPublicKey", Args: []string{admin.PublicKey()}, Setup: putAdmin,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetUserByPublicKey with missing arguments", Function: "GetUserByPublicKey", Code: errs.InvalidArgument},
+		{Name: "UserExists of a registered user", Function: "UserExists", Args: []string{"admin"}, Setup: putAdmin,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "UserExists of an unknown user", Function: "UserExists", Args: []string{"nobody"}, Setup: putAdmin,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "UserExists with missing arguments", Function: "UserExists", Code: errs.InvalidArgument},
+		{Name: "GetCurrentUser returns the caller", Function: "GetCurrentUser", Setup: putAdmin,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var user models.User
+				json.Unmarshal(res.Payload, &user)
+				if user.ADLogin != "admin" {
+					t.Errorf("Expected the user admin but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetCurrentUser of an unknown user", Function: "GetCurrentUser", Code: errs.NotFound},
+		{Name: "Migrate by an administrator of the ledger", Function: "Migrate", Setup: putAdmin,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var user map[string]interface{}
+				json.Unmarshal(stub.State[userKey("Org1MSP", "admin")], &user)
+				if !strings.Contains(string(res.Payload), `"completed":true`) || user[models.SchemaVersionColumnName] != float64(1) {
+					t.Errorf("Expected the user admin migrated but got %s", string(stub.State[userKey("Org1MSP", "admin")]))
+				}
+			}},
+		{Name: "Migrate by a user who is not an administrator", Function: "Migrate",
+			Setup: func(stub *testsupport.Stub) { stub.SetIdentity(manager) }, Code: errs.PermissionDenied},
+		{Name: "GetReferences returns the users of a role", Function: "GetReferences", Args: []string{UserTableName, models.UserRoleIDColumnName, "Administrators"}, Setup: putAdmin,
+			Check: testsupport.ExpectPayload(`["` + userKey("Org1MSP", "admin") + `"]`)},
+		{Name: "ClearReferences removes the role of the users", Function: "ClearReferences", Args: []string{UserTableName, models.UserRoleIDColumnName, "Administrators"}, Setup: putAdmin,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var user models.User
+				json.Unmarshal(stub.State[userKey("Org1MSP", "admin")], &user)
+				if user.ADLogin != "admin" || user.RoleID != "" {
+					t.Errorf("Expected the user without role but got %s", string(stub.State[userKey("Org1MSP", "admin")]))
+				}
+			}},
+		{Name: "ClearReferences without the role management feature", Function: "ClearReferences", Args: []string{UserTableName, models.UserRoleIDColumnName, "Administrators"},
+			Setup: func(stub *testsupport.Stub) {
+				putAdmin(stub)
+				stub.SetIdentity(manager)
+			}, Code: errs.PermissionDenied},
+		{Name: "ClearReferences of a column no chaincode references", Function: "ClearReferences", Args: []string{UserTableName, "email", "admin@example.com"}, Setup: putAdmin,
+			Code: errs.InvalidArgument},
+		{Name: "CreateOrgUnit stores the department", Function: "CreateOrgUnit", Args: []string{"Backend", "OU1"}, Setup: putOrganisation,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var orgUnit models.OrgUnit
+				json.Unmarshal(stub.State[string(res.Payload)], &orgUnit)
+				if orgUnit.OrgUnitName != "Backend" || orgUnit.ParentID != "OU1" {
+					t.Errorf("Unexpected org unit %s", string(stub.State[string(res.Payload)]))
+				}
+			}},
+		{Name: "CreateOrgUnit under an unknown parent", Function: "CreateOrgUnit", Args: []string{"Backend", "OU9"}, Setup: putOrganisation, Code: errs.NotFound},
+		{Name: "CreateOrgUnit by a user who can not manage the users", Function: "CreateOrgUnit", Args: []string{"Backend"}, Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "GetOrgUnits returns the departments", Function: "GetOrgUnits", Setup: putOrganisation,
+			Check: testsupport.ExpectCount(2)},
+		{Name: "GetOrgUnitUsers returns the users of the departments below", Function: "GetOrgUnitUsers", Args: []string{"OU1"}, Setup: putOrganisation,
+			Check: testsupport.ExpectCount(3)},
+		{Name: "GetOrgUnitUsers of a department at the bottom", Function: "GetOrgUnitUsers", Args: []string{"OU2"}, Setup: putOrganisation,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetOrgUnitUsers of an unknown department", Function: "GetOrgUnitUsers", Args: []string{"OU9"}, Setup: putOrganisation, Code: errs.NotFound},
+		{Name: "GetOrgUnitUsers hides the secrets", Function: "GetOrgUnitUsers", Args: []string{"OU1"}, Setup: putOrganisation,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if strings.Contains(string(res.Payload), "5ec2e7") {
+					t.Errorf("Expected the users without their secret hash but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetOrgUnitUsers by a manager returns their reports", Function: "GetOrgUnitUsers", Args: []string{"OU1"}, Setup: asManager,
+			Check: testsupport.ExpectCount(2)},
+		{Name: "GetOrgUnitUsers by a user who manages nobody", Function: "GetOrgUnitUsers", Args: []string{"OU1"}, Setup: asDave, Code: errs.PermissionDenied},
+		{Name: "GetOrgUnits by a manager", Function: "GetOrgUnits", Setup: asManager,
+			Check: testsupport.ExpectCount(2)},
+		{Name: "GetOrgUnits by a user who manages nobody", Function: "GetOrgUnits", Setup: asDave, Code: errs.PermissionDenied},
+		{Name: "AssignUser sets the department and the manager", Function: "AssignUser", Args: []string{"admin", "OU1", "manager"}, Setup: putOrganisation,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var user models.User
+				json.Unmarshal(stub.State[userKey("Org1MSP", "admin")], &user)
+				if user.OrgUnitID != "OU1" || user.ManagerID != "manager" || user.PublicKey != admin.PublicKey() {
+					t.Errorf("Unexpected user %s", string(stub.State[userKey("Org1MSP", "admin")]))
+				}
+			}},
+		{Name: "AssignUser under one of the reports", Function: "AssignUser", Args: []string{"manager", "OU1", "carol"}, Setup: putOrganisation, Status: shim.ERROR},
+		{Name: "AssignUser under the user", Function: "AssignUser", Args: []string{"bob", "OU1", "bob"}, Setup: putOrganisation, Status: shim.ERROR},
+		{Name: "AssignUser under an unknown manager", Function: "AssignUser", Args: []string{"bob", "OU1", "dave"}, Setup: putOrganisation, Code: errs.NotFound},
+		{Name: "AssignUser by a user who can not manage the users", Function: "AssignUser", Args: []string{"bob", "", ""}, Setup: asManager, Code: errs.PermissionDenied},
+		{Name: "GetReports returns the direct and indirect reports", Function: "GetReports", Args: []string{"manager"}, Setup: asManager,
+			Check: testsupport.ExpectCount(2)},
+		{Name: "GetReports returns the direct reports", Function: "GetReports", Args: []string{"manager", DirectReports}, Setup: asManager,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetReports by a user managing the users", Function: "GetReports", Args: []string{"bob"}, Setup: putOrganisation,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetReports hides the secrets", Function: "GetReports", Args: []string{"manager"}, Setup: asManager,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if strings.Contains(string(res.Payload), "5ec2e7") {
+					t.Errorf("Expected the reports without their secret hash but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetReports of another manager", Function: "GetReports", Args: []string{"admin"}, Setup: asManager, Status: shim.ERROR},
+		{Name: "CheckUserAccess of an indirect report", Function: "CheckUserAccess", Args: []string{"carol", core.SkillPlanManagementFeatureID, "1"}, Setup: asManager,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserAccess of the caller", Function: "CheckUserAccess", Args: []string{"manager", core.SkillPlanManagementFeatureID, "1"}, Setup: asManager,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserAccess of a user by the feature", Function: "CheckUserAccess", Args: []string{"admin", "SkillPlan", "0"}, Setup: asManager,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "CheckUserAccess of a user who is not a report", Function: "CheckUserAccess", Args: []string{"admin", "SkillPlan", "1"}, Setup: asManager,
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "CheckUserAccess with missing arguments", Function: "CheckUserAccess", Args: []string{"carol"}, Code: errs.InvalidArgument},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
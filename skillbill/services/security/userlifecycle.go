@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// GetUser returns a user without the hash of their secret.
+// The user, their managers and the users who can read the users can get it.
+// args[0] is ad login
+func (s *SecurityChaincode) GetUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+
+	allowed, err := canAccessUser(APIstub, adLogin, core.UserManagementFeatureID, "0")
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get user " + adLogin))
+	}
+
+	if !allowed {
+		return errs.Fail(errs.PermissionDenied, "Permission denied, the caller can not see the user " + adLogin)
+	}
+
+	user, err := getUser(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get user " + adLogin))
+	}
+
+	data, _ := json.Marshal(withoutSecret(*user))
+
+	return shim.Success(data)
+}
+
+// UpdateUser changes the role a user is registered with, only the users who can manage the users can.
+// A new second factor can be passed in the transient field "secondfactor", the secret of the user is replaced.
+// args[0] is ad login, args[1] is the optional role id, the user keeps their role without it
+func (s *SecurityChaincode) UpdateUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+	var roleID = optionalArg(args, 1)
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	user, err := getUser(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update user " + adLogin))
+	}
+
+	if roleID != "" && roleID != user.RoleID {
+		if err := checkRole(APIstub, roleID); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to update user " + adLogin))
+		}
+
+		user.RoleID = roleID
+	}
+
+	transient, err := APIstub.GetTransient()
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get transient data"))
+	}
+
+	if secondFactor, ok := transient[SecondFactorTransientKey]; ok && len(secondFactor) > 0 {
+		err = saveSecondFactor(APIstub, user, secondFactor)
+
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to store the secret of user " + adLogin))
+		}
+	}
+
+	return saveUser(APIstub, user, "update")
+}
+
+// DisableUser stops a user from login and accessing any feature, only the users who can manage the users can.
+// The callers can not disable themselves.
+// args[0] is ad login
+func (s *SecurityChaincode) DisableUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	if caller, err := utils.GetCurrentUser(APIstub); err == nil && caller == adLogin {
+		return errs.Fail(errs.InvalidArgument, "Failed to disable user " + adLogin + ", the callers can not disable themselves")
+	}
+
+	user, err := getUser(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to disable user " + adLogin))
+	}
+
+	user.Disabled = true
+
+	return saveUser(APIstub, user, "disable")
+}
+
+// EnableUser lets a disabled user login again, only the users who can manage the users can
+// args[0] is ad login
+func (s *SecurityChaincode) EnableUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	user, err := getUser(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to enable user " + adLogin))
+	}
+
+	user.Disabled = false
+
+	return saveUser(APIstub, user, "enable")
+}
+
+// RotatePublicKey registers the public key of the renewed certificate of a user.
+// The old and the new key are signed with the registered key of the user, so the user proves they own the old identity
+// and the signature can not be replayed once the key has changed,
+// or the caller can manage the users and passes no signature. A disabled or pending user can only be rotated by the latter.
+// args[0] is ad login, args[1] is the new public key (hex of PKIX),
+// args[2] is the optional hex of the ECDSA signature of the sha256 of "<ad login>:<old public key>:<new public key>"
+func (s *SecurityChaincode) RotatePublicKey(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+	var publicKey = args[1]
+
+	user, err := getUser(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to rotate the key of user " + adLogin))
+	}
+
+	if len(args) == 3 && args[2] != "" {
+		if user.Disabled || user.Pending {
+			return errs.Fail(errs.PermissionDenied, "Failed to rotate the key of user " + adLogin + ", the user is disabled or pending approval")
+		}
+
+		err = utils.VerifySignature(user.PublicKey, []byte(adLogin+":"+user.PublicKey+":"+publicKey), args[2])
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to rotate the key of user " + adLogin))
+		}
+	} else {
+		err = accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+		if err != nil {
+			return errs.Response(err)
+		}
+	}
+
+	query := `{"selector":{"` + models.DocTypeColumnName + `":"` + UserTableName + `","` + models.UserPublicKeyColumnName + `":"` + publicKey + `"}}`
+	data, err := userRepo.GetByQuery(APIstub, query)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to rotate the key of user " + adLogin))
+	}
+
+	var users []models.User
+	json.Unmarshal(data, &users)
+	if len(users) > 0 {
+		return errs.Fail(errs.AlreadyExists, "Failed to rotate the key of user " + adLogin + ", the public key is registered for " + users[0].ADLogin)
+	}
+
+	user.PublicKey = publicKey
+
+	return saveUser(APIstub, user, "rotate the key of")
+}
+
+// IsCallerEnabled is false when the caller is a registered user who has been disabled or is pending approval,
+// a caller who is not registered is enabled. It fails when the record of the caller can not be read.
+// It lets the access control of the chaincodes deny the disabled users who have roles in their certificate.
+func (s *SecurityChaincode) IsCallerEnabled(APIstub shim.ChaincodeStubInterface) pb.Response {
+
+	enabled, err := isCallerEnabled(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not check the caller"))
+	}
+
+	return shim.Success([]byte(strconv.FormatBool(enabled)))
+}
+
+func isCallerEnabled(stub shim.ChaincodeStubInterface) (bool, error) {
+	mspID, commonName, err := utils.GetCreatorIdentity(stub)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := getUserByIdentity(stub, mspID, commonName)
+	if errs.Is(err, errs.NotFound) {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return !user.Disabled && !user.Pending, nil
+}
+
+// saveSecondFactor keeps a hash of the second factor in the private collection, the user gets the hash of the secret
+func saveSecondFactor(stub shim.ChaincodeStubInterface, user *models.User, secondFactor []byte) error {
+	var secret = models.UserSecret{ADLogin: user.ADLogin, SecondFactorHash: hashSecondFactor(user.ADLogin, secondFactor), DocType: models.UserSecretDocType}
+
+	secretData, _ := json.Marshal(secret)
+
+	hash, err := userSecretRepo.Save(stub, userKey(user.MSPID, user.ADLogin), secretData)
+	if err != nil {
+		return err
+	}
+
+	user.SecretHash = hash
+
+	return nil
+}
+
+func saveUser(stub shim.ChaincodeStubInterface, user *models.User, action string) pb.Response {
+	data, _ := json.Marshal(user)
+
+	err := userRepo.Save(stub, userKey(user.MSPID, user.ADLogin), data)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to " + action + " user " + user.ADLogin))
+	}
+
+	return shim.Success(nil)
+}
+
+// withoutSecret returns the user without the hash of their secret, which is only read by the chaincode
+func withoutSecret(user models.User) models.User {
+	user.SecretHash = ""
+
+	return user
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// ActiveRoles is the optional argument of GetUserRoles to skip the assignments which are not active
+const ActiveRoles string = "active"
+
+// AssignRole assigns a role to a user, only the users who can manage the users can.
+// Without scope the features of the role are granted globally, in addition to the role the user is registered with.
+// Within a scope they are granted for the records of the scope, e.g. the skills of a knowledge group.
+// args[0] is ad login, args[1] is role id, args[2] is the optional scope id (knowledge group id or track id),
+// args[3] and args[4] are the optional validity window in RFC3339, from the transaction time and without end by default
+func (s *SecurityChaincode) AssignRole(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+	var roleID = args[1]
+	var scopeID, validFrom, validTo string
+	if len(args) > 2 {
+		scopeID = args[2]
+	}
+	if len(args) > 3 {
+		validFrom = args[3]
+	}
+	if len(args) > 4 {
+		validTo = args[4]
+	}
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	now, err := utils.GetTxTime(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign role " + roleID))
+	}
+
+	if validFrom == "" {
+		validFrom = now
+	}
+
+	if err := checkValidity(validFrom, validTo); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign role " + roleID))
+	}
+
+	if _, err := getUser(APIstub, adLogin); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign role " + roleID))
+	}
+
+	if err := checkRole(APIstub, roleID); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign role " + roleID))
+	}
+
+	assignments, err := getUserRoles(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign role " + roleID))
+	}
+
+	for _, assignment := range assignments {
+		if assignment.RoleID == roleID && assignment.ScopeID == scopeID && overlaps(assignment, validFrom, validTo) {
+			return errs.Fail(errs.AlreadyExists, "Failed to assign role " + roleID + ", the user " + adLogin + " has it within " + describeScope(scopeID) + " from " + assignment.ValidFrom + " already")
+		}
+	}
+
+	assignedBy, _ := utils.GetCurrentUser(APIstub)
+
+	var userRole = models.UserRole{ID: utils.NewID(APIstub, models.UserRoleDocType, adLogin, roleID, scopeID), UserID: adLogin, RoleID: roleID, ScopeID: scopeID,
+		ValidFrom: validFrom, ValidTo: validTo, AssignedBy: assignedBy, DocType: models.UserRoleDocType}
+
+	data, _ := json.Marshal(userRole)
+
+	err = userRoleRepo.Create(APIstub, userRole.ID, data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to assign role " + roleID))
+	}
+
+	return shim.Success([]byte(userRole.ID))
+}
+
+// RevokeRole ends the assignments of a role to a user which are active or to come, only the users who can manage the users can.
+// The assignments are kept with the transaction time as end of their validity, so the history of the roles is not lost.
+// args[0] is ad login, args[1] is role id, args[2] is the optional scope id, the global assignment by default
+func (s *SecurityChaincode) RevokeRole(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+	var roleID = args[1]
+	var scopeID = ""
+	if len(args) == 3 {
+		scopeID = args[2]
+	}
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	now, err := utils.GetTxTime(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to revoke role " + roleID))
+	}
+
+	assignments, err := getUserRoles(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to revoke role " + roleID))
+	}
+
+	revokedBy, _ := utils.GetCurrentUser(APIstub)
+
+	revoked := false
+	for _, assignment := range assignments {
+		if assignment.RoleID != roleID || assignment.ScopeID != scopeID || hasEnded(assignment, now) {
+			continue
+		}
+
+		// An assignment to come never becomes active
+		assignment.ValidTo = now
+		if assignment.ValidFrom > now {
+			assignment.ValidTo = assignment.ValidFrom
+		}
+		assignment.RevokedBy = revokedBy
+
+		data, _ := json.Marshal(assignment)
+
+		err = userRoleRepo.Save(APIstub, assignment.ID, data)
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to revoke role " + roleID))
+		}
+
+		revoked = true
+	}
+
+	if !revoked {
+		return errs.Fail(errs.NotFound, "Failed to revoke role " + roleID + ", the user " + adLogin + " does not have it within " + describeScope(scopeID))
+	}
+
+	return shim.Success(nil)
+}
+
+// GetUserRoles returns the role assignments of a user ordered by the start of their validity, including the revoked and expired ones.
+// The user, their managers and the users who can read the users can list them.
+// args[0] is ad login, args[1] is optional "active" to return the active assignments only
+func (s *SecurityChaincode) GetUserRoles(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var adLogin = args[0]
+
+	allowed, err := canAccessUser(APIstub, adLogin, core.UserManagementFeatureID, "0")
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the roles of " + adLogin))
+	}
+
+	if !allowed {
+		return errs.Fail(errs.PermissionDenied, "Permission denied, the caller can not see the roles of " + adLogin)
+	}
+
+	assignments, err := getUserRoles(APIstub, adLogin)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the roles of " + adLogin))
+	}
+
+	if len(args) == 2 && args[1] == ActiveRoles {
+		assignments, err = getActiveRoles(APIstub, assignments)
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to get the roles of " + adLogin))
+		}
+	}
+
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].ValidFrom < assignments[j].ValidFrom
+	})
+
+	data, _ := json.Marshal(assignments)
+
+	return shim.Success(data)
+}
+
+// GetCurrentRoles returns the global roles of the caller, the role they are registered with and the active global assignments
+func (s *SecurityChaincode) GetCurrentRoles(APIstub shim.ChaincodeStubInterface) pb.Response {
+
+	roles, err := getCurrentRoles(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the roles of the current user"))
+	}
+
+	data, _ := json.Marshal(roles)
+
+	return shim.Success(data)
+}
+
+// GetScopedRoles returns the roles actively assigned to the caller within one of the scopes, a caller who is not registered has none
+// args[0] is the scope ids separated by comma
+func (s *SecurityChaincode) GetScopedRoles(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	roles, err := getScopedRoles(APIstub, strings.Split(optionalArg(args, 0), ","))
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the scoped roles"))
+	}
+
+	data, _ := json.Marshal(roles)
+
+	return shim.Success(data)
+}
+
+// getCurrentRoles returns the role the caller is registered with followed by the roles actively assigned to them without scope
+func getCurrentRoles(stub shim.ChaincodeStubInterface) ([]string, error) {
+	currentUser, err := getCurrentUser(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	if currentUser.RoleID != "" {
+		roles = append(roles, currentUser.RoleID)
+	}
+
+	return appendActiveRoles(stub, roles, currentUser.ADLogin, map[string]bool{"": true})
+}
+
+func getScopedRoles(stub shim.ChaincodeStubInterface, scopeIDs []string) ([]string, error) {
+	roles := []string{}
+
+	currentUser, err := getCurrentUser(stub)
+	if err != nil {
+		return roles, nil
+	}
+
+	scopes := map[string]bool{}
+	for _, scopeID := range scopeIDs {
+		if scopeID != "" {
+			scopes[scopeID] = true
+		}
+	}
+
+	return appendActiveRoles(stub, roles, currentUser.ADLogin, scopes)
+}
+
+// appendActiveRoles adds the roles actively assigned to the user within the scopes, a role is added once
+func appendActiveRoles(stub shim.ChaincodeStubInterface, roles []string, adLogin string, scopes map[string]bool) ([]string, error) {
+	assignments, err := getUserRoles(stub, adLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err = getActiveRoles(stub, assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, role := range roles {
+		found[role] = true
+	}
+
+	for _, assignment := range assignments {
+		if scopes[assignment.ScopeID] && !found[assignment.RoleID] {
+			found[assignment.RoleID] = true
+			roles = append(roles, assignment.RoleID)
+		}
+	}
+
+	return roles, nil
+}
+
+// getActiveRoles returns the assignments whose validity window contains the transaction time
+func getActiveRoles(stub shim.ChaincodeStubInterface, assignments []models.UserRole) ([]models.UserRole, error) {
+	now, err := utils.GetTxTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	active := []models.UserRole{}
+	for _, assignment := range assignments {
+		if assignment.ValidFrom <= now && !hasEnded(assignment, now) {
+			active = append(active, assignment)
+		}
+	}
+
+	return active, nil
+}
+
+// getUserRoles returns the role assignments of a user
+func getUserRoles(stub shim.ChaincodeStubInterface, adLogin string) ([]models.UserRole, error) {
+	query := `{"selector":{"` + models.DocTypeColumnName + `":"` + models.UserRoleDocType + `","` + models.UserRoleUserIDColumnName + `":"` + adLogin + `"}}`
+
+	data, err := userRoleRepo.GetByQuery(stub, query)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := []models.UserRole{}
+	json.Unmarshal(data, &assignments)
+
+	return assignments, nil
+}
+
+// checkValidity returns an error unless the bounds are RFC3339 in UTC, so they compare as text, and the window is not empty
+func checkValidity(validFrom string, validTo string) error {
+	for _, bound := range []string{validFrom, validTo} {
+		if bound == "" {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, bound)
+		if err != nil || date.UTC().Format(time.RFC3339) != bound {
+			return errs.Errorf(errs.InvalidArgument, "The date %s is not RFC3339 in UTC, e.g. 2006-01-02T15:04:05Z", bound)
+		}
+	}
+
+	if validTo != "" && validTo <= validFrom {
+		return errs.Errorf(errs.InvalidArgument, "The validity ends at %s, before it starts at %s", validTo, validFrom)
+	}
+
+	return nil
+}
+
+func hasEnded(assignment models.UserRole, now string) bool {
+	return assignment.ValidTo != "" && assignment.ValidTo <= now
+}
+
+// overlaps is true when the validity window of the assignment shares a moment with the window from validFrom to validTo
+func overlaps(assignment models.UserRole, validFrom string, validTo string) bool {
+	startsBefore := validTo == "" || assignment.ValidFrom < validTo
+	endsAfter := assignment.ValidTo == "" || assignment.ValidTo > validFrom
+
+	return startsBefore && endsAfter
+}
+
+func describeScope(scopeID string) string {
+	if scopeID == "" {
+		return "the global scope"
+	}
+
+	return scopeID
+}
+
+// checkRole returns an error unless the role exists in the role chaincode
+func checkRole(stub shim.ChaincodeStubInterface, roleID string) error {
+	response := core.InvokeChaincode(stub, "role", "getAllByQuery", models.DocTypeColumnName+","+models.RoleDocType, models.RoleIDColumnName+","+roleID)
+	if response.Status != shim.OK {
+		return errs.Wrapf(errs.FromResponse(response), "Could not get the role %s", roleID)
+	}
+
+	var roles []models.Role
+	json.Unmarshal(response.Payload, &roles)
+
+	if len(roles) == 0 {
+		return errs.Errorf(errs.NotFound, "Could not find any role with id %s", roleID)
+	}
+
+	return nil
+}
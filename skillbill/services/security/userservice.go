@@ -1,134 +1,183 @@
-package main
-
-import (
-	"bytes"
-	"encoding/hex"
-	"encoding/json"
-	"fmt"
-	"strconv"
-
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	pb "github.com/hyperledger/fabric/protos/peer"
-	"github.com/skillbill/models"
-	"github.com/skillbill/packages/utils"
-	"golang.org/x/crypto/bcrypt"
-)
-
-// RegisterUser to anonymous user can register a user
-func (s *SecurityChaincode) RegisterUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
-	}
-	var adLogin = args[0]
-	var roleID = args[1]
-	var password = args[2]
-	response, _ := utils.GetPublicKey(APIstub)
-	publicKey := hex.EncodeToString([]byte(response))
-
-	passbytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Could not hash the password, err %s", err))
-	}
-
-	return s.AddUser(APIstub, []string{adLogin, publicKey, roleID, string(passbytes)})
-}
-
-// AddUser is add new user
-func (s *SecurityChaincode) AddUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
-	if len(args) != 4 {
-		return shim.Error("Incorrect number of columns. Expecting 4 but " + strconv.Itoa(len(args)) + " is " + args[0])
-	}
-	var adLogin = args[0]
-	var publicKey = args[1]
-	var roleID = args[2]
-	var hashedPassword = args[3]
-
-	userAdLoginRes, err := userRepo.Any(APIstub, func(item interface{}) bool { return item.(models.User).ADLogin == adLogin })
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Get error %s", err))
-	}
-
-	b, _ := strconv.ParseBool(string(userAdLoginRes))
-	if b == true {
-		return shim.Error("User login " + adLogin + " existed already")
-	}
-
-	userPublicKeyRes, err := userRepo.Any(APIstub, func(item interface{}) bool { return item.(models.User).PublicKey == publicKey })
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Get error %s", err))
-	}
-
-	b, _ = strconv.ParseBool(string(userPublicKeyRes))
-	if b == true {
-		return shim.Error("User publickey existed already")
-	}
-
-	var user = models.User{ADLogin: adLogin, PublicKey: publicKey, RoleID: roleID, HashedPassword: hashedPassword, DocType: UserTableName}
-
-	data, _ := json.Marshal(user)
-
-	err = userRepo.Save(APIstub, user.ADLogin, data)
-
-	if err != nil {
-		return shim.Error("Failed to create knowledge group " + args[0] + " due to " + err.Error())
-	}
-
-	return shim.Success([]byte(user.ADLogin))
-}
-
-// GetAllUsers is get all users by doctype (user)
-func (s *SecurityChaincode) GetAllUsers(APIstub shim.ChaincodeStubInterface) pb.Response {
-
-	users, err := userRepo.GetAll(APIstub)
-	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get all users %s", err))
-	}
-
-	return shim.Success(users)
-}
-
-// GetUserByPublicKey is
-func (s *SecurityChaincode) GetUserByPublicKey(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	publicKey := args[0]
-	query := `{"selector":{"` + UserPublicKeyColumnName + `":"` + publicKey + `"}}`
-	resultsIterator, err := APIstub.GetQueryResult(query)
-	if err != nil {
-		return shim.Error(err.Error())
-	}
-	defer resultsIterator.Close()
-
-	// buffer is a JSON array containing QueryResults
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
-
-	bArrayMemberAlreadyWritten := false
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		// Add a comma before array members, suppress it for the first array member
-		if bArrayMemberAlreadyWritten == true {
-			buffer.WriteString(",")
-		}
-		buffer.WriteString(string(queryResponse.Value))
-		bArrayMemberAlreadyWritten = true
-	}
-	buffer.WriteString("]")
-
-	var users []models.User
-	json.Unmarshal([]byte(buffer.String()), &users)
-	if len(users) > 1 {
-		return shim.Error("multiple users with the same public key " + publicKey)
-	}
-
-	fmt.Printf("- getUserByPublicKey:\n%s\n", buffer.String())
-
-	return shim.Success(buffer.Bytes())
-}
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/utils"
+)
+
+// RegisterUser to anonymous user can register a user.
+// The user is identified by the MSP ID and CommonName of the caller certificate, no password is sent to the ledger.
+// The user gets the default role "Users" and is pending until a user who can manage the users approves the registration.
+// An optional second factor is passed in the transient field "secondfactor"
+func (s *SecurityChaincode) RegisterUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	mspID, adLogin, err := utils.GetCreatorIdentity(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get the identity of the caller"))
+	}
+
+	response, err := utils.GetPublicKey(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get Certificate"))
+	}
+
+	publicKey := hex.EncodeToString([]byte(response))
+
+	roleID, err := getDefaultRoleID(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to register user " + adLogin))
+	}
+
+	return addUser(APIstub, models.User{ADLogin: adLogin, MSPID: mspID, PublicKey: publicKey, RoleID: roleID, Pending: true, DocType: UserTableName})
+}
+
+// AddUser is add new user, only the users who can manage the users can. The user does not need an approval.
+// args[0] is ad login (CommonName of the certificate), args[1] is MSP ID, args[2] is public key, args[3] is role id
+func (s *SecurityChaincode) AddUser(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "1")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	return addUser(APIstub, models.User{ADLogin: args[0], MSPID: args[1], PublicKey: args[2], RoleID: args[3], DocType: UserTableName})
+}
+
+func addUser(APIstub shim.ChaincodeStubInterface, user models.User) pb.Response {
+	var adLogin = user.ADLogin
+
+	existing, _ := APIstub.GetState(userKey(user.MSPID, adLogin))
+	if len(existing) != 0 {
+		return errs.Fail(errs.AlreadyExists, "User login " + adLogin + " of " + user.MSPID + " existed already")
+	}
+
+	query := `{"selector":{"` + models.DocTypeColumnName + `":"` + UserTableName + `","` + models.UserPublicKeyColumnName + `":"` + user.PublicKey + `"}}`
+	userPublicKeyRes, err := userRepo.GetByQuery(APIstub, query)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create user " + adLogin))
+	}
+
+	var users []models.User
+	json.Unmarshal(userPublicKeyRes, &users)
+	if len(users) > 0 {
+		return errs.Fail(errs.AlreadyExists, "User publickey existed already")
+	}
+
+	// The second factor is optional, only a hash of it is kept in the private collection
+	transient, err := APIstub.GetTransient()
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get transient data"))
+	}
+
+	if secondFactor, ok := transient[SecondFactorTransientKey]; ok && len(secondFactor) > 0 {
+		err = saveSecondFactor(APIstub, &user, secondFactor)
+
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to store the secret of user " + adLogin))
+		}
+	}
+
+	data, _ := json.Marshal(user)
+
+	err = userRepo.Save(APIstub, userKey(user.MSPID, user.ADLogin), data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create user " + adLogin))
+	}
+
+	return shim.Success([]byte(user.ADLogin))
+}
+
+// GetAllUsers is get all users by doctype (user), only the users who can read the users can.
+// The hashes of the secrets are not returned.
+func (s *SecurityChaincode) GetAllUsers(APIstub shim.ChaincodeStubInterface) pb.Response {
+
+	err := accessControl().CheckPermission(APIstub, core.UserManagementFeatureID, "0")
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	data, err := userRepo.GetAll(APIstub)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get all users"))
+	}
+
+	var users []models.User
+	json.Unmarshal(data, &users)
+
+	result := []models.User{}
+	for _, user := range users {
+		result = append(result, withoutSecret(user))
+	}
+
+	data, _ = json.Marshal(result)
+
+	return shim.Success(data)
+}
+
+// UserExists is true when a user is registered with the ad login, it lets the chaincodes validate the users they refer to
+// args[0] is ad login
+func (s *SecurityChaincode) UserExists(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	// An ad login registered by the users of several MSPs exists as well
+	_, err := getUser(APIstub, args[0])
+	if err != nil && !errs.Is(err, errs.NotFound) && !errs.Is(err, errs.Conflict) {
+		return errs.Response(errs.Wrap(err, "Failed to check user " + args[0]))
+	}
+
+	return shim.Success([]byte(strconv.FormatBool(!errs.Is(err, errs.NotFound))))
+}
+
+// GetUserByPublicKey is
+func (s *SecurityChaincode) GetUserByPublicKey(APIstub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	publicKey := args[0]
+	query := `{"selector":{"` + models.UserPublicKeyColumnName + `":"` + publicKey + `"}}`
+	resultsIterator, err := APIstub.GetQueryResult(query)
+	if err != nil {
+		return errs.Response(err)
+	}
+	defer resultsIterator.Close()
+
+	// buffer is a JSON array containing QueryResults
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	bArrayMemberAlreadyWritten := false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errs.Response(err)
+		}
+		// Add a comma before array members, suppress it for the first array member
+		if bArrayMemberAlreadyWritten == true {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString(string(queryResponse.Value))
+		bArrayMemberAlreadyWritten = true
+	}
+	buffer.WriteString("]")
+
+	var users []models.User
+	json.Unmarshal([]byte(buffer.String()), &users)
+	if len(users) > 1 {
+		return errs.Fail(errs.Conflict, "multiple users with the same public key " + publicKey)
+	}
+
+	for i := range users {
+		users[i] = withoutSecret(users[i])
+	}
+
+	data, _ := json.Marshal(users)
+
+	return shim.Success(data)
+}
@@ -1,206 +1,473 @@
-package main
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-)
-
-// SkillChaincode define the Smart Contract structure
-type SkillChaincode struct {
-}
-
-// Init method is called when the Smart Contract "Skill" is instantiated by the blockchain network
-func (s *SkillChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
-	return shim.Success(nil)
-}
-
-// Invoke method is called as a result of an application request to run the Smart Contract "Skill"
-func (s *SkillChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	// Retrieve the requested Smart Contract function and arguments
-	function, args := APIstub.GetFunctionAndParameters()
-
-	// Route to the appropriate handler function to interact with the ledger appropriately
-	if function == "getAll" {
-		return s.getAll(APIstub)
-	} else if function == "getAllByQuery" {
-		return s.getAllByQuery(APIstub)
-	} else if function == "create" {
-		return s.create(APIstub, args)
-	} else if function == "getByID" {
-		return s.getByID(APIstub, args)
-	} else if function == "delete" {
-		return s.delete(APIstub, args)
-	} else if function == "update" {
-		return s.update(APIstub, args)
-	}
-
-	return shim.Error("Invalid Smart Contract function name.")
-}
-
-func (s *SkillChaincode) create(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
-	}
-
-	// level, errlevel := strconv.Atoi(args[5])
-	// if errlevel != nil {
-	// 	fmt.Println(errlevel)
-	// }
-
-	// est, errest := strconv.ParseFloat(args[8], 64)
-	// if errest != nil {
-	// 	fmt.Println(errest)
-	// }
-
-	// ver, errver := strconv.Atoi(args[9])
-	// if errver != nil {
-	// 	fmt.Println(errver)
-	// }
-
-	var skill = Skill{BackwardCompatibleTo: args[1],
-		DescriptionTranslationID: args[2],
-		ImageID:                  args[3],
-		KnowledgeGroupID:         args[4],
-		//Level:                    level,
-		NameTranslationID: args[6],
-		SkillID:           args[7],
-		//TimeEstimationInHours:    est,
-		//Version:                  ver
-	}
-
-	skillAsBytes, _ := json.Marshal(skill)
-	APIstub.PutState(args[0], skillAsBytes)
-
-	return shim.Success(nil)
-}
-
-func (s *SkillChaincode) getAllByQuery(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	resultsIterator, err := APIstub.GetQueryResult(`{"selector":{"DocType":"skill"}}`)
-	if err != nil {
-		return shim.Error(err.Error())
-	}
-	defer resultsIterator.Close()
-
-	// buffer is a JSON array containing
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
-
-	bArrayMemberAlreadyWritten := false
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		// Add a comma before array members, suppress it for the first array member
-		if bArrayMemberAlreadyWritten == true {
-			buffer.WriteString(",")
-		}
-		buffer.WriteString("{\"Key\":")
-		buffer.WriteString("\"")
-		buffer.WriteString(queryResponse.Key)
-		buffer.WriteString("\"")
-
-		buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
-		buffer.WriteString("}")
-		bArrayMemberAlreadyWritten = true
-	}
-	buffer.WriteString("]")
-
-	fmt.Printf("- getAllByQuery:\n%s\n", buffer.String())
-
-	return shim.Success(buffer.Bytes())
-}
-
-func (s *SkillChaincode) getAll(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	startKey := "SKILL0"
-	endKey := "SKILL999"
-
-	resultsIterator, err := APIstub.GetStateByRange(startKey, endKey)
-	if err != nil {
-		return shim.Error(err.Error())
-	}
-	defer resultsIterator.Close()
-
-	// buffer is a JSON array containing
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
-
-	bArrayMemberAlreadyWritten := false
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-		// Add a comma before array members, suppress it for the first array member
-		if bArrayMemberAlreadyWritten == true {
-			buffer.WriteString(",")
-		}
-		buffer.WriteString("{\"Key\":")
-		buffer.WriteString("\"")
-		buffer.WriteString(queryResponse.Key)
-		buffer.WriteString("\"")
-
-		buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
-		buffer.WriteString("}")
-		bArrayMemberAlreadyWritten = true
-	}
-	buffer.WriteString("]")
-
-	fmt.Printf("- GetAllskills:\n%s\n", buffer.String())
-
-	return shim.Success(buffer.Bytes())
-}
-
-func (s *SkillChaincode) getByID(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	skillAsBytes, _ := APIstub.GetState(args[0])
-	return shim.Success(skillAsBytes)
-}
-
-func (s *SkillChaincode) delete(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	APIstub.DelState(args[0])
-	return shim.Success(nil)
-}
-
-func (s *SkillChaincode) update(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	data := &Skill{}
-
-	skillAsBytes, _ := APIstub.GetState(args[0])
-	err := json.Unmarshal(skillAsBytes, data)
-	if err != nil {
-		fmt.Printf("Error creating new Skill Chaincode: %s", err)
-	}
-	//data.Level, err = strconv.Atoi(args[1])
-	if err != nil {
-		fmt.Printf("Error creating new Skill Chaincode: %s", err)
-	}
-	skill2AsBytes, _ := json.Marshal(data)
-	APIstub.PutState(args[0], skill2AsBytes)
-
-	return shim.Success(nil)
-}
-
-func main() {
-	err := shim.Start(new(SkillChaincode))
-	if err != nil {
-		fmt.Printf("Error creating new Skill Chaincode: %s", err)
-	}
-}
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+const SkillAcceptanceCriteriaDocType string = models.SkillAcceptanceCriteriaDocType
+
+// integrity of the skills, the resources, dependencies and acceptance criteria of a skill point to it,
+// as well as the milestones of the milestone chaincode and the planned skills of the skill plan chaincode.
+// The skills point to the knowledge groups of the knowledge group chaincode.
+var integrity = core.Integrity{References: map[string][]core.Reference{
+	models.SkillDocType: {
+		{DocType: models.SkillResourceDocType, Column: models.SkillIDColumnName},
+		{DocType: models.SkillAcceptanceCriteriaDocType, Column: models.SkillIDColumnName},
+		{DocType: models.SkillDependencyDocType, Column: models.SkillIDColumnName},
+		{DocType: models.SkillDependencyDocType, Column: models.SkillDependingOnSkillColumnName},
+		{Chaincode: "milestone", DocType: models.MilestoneSkillDocType, Column: models.MilestoneSkillIDColumnName},
+		{Chaincode: "skillplan", DocType: models.PlannedSkillDocType, Column: models.SkillPlanSkillIDColumnName},
+	},
+}, Inbound: []core.Inbound{
+	{DocType: models.SkillDocType, Column: models.SkillKnowledgeGroupIDColumnName, Clear: true, FeatureID: core.KnowledgeGroupFeatureID},
+}}
+
+// schemas of the arguments of the functions, they are checked before the functions are called
+var schemas = core.Schemas{
+	"create": {{Name: "backward compatible to"}, {Name: "description translation id"}, {Name: "image id"},
+		{Name: "knowledge group id"}, {Name: "level", Type: core.ArgInt}, {Name: "name translation id", Required: true}},
+	"getByID":                   {{Name: "skill id", Required: true}},
+	"delete":                    {{Name: "key", Required: true}, {Name: "delete mode"}},
+	"update":                    {{Name: "skill id", Required: true}, {Name: "level", Type: core.ArgInt}, {Name: "knowledge group id"}},
+	"getPrerequisites":          {{Name: "skill ids", Required: true}},
+	"GetSkillsByKnowledgeGroup": {{Name: "knowledge group id", Required: true}},
+	"getAcceptanceCriteria":     {{Name: "skill id", Required: true}},
+}
+
+// SkillChaincode define the Smart Contract structure
+type SkillChaincode struct {
+}
+
+// Init method is called when the Smart Contract "Skill" is instantiated by the blockchain network
+func (s *SkillChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
+	if err := core.InitAttributeMSPIDs(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to configure the MSPs trusted to issue the certificate attributes"))
+	}
+
+	return shim.Success(nil)
+}
+
+// Invoke method is called as a result of an application request to run the Smart Contract "Skill"
+func (s *SkillChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	// Retrieve the requested Smart Contract function and arguments
+	function, args := APIstub.GetFunctionAndParameters()
+
+	// Check the arguments against the schema of the function, so the handlers can index them
+	if err := schemas.Validate(function, args); err != nil {
+		return errs.Response(err)
+	}
+
+	// Route to the appropriate handler function to interact with the ledger appropriately
+	if function == "getAll" {
+		return s.getAll(APIstub)
+	} else if function == "getAllByQuery" {
+		return s.getAllByQuery(APIstub)
+	} else if function == "create" {
+		return s.create(APIstub, args)
+	} else if function == "getByID" {
+		return s.getByID(APIstub, args)
+	} else if function == "delete" {
+		return s.delete(APIstub, args)
+	} else if function == "update" {
+		return s.update(APIstub, args)
+	} else if function == "getPrerequisites" {
+		return s.getPrerequisites(APIstub, args)
+	} else if function == "GetSkillsByKnowledgeGroup" {
+		return s.getSkillsByKnowledgeGroup(APIstub, args)
+	} else if function == "getAcceptanceCriteria" {
+		return s.getAcceptanceCriteria(APIstub, args)
+	} else if function == "GetReferences" {
+		return integrity.GetReferences(APIstub, args)
+	} else if function == "DeleteReferences" {
+		return integrity.DeleteReferences(APIstub, args)
+	} else if function == "ClearReferences" {
+		return integrity.ClearReferences(APIstub, args)
+	} else if function == "Migrate" {
+		return core.CreateBase().Migrate(APIstub, args)
+	} else if function == "GetMigrations" {
+		return core.CreateBase().GetMigrations(APIstub)
+	} else if function == "Restore" {
+		return core.CreateBase().Restore(APIstub, args, canDeleteSkill)
+	} else if function == "ListArchived" {
+		return core.CreateBase().ListArchived(APIstub, args, canDeleteSkill, models.SkillDocType, models.SkillResourceDocType, models.SkillDependencyDocType, models.SkillAcceptanceCriteriaDocType)
+	} else if function == "Purge" {
+		return core.CreateBase().Purge(APIstub, args)
+	}
+
+	return errs.Fail(errs.InvalidArgument, "Invalid Smart Contract function name.")
+}
+
+// create stores a skill and returns its id, the id is derived from the transaction and the content of the skill
+// args[0] is backward compatible to, args[1] is description translation id, args[2] is image id,
+// args[3] is knowledge group id, args[4] is the level, args[5] is name translation id
+func (s *SkillChaincode) create(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	// est, errest := strconv.ParseFloat(args[8], 64)
+	// if errest != nil {
+	// 	fmt.Println(errest)
+	// }
+
+	// ver, errver := strconv.Atoi(args[9])
+	// if errver != nil {
+	// 	fmt.Println(errver)
+	// }
+
+	var skill = Skill{BackwardCompatibleTo: args[0],
+		DescriptionTranslationID: args[1],
+		ImageID:                  args[2],
+		KnowledgeGroupID:         args[3],
+		Level:                    args[4],
+		NameTranslationID: args[5],
+		SkillID:           utils.NewID(APIstub, models.SkillDocType, args[5], args[3]),
+		DocType:           models.SkillDocType,
+		//TimeEstimationInHours:    est,
+		//Version:                  ver
+	}
+
+	if err := checkGroupAdministrator(APIstub, skill.KnowledgeGroupID); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create skill " + skill.NameTranslationID))
+	}
+
+	skillAsBytes, _ := json.Marshal(skill)
+	err := repository.CreateDocument(APIstub, skill.SkillID, skillAsBytes)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create skill " + skill.NameTranslationID))
+	}
+
+	return shim.Success([]byte(skill.SkillID))
+}
+
+func (s *SkillChaincode) getAllByQuery(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	resultsIterator, err := APIstub.GetQueryResult(`{"selector":{"` + models.DocTypeColumnName + `":"` + models.SkillDocType + `"}}`)
+	if err != nil {
+		return errs.Response(err)
+	}
+	defer resultsIterator.Close()
+
+	// buffer is a JSON array containing
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	bArrayMemberAlreadyWritten := false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errs.Response(err)
+		}
+
+		if repository.IsArchived(queryResponse.Value) {
+			continue
+		}
+		// Add a comma before array members, suppress it for the first array member
+		if bArrayMemberAlreadyWritten == true {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("{\"Key\":")
+		buffer.WriteString("\"")
+		buffer.WriteString(queryResponse.Key)
+		buffer.WriteString("\"")
+
+		buffer.WriteString(", \"Record\":")
+		// Record is a JSON object, so we write as-is
+		buffer.WriteString(string(queryResponse.Value))
+		buffer.WriteString("}")
+		bArrayMemberAlreadyWritten = true
+	}
+	buffer.WriteString("]")
+
+	fmt.Printf("- getAllByQuery:\n%s\n", buffer.String())
+
+	return shim.Success(buffer.Bytes())
+}
+
+func (s *SkillChaincode) getAll(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	startKey := "SKILL0"
+	endKey := "SKILL999"
+
+	resultsIterator, err := APIstub.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return errs.Response(err)
+	}
+	defer resultsIterator.Close()
+
+	// buffer is a JSON array containing
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	bArrayMemberAlreadyWritten := false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errs.Response(err)
+		}
+
+		if repository.IsArchived(queryResponse.Value) {
+			continue
+		}
+		// Add a comma before array members, suppress it for the first array member
+		if bArrayMemberAlreadyWritten == true {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("{\"Key\":")
+		buffer.WriteString("\"")
+		buffer.WriteString(queryResponse.Key)
+		buffer.WriteString("\"")
+
+		buffer.WriteString(", \"Record\":")
+		// Record is a JSON object, so we write as-is
+		buffer.WriteString(string(queryResponse.Value))
+		buffer.WriteString("}")
+		bArrayMemberAlreadyWritten = true
+	}
+	buffer.WriteString("]")
+
+	fmt.Printf("- GetAllskills:\n%s\n", buffer.String())
+
+	return shim.Success(buffer.Bytes())
+}
+
+func (s *SkillChaincode) getByID(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	skillAsBytes, _ := repository.GetDocument(APIstub, args[0])
+	return shim.Success(skillAsBytes)
+}
+
+// getAcceptanceCriteria returns the acceptance criteria of a skill
+// args[0] is skill id
+func (s *SkillChaincode) getAcceptanceCriteria(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var query = `{"selector":{"` + models.DocTypeColumnName + `":"` + SkillAcceptanceCriteriaDocType + `", "` + models.SkillIDColumnName + `":"` + args[0] + `"}}`
+
+	data, err := repository.InitRepo(SkillAcceptanceCriteriaDocType).GetByQuery(APIstub, query)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get acceptance criteria for skill " + args[0]))
+	}
+
+	return shim.Success(data)
+}
+
+// getSkillsByKnowledgeGroup returns the skills of a knowledge group and of the groups below it
+// args[0] is knowledge group id
+func (s *SkillChaincode) getSkillsByKnowledgeGroup(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	response := core.InvokeChaincode(APIstub, "knowledgegroup", "GetSubgroups", args[0])
+	if response.Status != shim.OK {
+		return errs.Response(errs.Wrap(errs.FromResponse(response), "Failed to get the subgroups of knowledge group " + args[0]))
+	}
+
+	var groups []models.KnowledgeGroup
+	json.Unmarshal(response.Payload, &groups)
+
+	groupIDs := []string{}
+	for _, group := range groups {
+		groupIDs = append(groupIDs, group.GroupID)
+	}
+
+	selector := map[string]interface{}{models.DocTypeColumnName: models.SkillDocType, models.SkillKnowledgeGroupIDColumnName: map[string]interface{}{"$in": groupIDs}}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	data, err := repository.InitRepo(models.SkillDocType).GetByQuery(APIstub, string(query))
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the skills of knowledge group " + args[0]))
+	}
+
+	return shim.Success(data)
+}
+
+// getPrerequisites returns the skills with every skill they depend on, directly or not, and the dependencies between them
+// args[0] is the skill ids separated by comma
+func (s *SkillChaincode) getPrerequisites(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	graph := models.SkillGraph{Skills: []models.Skill{}, Dependencies: []models.SkillDependency{}}
+	found := map[string]bool{}
+
+	pending := []string{}
+	for _, skillID := range strings.Split(args[0], ",") {
+		if skillID != "" && !found[skillID] {
+			found[skillID] = true
+			pending = append(pending, skillID)
+		}
+	}
+
+	for len(pending) > 0 {
+		selector := map[string]interface{}{models.DocTypeColumnName: models.SkillDependencyDocType, models.SkillIDColumnName: map[string]interface{}{"$in": pending}}
+		query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+		data, err := repository.InitRepo(models.SkillDependencyDocType).GetByQuery(APIstub, string(query))
+
+		if err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to get the dependencies of skills " + strings.Join(pending, ",")))
+		}
+
+		for _, skillID := range pending {
+			skill := models.Skill{}
+			value, err := repository.GetDocument(APIstub, skillID)
+
+			if err != nil || len(value) == 0 {
+				return errs.Fail(errs.NotFound, "Failed to get prerequisites, because the skill " + skillID + " does not exist.")
+			}
+
+			json.Unmarshal(value, &skill)
+			graph.Skills = append(graph.Skills, skill)
+		}
+
+		var dependencies []models.SkillDependency
+		json.Unmarshal(data, &dependencies)
+
+		pending = []string{}
+		for _, dependency := range dependencies {
+			graph.Dependencies = append(graph.Dependencies, dependency)
+
+			if !found[dependency.DependingOnSkill] {
+				found[dependency.DependingOnSkill] = true
+				pending = append(pending, dependency.DependingOnSkill)
+			}
+		}
+	}
+
+	data, _ := json.Marshal(graph)
+
+	return shim.Success(data)
+}
+
+func (s *SkillChaincode) delete(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	mode, err := core.GetDeleteMode(args, 1)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = canDeleteSkill(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete skill " + args[0]))
+	}
+
+	err = integrity.Delete(APIstub, args[0], mode)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to delete skill " + args[0]))
+	}
+
+	return shim.Success(nil)
+}
+
+// update changes a skill, the caller must administer its knowledge group and the one it is moved to
+// args[0] is skill id, args[1] is the level, args[2] is the knowledge group id, the skill keeps its level or group when it is empty
+func (s *SkillChaincode) update(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	data := &Skill{}
+
+	skillAsBytes, err := repository.GetDocument(APIstub, args[0])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update skill " + args[0]))
+	}
+
+	if len(skillAsBytes) == 0 {
+		return errs.Fail(errs.NotFound, "Failed to update skill " + args[0] + ", the skill does not exist")
+	}
+
+	err = json.Unmarshal(skillAsBytes, data)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update skill " + args[0]))
+	}
+
+	if err := checkGroupAdministrator(APIstub, data.KnowledgeGroupID); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update skill " + args[0]))
+	}
+
+	if len(args) > 2 && args[2] != "" && args[2] != data.KnowledgeGroupID {
+		if err := checkGroupAdministrator(APIstub, args[2]); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to update skill " + args[0]))
+		}
+
+		data.KnowledgeGroupID = args[2]
+	}
+
+	if len(args) > 1 && args[1] != "" {
+		data.Level = args[1]
+	}
+
+	skill2AsBytes, _ := json.Marshal(data)
+	repository.PutDocument(APIstub, args[0], skill2AsBytes)
+
+	return shim.Success(nil)
+}
+
+// checkGroupAdministrator returns an error unless the caller administers the knowledge group of a skill,
+// directly or by one of the groups above it, or can manage the skills within them. A skill without knowledge group is not restricted.
+func checkGroupAdministrator(APIstub shim.ChaincodeStubInterface, groupID string) error {
+
+	if groupID == "" {
+		return nil
+	}
+
+	response := core.InvokeChaincode(APIstub, "knowledgegroup", "CheckGroupAdministrator", groupID, core.SkillManagementFeatureID)
+	if response.Status != shim.OK {
+		return errs.Wrapf(errs.FromResponse(response), "Could not check the administrators of knowledge group %s", groupID)
+	}
+
+	if string(response.Payload) != "true" {
+		return errs.Errorf(errs.PermissionDenied, "Permission denied, the caller does not administer the knowledge group %s", groupID)
+	}
+
+	return nil
+}
+
+// canDeleteSkill checks the caller administers the knowledge group of a skill or of one of its records,
+// to delete, restore or list it among the archived records
+func canDeleteSkill(APIstub shim.ChaincodeStubInterface, key string) error {
+
+	groupID, err := getKnowledgeGroupID(APIstub, key)
+	if err != nil {
+		return err
+	}
+
+	return checkGroupAdministrator(APIstub, groupID)
+}
+
+// getKnowledgeGroupID returns the knowledge group of a skill, or of the skill a resource, dependency or acceptance criteria belongs to
+func getKnowledgeGroupID(APIstub shim.ChaincodeStubInterface, key string) (string, error) {
+
+	var record Skill
+	data, err := repository.GetDocument(APIstub, key)
+	if err != nil {
+		return "", err
+	}
+	json.Unmarshal(data, &record)
+
+	if record.DocType != models.SkillDocType && record.SkillID != "" {
+		data, err = repository.GetDocument(APIstub, record.SkillID)
+		if err != nil {
+			return "", err
+		}
+		json.Unmarshal(data, &record)
+	}
+
+	return record.KnowledgeGroupID, nil
+}
+
+func main() {
+	err := shim.Start(new(SkillChaincode))
+	if err != nil {
+		fmt.Printf("Error creating new Skill Chaincode: %s", err)
+	}
+}
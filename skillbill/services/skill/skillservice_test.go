@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+var administrator = testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": "Administrators"})
+
+func newSkillStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("skill", new(SkillChaincode), testsupport.MustNewIdentity("Org1MSP", "admin", nil))
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("role", testsupport.NewRoleFake(
+		models.RoleFeature{ID: "1", RoleID: "Administrators", FeatureID: core.FeatureManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "2", RoleID: "Administrators", FeatureID: core.KnowledgeGroupFeatureID, AccessLevel: models.ReadWrite}))
+	stub.MockPeer("security", testsupport.NewSecurityFake())
+	stub.MockPeer("milestone", testsupport.NewFakeChaincode().WithReferences())
+	stub.MockPeer("skillplan", testsupport.NewFakeChaincode().WithReferences())
+	stub.MockPeer("knowledgegroup", testsupport.NewFakeChaincode().
+		On("GetSubgroups", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			groups := []models.KnowledgeGroup{{GroupID: args[0]}}
+			if args[0] == "G1" {
+				groups = append(groups, models.KnowledgeGroup{GroupID: "G11", ParentID: "G1"})
+			}
+
+			data, _ := json.Marshal(groups)
+			return shim.Success(data)
+		}).
+		On("CheckGroupAdministrator", testsupport.Returns([]byte("true"))))
+
+	return stub
+}
+
+// denyGroupAdministration makes the caller no administrator of the knowledge groups
+func denyGroupAdministration(stub *testsupport.Stub) {
+	stub.MockPeer("knowledgegroup", testsupport.NewFakeChaincode().On("CheckGroupAdministrator", testsupport.Returns([]byte("false"))))
+}
+
+func putOldSkill(stub *testsupport.Stub) {
+	stub.SetIdentity(administrator)
+	stub.PutRecord("SKILL2", []byte(`{"SkillID":"SKILL2","Level":"2","DocType":"Skill"}`))
+}
+
+func putArchivedSkill(stub *testsupport.Stub) {
+	putSkill(stub)
+	stub.PutRecord("SKILL3", []byte(`{"skillid":"SKILL3","knowledgegroupid":"G2","doctype":"skill","deleted":true,"deletedby":"admin","deletedat":"2018-06-01T00:00:00Z"}`))
+}
+
+func putSkill(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "SKILL1", Skill{SkillID: "SKILL1", KnowledgeGroupID: "G1", Level: "1", Version: "1", DocType: "skill"})
+	testsupport.PutJSON(stub, "AC1", SkillAcceptanceCriteria{ID: "AC1", SkillID: "SKILL1", DescriptionTranslationID: "TRANS1", DocType: SkillAcceptanceCriteriaDocType})
+}
+
+func TestSkillInvoke(t *testing.T) {
+	milestones := testsupport.NewFakeChaincode().WithReferences("MS1")
+
+	testsupport.RunInvokeCases(t, newSkillStub, []testsupport.InvokeCase{
+		{Name: "getAll returns the skill range", Function: "getAll", Setup: putSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getAllByQuery filters by doctype", Function: "getAllByQuery", Setup: putSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "create stores the skill with a derived id", Function: "create", Args: []string{"", "TRANS2", "IMG1", "", "3", "TRANS1"},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill Skill
+				json.Unmarshal(stub.State[string(res.Payload)], &skill)
+				if skill.SkillID != string(res.Payload) || skill.NameTranslationID != "TRANS1" || skill.ImageID != "IMG1" || skill.Level != "3" || skill.DocType != models.SkillDocType {
+					t.Errorf("Expected the skill %s but got %s", string(res.Payload), string(stub.State[string(res.Payload)]))
+				}
+			}},
+		{Name: "create with wrong number of arguments", Function: "create", Args: []string{"SKILL2"}, Code: errs.InvalidArgument},
+		{Name: "create with an invalid level", Function: "create", Args: []string{"", "TRANS2", "IMG1", "", "high", "TRANS1"}, Code: errs.InvalidArgument},
+		{Name: "getByID returns the skill", Function: "getByID", Args: []string{"SKILL1"}, Setup: putSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill Skill
+				json.Unmarshal(res.Payload, &skill)
+				if skill.SkillID != "SKILL1" {
+					t.Errorf("Expected the skill SKILL1 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "getByID with missing arguments", Function: "getByID", Code: errs.InvalidArgument},
+		{Name: "getAcceptanceCriteria returns the criteria of the skill", Function: "getAcceptanceCriteria", Args: []string{"SKILL1"}, Setup: putSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getAcceptanceCriteria with missing arguments", Function: "getAcceptanceCriteria", Code: errs.InvalidArgument},
+		{Name: "GetSkillsByKnowledgeGroup returns the skills of the group", Function: "GetSkillsByKnowledgeGroup", Args: []string{"G1"}, Setup: putArchivedSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetSkillsByKnowledgeGroup includes the skills of the subgroups", Function: "GetSkillsByKnowledgeGroup", Args: []string{"G1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				testsupport.PutJSON(stub, "SKILL4", Skill{SkillID: "SKILL4", KnowledgeGroupID: "G11", DocType: "skill"})
+			},
+			Check: testsupport.ExpectCount(2)},
+		{Name: "GetSkillsByKnowledgeGroup of another group", Function: "GetSkillsByKnowledgeGroup", Args: []string{"G2"}, Setup: putSkill,
+			Check: testsupport.ExpectCount(0)},
+		{Name: "GetSkillsByKnowledgeGroup with missing arguments", Function: "GetSkillsByKnowledgeGroup", Code: errs.InvalidArgument},
+		{Name: "getPrerequisites returns the skills they depend on", Function: "getPrerequisites", Args: []string{"SKILL1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				testsupport.PutJSON(stub, "SKILL4", Skill{SkillID: "SKILL4", TimeEstimationInHours: "4", DocType: "skill"})
+				testsupport.PutJSON(stub, "SD1", models.SkillDependency{ID: "SD1", SkillID: "SKILL1", DependingOnSkill: "SKILL4", DocType: models.SkillDependencyDocType})
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var graph models.SkillGraph
+				json.Unmarshal(res.Payload, &graph)
+				if len(graph.Skills) != 2 || graph.Skills[1].SkillID != "SKILL4" || len(graph.Dependencies) != 1 {
+					t.Errorf("Expected the skill SKILL1 and its prerequisite but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "getPrerequisites of an unknown skill", Function: "getPrerequisites", Args: []string{"SKILL9"}, Code: errs.NotFound},
+		{Name: "delete removes the acceptance criteria", Function: "delete", Args: []string{"AC1"}, Setup: putSkill,
+			Check: testsupport.ExpectNoState("AC1")},
+		{Name: "delete of a skill with acceptance criteria", Function: "delete", Args: []string{"SKILL1"}, Setup: putSkill, Status: shim.ERROR},
+		{Name: "delete cascades to the acceptance criteria and the milestones", Function: "delete", Args: []string{"SKILL1", "cascade"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				stub.MockPeer("milestone", milestones)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["SKILL1"]) > 0 || len(stub.State["AC1"]) > 0 || !milestones.Called("DeleteReferences") {
+					t.Errorf("Expected the skill deleted with its acceptance criteria and milestone skills")
+				}
+			}},
+		{Name: "delete with missing arguments", Function: "delete", Code: errs.InvalidArgument},
+		{Name: "delete of a skill of a group the caller does not administer", Function: "delete", Args: []string{"SKILL1", "cascade"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				denyGroupAdministration(stub)
+			}, Status: shim.ERROR},
+		{Name: "delete of the acceptance criteria of a skill of a group the caller does not administer", Function: "delete", Args: []string{"AC1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				denyGroupAdministration(stub)
+			}, Status: shim.ERROR},
+		{Name: "update of a skill of a group the caller does not administer", Function: "update", Args: []string{"SKILL1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				denyGroupAdministration(stub)
+			}, Status: shim.ERROR},
+		{Name: "delete soft archives the skill with the caller", Function: "delete", Args: []string{"SKILL1", "soft"}, Setup: putSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill map[string]interface{}
+				json.Unmarshal(stub.State["SKILL1"], &skill)
+				if skill[models.DeletedColumnName] != true || skill[models.DeletedByColumnName] != "admin" || skill[models.DeletedAtColumnName] == "" {
+					t.Errorf("Expected the skill archived by admin but got %s", string(stub.State["SKILL1"]))
+				}
+			}},
+		{Name: "delete soft of an archived skill", Function: "delete", Args: []string{"SKILL3", "soft"}, Setup: putArchivedSkill, Status: shim.ERROR},
+		{Name: "getAllByQuery excludes the archived skills", Function: "getAllByQuery", Setup: putArchivedSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "ListArchived returns the archived skills", Function: "ListArchived", Args: []string{"skill"}, Setup: putArchivedSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "ListArchived of a doc type of another chaincode", Function: "ListArchived", Args: []string{"track"}, Status: shim.ERROR},
+		{Name: "Restore brings back the archived skill", Function: "Restore", Args: []string{"SKILL3"}, Setup: putArchivedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if repository.IsArchived(stub.State["SKILL3"]) || strings.Contains(string(stub.State["SKILL3"]), models.DeletedByColumnName) {
+					t.Errorf("Expected the skill restored but got %s", string(stub.State["SKILL3"]))
+				}
+			}},
+		{Name: "Restore of a skill which is not archived", Function: "Restore", Args: []string{"SKILL1"}, Setup: putSkill, Status: shim.ERROR},
+		{Name: "Restore of a skill of a group the caller does not administer", Function: "Restore", Args: []string{"SKILL3"},
+			Setup: func(stub *testsupport.Stub) {
+				putArchivedSkill(stub)
+				denyGroupAdministration(stub)
+			}, Code: errs.PermissionDenied},
+		{Name: "ListArchived skips the skills of the groups the caller does not administer", Function: "ListArchived", Args: []string{"skill"},
+			Setup: func(stub *testsupport.Stub) {
+				putArchivedSkill(stub)
+				denyGroupAdministration(stub)
+			}, Check: testsupport.ExpectCount(0)},
+		{Name: "Purge removes the archived skill", Function: "Purge", Args: []string{"SKILL3"},
+			Setup: func(stub *testsupport.Stub) {
+				putArchivedSkill(stub)
+				stub.SetIdentity(administrator)
+			},
+			Check: testsupport.ExpectNoState("SKILL3")},
+		{Name: "Purge of a skill which is not archived", Function: "Purge", Args: []string{"SKILL1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				stub.SetIdentity(administrator)
+			},
+			Status: shim.ERROR},
+		{Name: "Purge by a caller who is not an administrator", Function: "Purge", Args: []string{"SKILL3"}, Setup: putArchivedSkill, Code: errs.PermissionDenied},
+		{Name: "ClearReferences removes the knowledge group of the skills", Function: "ClearReferences", Args: []string{"skill", models.SkillKnowledgeGroupIDColumnName, "G1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				stub.SetIdentity(administrator)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill Skill
+				json.Unmarshal(stub.State["SKILL1"], &skill)
+				if skill.SkillID != "SKILL1" || skill.KnowledgeGroupID != "" {
+					t.Errorf("Expected the skill without knowledge group but got %s", string(stub.State["SKILL1"]))
+				}
+			}},
+		{Name: "ClearReferences by a caller who can not manage the knowledge groups", Function: "ClearReferences", Args: []string{"skill", models.SkillKnowledgeGroupIDColumnName, "G1"}, Setup: putSkill,
+			Code: errs.PermissionDenied},
+		{Name: "DeleteReferences of a column the skills clear", Function: "DeleteReferences", Args: []string{"skill", models.SkillKnowledgeGroupIDColumnName, "G1"}, Setup: putSkill,
+			Code: errs.InvalidArgument},
+		{Name: "update changes the level", Function: "update", Args: []string{"SKILL1", "2"}, Setup: putSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill Skill
+				json.Unmarshal(stub.State["SKILL1"], &skill)
+				if skill.Level != "2" || skill.KnowledgeGroupID != "G1" {
+					t.Errorf("Expected the level 2 in the knowledge group G1 but got %s", string(stub.State["SKILL1"]))
+				}
+			}},
+		{Name: "update without level keeps the level", Function: "update", Args: []string{"SKILL1", "", "G2"}, Setup: putSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill Skill
+				json.Unmarshal(stub.State["SKILL1"], &skill)
+				if skill.Level != "1" || skill.KnowledgeGroupID != "G2" {
+					t.Errorf("Expected the level 1 in the knowledge group G2 but got %s", string(stub.State["SKILL1"]))
+				}
+			}},
+		{Name: "update with an invalid level", Function: "update", Args: []string{"SKILL1", "high"}, Setup: putSkill, Code: errs.InvalidArgument},
+		{Name: "update with missing arguments", Function: "update", Code: errs.InvalidArgument},
+		{Name: "update of an unknown skill", Function: "update", Args: []string{"SKILL9", "2"}, Code: errs.NotFound},
+		{Name: "update moves the skill to another knowledge group", Function: "update", Args: []string{"SKILL1", "2", "G2"}, Setup: putSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill Skill
+				json.Unmarshal(stub.State["SKILL1"], &skill)
+				if skill.KnowledgeGroupID != "G2" {
+					t.Errorf("Expected the skill in the knowledge group G2 but got %s", string(stub.State["SKILL1"]))
+				}
+			}},
+		{Name: "update moving the skill to a group the caller does not administer", Function: "update", Args: []string{"SKILL1", "2", "G2"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				stub.MockPeer("knowledgegroup", testsupport.NewFakeChaincode().
+					On("CheckGroupAdministrator", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+						return shim.Success([]byte(strconv.FormatBool(args[0] == "G1")))
+					}))
+			}, Code: errs.PermissionDenied},
+		{Name: "getByID upgrades an old record", Function: "getByID", Args: []string{"SKILL2"}, Setup: putOldSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var skill map[string]interface{}
+				json.Unmarshal(res.Payload, &skill)
+				if skill[models.SkillIDColumnName] != "SKILL2" || skill[models.SchemaVersionColumnName] != float64(1) {
+					t.Errorf("Expected the upgraded skill SKILL2 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "Migrate upgrades the stored records", Function: "Migrate", Args: []string{"10"}, Setup: putOldSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var result repository.MigrationResult
+				json.Unmarshal(res.Payload, &result)
+				if !result.Completed || result.Migrated != 1 || !strings.Contains(string(stub.State["SKILL2"]), `"skillid":"SKILL2"`) {
+					t.Errorf("Expected the skill SKILL2 migrated but got %s", string(stub.State["SKILL2"]))
+				}
+			}},
+		// The mock stub ranges over the composite key of the trusted MSPs as well, it is the first key
+		{Name: "Migrate resumes from the bookmark", Function: "Migrate", Args: []string{"2"},
+			Setup: func(stub *testsupport.Stub) {
+				putSkill(stub)
+				putOldSkill(stub)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var result repository.MigrationResult
+				json.Unmarshal(res.Payload, &result)
+				if result.Completed || result.Bookmark != "SKILL1" {
+					t.Errorf("Expected the bookmark SKILL1 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "Migrate of a caller who is not an administrator", Function: "Migrate", Code: errs.PermissionDenied},
+		{Name: "Migrate by an administrator who has been disabled", Function: "Migrate", Args: []string{"10"},
+			Setup: func(stub *testsupport.Stub) {
+				putOldSkill(stub)
+				stub.MockPeer("security", testsupport.NewSecurityFake().On("IsCallerEnabled", testsupport.Returns([]byte("false"))))
+			}, Status: shim.ERROR},
+		{Name: "Migrate with an invalid page size", Function: "Migrate", Args: []string{"all"}, Setup: putOldSkill, Status: shim.ERROR},
+		{Name: "GetMigrations returns the migrations which have run", Function: "GetMigrations",
+			Setup: func(stub *testsupport.Stub) {
+				putOldSkill(stub)
+				stub.Invoke("Migrate")
+			},
+			Check: testsupport.ExpectCount(len(repository.GetMigrations()))},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+var assessmentResultRepo repository.IPrivateRepo
+
+// getAssessmentResult returns the private assessment result of a completed skill (collection members only),
+// to the assessee, their managers and the assessor
+// args[0] is completed skill id
+func getAssessmentResult(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 1")
+	}
+
+	completed, _, err := getCompletedSkill(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = checkAssessmentResultAccess(APIstub, completed)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	data, err := assessmentResultRepo.GetByKey(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get assessment result " + args[0]))
+	}
+
+	return shim.Success(data)
+}
+
+// checkAssessmentResultAccess lets the assessor of the completed skill read its result, anyone else needs the access to the skill plan of the assessee
+func checkAssessmentResultAccess(APIstub shim.ChaincodeStubInterface, completed SkillPlanCompletedSkill) error {
+	certificate, err := utils.GetCreatorCertPEM(APIstub)
+
+	if err == nil && completed.AssessorCertificate != "" && certificate == completed.AssessorCertificate {
+		return nil
+	}
+
+	return checkUserAccess(APIstub, completed.UserID, "0")
+}
+
+// verifyAssessmentResult checks an assessment result against the hash kept on the public state,
+// so it can be verified without access to the collection
+// args[0] is completed skill id, the result is passed in the transient field "assessment" with its salt in the field "salt"
+func verifyAssessmentResult(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 1")
+	}
+
+	completed, _, err := getCompletedSkill(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	result, err := getTransientAssessmentResult(APIstub, completed.ID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to verify assessment result"))
+	}
+
+	// Marshal the struct again, so the hash does not depend on field order or whitespace of the input
+	data, _ := json.Marshal(result)
+
+	return shim.Success([]byte(strconv.FormatBool(utils.HashData(data) == completed.AssessmentHash)))
+}
+
+// getTransientAssessmentResult reads the assessment result and its salt from the transient data of the proposal,
+// so the private fields are not written to the block. The client generates the salt, the chaincode can not
+// as every endorsing peer has to compute the same hash
+func getTransientAssessmentResult(APIstub shim.ChaincodeStubInterface, id string) (SkillPlanAssessmentResult, error) {
+	result := SkillPlanAssessmentResult{}
+
+	transient, err := APIstub.GetTransient()
+	if err != nil {
+		return result, err
+	}
+
+	value, ok := transient[AssessmentTransientKey]
+	if !ok || len(value) == 0 {
+		return result, errs.Errorf(errs.InvalidArgument, "The assessment result must be passed in the transient field %s", AssessmentTransientKey)
+	}
+
+	err = json.Unmarshal(value, &result)
+	if err != nil {
+		return result, errs.Errorf(errs.InvalidArgument, "Could not parse json to assessment result, err %s", err)
+	}
+
+	salt := transient[AssessmentSaltTransientKey]
+	if len(salt) < AssessmentSaltMinLength {
+		return result, errs.Errorf(errs.InvalidArgument, "The assessment result must be salted with at least %d random bytes in the transient field %s", AssessmentSaltMinLength, AssessmentSaltTransientKey)
+	}
+
+	result.ID = id
+	result.Salt = hex.EncodeToString(salt)
+	result.DocType = "assessmentresult"
+
+	return result, nil
+}
+
+// saveAssessmentResult stores the result in the private collection and returns its hash, the hash covers the salt
+func saveAssessmentResult(APIstub shim.ChaincodeStubInterface, result SkillPlanAssessmentResult) (string, error) {
+	data, _ := json.Marshal(result)
+
+	return assessmentResultRepo.Save(APIstub, result.ID, data)
+}
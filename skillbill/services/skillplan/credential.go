@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/credential"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+// signCompletedSkill stores the signature of the record hash made by the assessor, a credential can only be issued for a signed record
+// args[0] is completed skill id, args[1] is the hex of the ASN.1 ECDSA signature of the sha256 of the record hash
+func signCompletedSkill(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	completed, data, err := getCompletedSkill(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	certificate, err := utils.GetCreatorCertPEM(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get Certificate"))
+	}
+
+	if certificate != completed.AssessorCertificate {
+		return errs.Fail(errs.PermissionDenied, "Permission denied, only the assessor can sign the completed skill " + args[0])
+	}
+
+	hash, err := credential.HashRecord(data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to sign completed skill " + args[0]))
+	}
+
+	err = credential.VerifyRecordSignature(certificate, hash, args[1])
+
+	if err != nil {
+		return errs.Fail(errs.InvalidArgument, "Failed to sign completed skill " + args[0] + ", " + err.Error())
+	}
+
+	completed.AssessorSignature = args[1]
+
+	data, _ = json.Marshal(completed)
+
+	repository.PutDocument(APIstub, args[0], data)
+
+	return shim.Success(nil)
+}
+
+// getVerifiableCredential renders a completed skill as a W3C verifiable credential, issued by the assessor who signed it
+// args[0] is completed skill id
+// e.g: ['E1ED5DAD-B286-4522-8A93-926E6D5DC9C9']
+func getVerifiableCredential(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 1")
+	}
+
+	completed, data, err := getCompletedSkill(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	if completed.AssessorSignature == "" {
+		return errs.Fail(errs.Conflict, "Failed to build credential for " + args[0] + ", the assessor has not signed the completed skill")
+	}
+
+	fingerprint, err := credential.CertificateFingerprint(completed.AssessorCertificate)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to build credential for " + args[0] + ", the assessor certificate is invalid"))
+	}
+
+	hash, err := credential.HashRecord(data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to build credential for " + args[0]))
+	}
+
+	vc := credential.VerifiableCredential{
+		Context:      []string{credential.CredentialsContext},
+		ID:           credential.CompletedSkillURNPrefix + completed.ID,
+		Type:         []string{credential.VerifiableCredentialType, credential.SkillCredentialType},
+		Issuer:       fingerprint,
+		IssuanceDate: completed.CompletedOn,
+		CredentialSubject: credential.CredentialSubject{
+			ID:      credential.UserURNPrefix + completed.UserID,
+			SkillID: completed.SkillID,
+			Version: completed.SkillVersion,
+			Level:   completed.SkillLevel},
+		Proof: credential.Proof{
+			Type:               credential.LedgerProofType,
+			Created:            completed.CompletedOn,
+			ProofPurpose:       credential.AssertionMethod,
+			VerificationMethod: fingerprint,
+			TransactionID:      completed.TxID,
+			LedgerKey:          completed.ID,
+			RecordHash:         hash,
+			ProofValue:         completed.AssessorSignature}}
+
+	data, _ = json.Marshal(vc)
+
+	return shim.Success(data)
+}
+
+// exportLedgerProof exports the ledger state a credential is verified against
+// args[0] is completed skill id
+func exportLedgerProof(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 1")
+	}
+
+	completed, data, err := getCompletedSkill(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	proof := credential.LedgerProof{
+		Key:                 completed.ID,
+		TxID:                completed.TxID,
+		Record:              data,
+		AssessorCertificate: completed.AssessorCertificate}
+
+	data, _ = json.Marshal(proof)
+
+	return shim.Success(data)
+}
+
+// getCompletedSkill returns the completed skill together with its raw ledger record
+func getCompletedSkill(APIstub shim.ChaincodeStubInterface, id string) (SkillPlanCompletedSkill, []byte, error) {
+	completed := SkillPlanCompletedSkill{}
+
+	data, err := APIstub.GetState(id)
+
+	if err != nil {
+		return completed, nil, errs.Wrapf(err, "Failed to get completed skill %s", id)
+	}
+
+	if len(string(data)) == 0 {
+		return completed, nil, errs.Errorf(errs.NotFound, "Failed to get completed skill, because the record %s does not exist.", id)
+	}
+
+	err = json.Unmarshal(data, &completed)
+
+	if err != nil || completed.DocType != "completedskill" {
+		return completed, nil, errs.Errorf(errs.InvalidArgument, "The record %s is not a completed skill.", id)
+	}
+
+	return completed, data, nil
+}
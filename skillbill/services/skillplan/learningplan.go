@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+// PlanDateLayout is the format of the planned dates
+const PlanDateLayout string = "2006-01-02"
+
+// generatePlan plans every skill of a track which the user has not completed, a skill is planned after its prerequisites.
+// The skills follow each other, each one lasts its time estimation at the given hours per week.
+// The priority of a skill is the order of its milestone, a prerequisite takes the priority of the skill which needs it.
+// The skills already planned for the user keep their plan. The user, their managers and the skill plan managers can generate the plan.
+// args[0] is user id, args[1] is track id, args[2] is start date, args[3] is hours per week
+func generatePlan(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 4")
+	}
+
+	userID := args[0]
+
+	if err := checkUserAccess(APIstub, userID, "1"); err != nil {
+		return errs.Response(err)
+	}
+
+	startDate, err := time.Parse(PlanDateLayout, args[2])
+
+	if err != nil {
+		return errs.Fail(errs.InvalidArgument, "Invalid start date " + args[2] + ", expecting the format " + PlanDateLayout)
+	}
+
+	hoursPerWeek, err := strconv.ParseFloat(args[3], 64)
+
+	if err != nil || hoursPerWeek <= 0 {
+		return errs.Fail(errs.InvalidArgument, "Invalid hours per week " + args[3] + ", expecting a positive number")
+	}
+
+	gap, err := buildSkillGap(APIstub, userID, args[1])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to generate the plan of user " + userID))
+	}
+
+	planned, err := getPlannedSkillIDs(APIstub, userID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to generate the plan of user " + userID))
+	}
+
+	plan := []SkillPlanPlannedSkill{}
+	hours := 0.0
+
+	for _, missing := range gap.MissingSkills {
+		skills := append(append([]GapSkill{}, missing.Prerequisites...), GapSkill{SkillID: missing.SkillID, TimeEstimationInHours: missing.TimeEstimationInHours})
+
+		for _, skill := range skills {
+			if planned[skill.SkillID] {
+				continue
+			}
+			planned[skill.SkillID] = true
+
+			plannedSkill := SkillPlanPlannedSkill{
+				ID:          utils.NewID(APIstub, models.PlannedSkillDocType, skill.SkillID, userID),
+				PlannedFrom: planDate(startDate, hours, hoursPerWeek, math.Floor),
+				PlannedTo:   planDate(startDate, hours+skill.TimeEstimationInHours, hoursPerWeek, math.Ceil),
+				Priority:    strconv.Itoa(missing.MilestoneOrder),
+				SkillID:     skill.SkillID,
+				UserID:      userID,
+				DocType:     models.PlannedSkillDocType}
+
+			hours += skill.TimeEstimationInHours
+
+			data, _ := json.Marshal(plannedSkill)
+			err = repository.CreateDocument(APIstub, plannedSkill.ID, data)
+
+			if err != nil {
+				return errs.Response(errs.Wrap(err, "Failed to plan skill " + skill.SkillID))
+			}
+
+			plan = append(plan, plannedSkill)
+		}
+	}
+
+	data, _ := json.Marshal(plan)
+
+	return shim.Success(data)
+}
+
+// planDate returns the day when the hours of learning are reached, the days are rounded by the round function
+func planDate(startDate time.Time, hours float64, hoursPerWeek float64, round func(float64) float64) string {
+	days := round(hours * 7 / hoursPerWeek)
+
+	return startDate.AddDate(0, 0, int(days)).Format(PlanDateLayout)
+}
+
+// getPlannedSkillIDs returns the skills which are planned for the user
+func getPlannedSkillIDs(APIstub shim.ChaincodeStubInterface, userID string) (map[string]bool, error) {
+	var query = `{"selector":{"` + models.DocTypeColumnName + `":"` + models.PlannedSkillDocType + `","` + models.SkillPlanUserIDColumnName + `":"` + userID + `"}}`
+
+	data, err := repository.InitRepo(models.PlannedSkillDocType).GetByQuery(APIstub, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var skills []SkillPlanPlannedSkill
+	json.Unmarshal(data, &skills)
+
+	planned := map[string]bool{}
+	for _, skill := range skills {
+		planned[skill.SkillID] = true
+	}
+
+	return planned, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+)
+
+// Open Badges 2.0 vocabulary, see https://www.imsglobal.org/sites/default/files/Badges/OBv2p0Final/index.html
+const (
+	OpenBadgesContext string = "https://w3id.org/openbadges/v2"
+
+	BadgeAssertionType string = "Assertion"
+	BadgeClassType     string = "BadgeClass"
+	BadgeIssuerType    string = "Profile"
+	BadgeHostedType    string = "hosted"
+
+	// BadgeRecipientType is the identity type of the recipient, the url of the user where the ledger records are published
+	BadgeRecipientType string = "url"
+)
+
+type OpenBadgeAssertion struct {
+	Context      string                `json:"@context"`
+	Type         string                `json:"type"`
+	ID           string                `json:"id"`
+	Recipient    OpenBadgeRecipient    `json:"recipient"`
+	Badge        OpenBadgeClass        `json:"badge"`
+	Verification OpenBadgeVerification `json:"verification"`
+	IssuedOn     string                `json:"issuedOn"`
+}
+
+type OpenBadgeRecipient struct {
+	Type     string `json:"type"`
+	Hashed   bool   `json:"hashed"`
+	Salt     string `json:"salt"`
+	Identity string `json:"identity"`
+}
+
+type OpenBadgeClass struct {
+	Type        string            `json:"type"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Image       string            `json:"image,omitempty"`
+	Criteria    OpenBadgeCriteria `json:"criteria"`
+	Issuer      OpenBadgeProfile  `json:"issuer"`
+}
+
+type OpenBadgeCriteria struct {
+	Narrative string `json:"narrative"`
+}
+
+type OpenBadgeProfile struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type OpenBadgeVerification struct {
+	Type string `json:"type"`
+}
+
+// getOpenBadgeAssertion renders a completed skill as an Open Badges 2.0 assertion (JSON-LD)
+// args[0] is completed skill id, args[1] is the base url where ledger records are published
+// e.g: ['E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', 'https://skillbill.example.com/ledger']
+func getOpenBadgeAssertion(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 2")
+	}
+
+	var id = args[0]
+	var baseURL = strings.TrimRight(args[1], "/")
+
+	completed, _, err := getCompletedSkill(APIstub, id)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	skill, err := getSkill(APIstub, completed.SkillID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to build badge for " + id))
+	}
+
+	criteria, err := getAcceptanceCriteriaNarrative(APIstub, skill.SkillID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to build badge for " + id))
+	}
+
+	group, err := getKnowledgeGroup(APIstub, skill.KnowledgeGroupID)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to build badge for " + id))
+	}
+
+	// The recipient is the url of the user, hashed with the record id as salt, so the badge does not expose the login.
+	recipient := baseURL + "/user/" + completed.UserID
+	identityHash := sha256.Sum256([]byte(recipient + completed.ID))
+
+	assertion := OpenBadgeAssertion{
+		Context: OpenBadgesContext,
+		Type:    BadgeAssertionType,
+		ID:      baseURL + "/completedskill/" + completed.ID,
+		Recipient: OpenBadgeRecipient{
+			Type:     BadgeRecipientType,
+			Hashed:   true,
+			Salt:     completed.ID,
+			Identity: "sha256$" + hex.EncodeToString(identityHash[:])},
+		Badge: OpenBadgeClass{
+			Type:        BadgeClassType,
+			ID:          baseURL + "/skill/" + skill.SkillID,
+			Name:        getTranslation(APIstub, skill.NameTranslationID),
+			Description: getTranslation(APIstub, skill.DescriptionTranslationID),
+			Criteria:    OpenBadgeCriteria{Narrative: criteria},
+			Issuer: OpenBadgeProfile{
+				Type: BadgeIssuerType,
+				ID:   baseURL + "/knowledgegroup/" + group.GroupID,
+				Name: group.GroupName}},
+		Verification: OpenBadgeVerification{Type: BadgeHostedType},
+		IssuedOn:     completed.CompletedOn}
+
+	if skill.ImageID != "" {
+		assertion.Badge.Image = baseURL + "/image/" + skill.ImageID
+	}
+
+	data, _ := json.Marshal(assertion)
+
+	return shim.Success(data)
+}
+
+func getSkill(APIstub shim.ChaincodeStubInterface, skillID string) (models.Skill, error) {
+	skill := models.Skill{}
+
+	response := core.InvokeChaincode(APIstub, "skill", "getByID", skillID)
+	if response.Status != shim.OK {
+		return skill, errs.Wrapf(errs.FromResponse(response), "Failed to get skill %s", skillID)
+	}
+
+	if len(response.Payload) == 0 {
+		return skill, errs.Errorf(errs.NotFound, "The skill %s does not exist.", skillID)
+	}
+
+	err := json.Unmarshal(response.Payload, &skill)
+
+	return skill, err
+}
+
+func getKnowledgeGroup(APIstub shim.ChaincodeStubInterface, groupID string) (models.KnowledgeGroup, error) {
+	groups := []models.KnowledgeGroup{}
+
+	response := core.InvokeChaincode(APIstub, "knowledgegroup", "GetByQuery", models.DocTypeColumnName+","+models.KnowledgeGroupDocType, models.KnowledgeGroupIDColumnName+","+groupID)
+	if response.Status != shim.OK {
+		return models.KnowledgeGroup{}, errs.Wrapf(errs.FromResponse(response), "Failed to get knowledge group %s", groupID)
+	}
+
+	err := json.Unmarshal(response.Payload, &groups)
+	if err != nil {
+		return models.KnowledgeGroup{}, err
+	}
+
+	if len(groups) == 0 {
+		return models.KnowledgeGroup{}, errs.Errorf(errs.NotFound, "The knowledge group %s does not exist.", groupID)
+	}
+
+	return groups[0], nil
+}
+
+// getAcceptanceCriteriaNarrative joins the translated acceptance criteria of a skill, one per line
+func getAcceptanceCriteriaNarrative(APIstub shim.ChaincodeStubInterface, skillID string) (string, error) {
+	criteria := []models.SkillAcceptanceCriteria{}
+
+	response := core.InvokeChaincode(APIstub, "skill", "getAcceptanceCriteria", skillID)
+	if response.Status != shim.OK {
+		return "", errs.Wrapf(errs.FromResponse(response), "Failed to get acceptance criteria of skill %s", skillID)
+	}
+
+	err := json.Unmarshal(response.Payload, &criteria)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, item := range criteria {
+		lines = append(lines, "- "+getTranslation(APIstub, item.DescriptionTranslationID))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// getTranslation returns the translated text, or the translation id itself when there is none
+func getTranslation(APIstub shim.ChaincodeStubInterface, translationID string) string {
+	if translationID == "" {
+		return ""
+	}
+
+	response := core.InvokeChaincode(APIstub, "translation", "getByID", translationID)
+	if response.Status != shim.OK || len(response.Payload) == 0 {
+		return translationID
+	}
+
+	translation := models.TranslationObject{}
+	if err := json.Unmarshal(response.Payload, &translation); err != nil || translation.Translation == "" {
+		return translationID
+	}
+
+	return translation.Translation
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+)
+
+// GapSkill is a skill which the user has not completed yet
+type GapSkill struct {
+	SkillID               string  `json:"skillid"`
+	TimeEstimationInHours float64 `json:"timeestimationinhours"`
+}
+
+// MissingSkill is a skill of the track which the user lacks, with the prerequisites the user lacks as well.
+// The prerequisites are ordered so that a skill comes after the skills it depends on.
+type MissingSkill struct {
+	SkillID                    string     `json:"skillid"`
+	MilestoneID                string     `json:"milestoneid"`
+	MilestoneOrder             int        `json:"milestoneorder"`
+	TimeEstimationInHours      float64    `json:"timeestimationinhours"`
+	Prerequisites              []GapSkill `json:"prerequisites"`
+	TotalTimeEstimationInHours float64    `json:"totaltimeestimationinhours"`
+}
+
+// SkillGap is what a user lacks to complete a track, the total counts every missing skill once
+type SkillGap struct {
+	UserID                     string         `json:"userid"`
+	TrackID                    string         `json:"trackid"`
+	MissingSkills              []MissingSkill `json:"missingskills"`
+	TotalTimeEstimationInHours float64        `json:"totaltimeestimationinhours"`
+}
+
+// getSkillGap returns the skills of a track which a user has not completed, in the order of the milestones.
+// The gap is seen by the user, their managers and the skill plan managers.
+// args[0] is user id, args[1] is track id
+func getSkillGap(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 2")
+	}
+
+	if err := checkUserAccess(APIstub, args[0], "0"); err != nil {
+		return errs.Response(err)
+	}
+
+	gap, err := buildSkillGap(APIstub, args[0], args[1])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the skill gap of user " + args[0]))
+	}
+
+	data, _ := json.Marshal(gap)
+
+	return shim.Success(data)
+}
+
+// buildSkillGap combines the milestones of the track, the dependencies of their skills and the completions of the user
+func buildSkillGap(APIstub shim.ChaincodeStubInterface, userID string, trackID string) (SkillGap, error) {
+	gap := SkillGap{UserID: userID, TrackID: trackID, MissingSkills: []MissingSkill{}}
+
+	milestones, err := getTrackMilestones(APIstub, trackID)
+	if err != nil {
+		return gap, err
+	}
+
+	trackSkills := []MissingSkill{}
+	skillIDs := []string{}
+	for _, milestone := range milestones {
+		for _, skillID := range milestone.SkillIDs {
+			trackSkills = append(trackSkills, MissingSkill{SkillID: skillID, MilestoneID: milestone.MilestoneID, MilestoneOrder: milestone.Order})
+			skillIDs = append(skillIDs, skillID)
+		}
+	}
+
+	if len(skillIDs) == 0 {
+		return gap, nil
+	}
+
+	graph, err := getPrerequisites(APIstub, skillIDs)
+	if err != nil {
+		return gap, err
+	}
+
+	hours := map[string]float64{}
+	for _, skill := range graph.Skills {
+		hours[skill.SkillID], err = parseHours(skill)
+		if err != nil {
+			return gap, err
+		}
+	}
+
+	dependsOn := map[string][]string{}
+	for _, dependency := range graph.Dependencies {
+		dependsOn[dependency.SkillID] = append(dependsOn[dependency.SkillID], dependency.DependingOnSkill)
+	}
+
+	completed, err := getCompletedSkillIDs(APIstub, userID)
+	if err != nil {
+		return gap, err
+	}
+
+	counted := map[string]bool{}
+	for _, missing := range trackSkills {
+		if completed[missing.SkillID] {
+			continue
+		}
+
+		prerequisites, err := getMissingPrerequisites(missing.SkillID, dependsOn, completed)
+		if err != nil {
+			return gap, err
+		}
+
+		missing.TimeEstimationInHours = hours[missing.SkillID]
+		missing.TotalTimeEstimationInHours = missing.TimeEstimationInHours
+		missing.Prerequisites = []GapSkill{}
+		for _, prerequisite := range prerequisites {
+			missing.Prerequisites = append(missing.Prerequisites, GapSkill{SkillID: prerequisite, TimeEstimationInHours: hours[prerequisite]})
+			missing.TotalTimeEstimationInHours += hours[prerequisite]
+		}
+
+		for _, skillID := range append(append([]string{}, prerequisites...), missing.SkillID) {
+			if !counted[skillID] {
+				counted[skillID] = true
+				gap.TotalTimeEstimationInHours += hours[skillID]
+			}
+		}
+
+		gap.MissingSkills = append(gap.MissingSkills, missing)
+	}
+
+	return gap, nil
+}
+
+// getMissingPrerequisites returns the skills a skill depends on, directly or not, which are not completed.
+// A completed skill stands for its own prerequisites.
+func getMissingPrerequisites(skillID string, dependsOn map[string][]string, completed map[string]bool) ([]string, error) {
+	found := map[string]bool{skillID: true}
+	prerequisites := []string{}
+
+	pending := []string{skillID}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		for _, dependency := range dependsOn[current] {
+			if found[dependency] || completed[dependency] {
+				continue
+			}
+
+			found[dependency] = true
+			prerequisites = append(prerequisites, dependency)
+			pending = append(pending, dependency)
+		}
+	}
+
+	// The skill itself is sorted with its prerequisites to detect the cycles through it
+	sorted, err := utils.SortByDependencies(append(append([]string{}, prerequisites...), skillID), dependsOn)
+	if err != nil {
+		return nil, err
+	}
+
+	return sorted[:len(sorted)-1], nil
+}
+
+func getTrackMilestones(APIstub shim.ChaincodeStubInterface, trackID string) ([]models.TrackMilestone, error) {
+	milestones := []models.TrackMilestone{}
+
+	response := core.InvokeChaincode(APIstub, "milestone", "GetTrackMilestones", trackID)
+	if response.Status != shim.OK {
+		return nil, errs.Wrapf(errs.FromResponse(response), "Failed to get the milestones of track %s", trackID)
+	}
+
+	err := json.Unmarshal(response.Payload, &milestones)
+
+	return milestones, err
+}
+
+func getPrerequisites(APIstub shim.ChaincodeStubInterface, skillIDs []string) (models.SkillGraph, error) {
+	graph := models.SkillGraph{}
+
+	response := core.InvokeChaincode(APIstub, "skill", "getPrerequisites", strings.Join(skillIDs, ","))
+	if response.Status != shim.OK {
+		return graph, errs.Wrap(errs.FromResponse(response), "Failed to get the prerequisites of skills " + strings.Join(skillIDs, ","))
+	}
+
+	err := json.Unmarshal(response.Payload, &graph)
+
+	return graph, err
+}
+
+// getCompletedSkillIDs returns the skills the user has completed
+func getCompletedSkillIDs(APIstub shim.ChaincodeStubInterface, userID string) (map[string]bool, error) {
+	var query = `{"selector":{"` + models.DocTypeColumnName + `":"` + models.CompletedSkillDocType + `","` + models.SkillPlanUserIDColumnName + `":"` + userID + `"}}`
+
+	data, err := repository.InitRepo(models.CompletedSkillDocType).GetByQuery(APIstub, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var skills []SkillPlanCompletedSkill
+	json.Unmarshal(data, &skills)
+
+	completed := map[string]bool{}
+	for _, skill := range skills {
+		completed[skill.SkillID] = true
+	}
+
+	return completed, nil
+}
+
+func parseHours(skill models.Skill) (float64, error) {
+	if skill.TimeEstimationInHours == "" {
+		return 0, nil
+	}
+
+	hours, err := strconv.ParseFloat(skill.TimeEstimationInHours, 64)
+	if err != nil {
+		return 0, errs.Errorf(errs.InvalidArgument, "Invalid time estimation %s of skill %s", skill.TimeEstimationInHours, skill.SkillID)
+	}
+
+	return hours, nil
+}
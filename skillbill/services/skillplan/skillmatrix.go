@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+)
+
+// AwaitingAssessment is the status of a skill with an assessment request
+const AwaitingAssessment string = "awaitingassessment"
+
+// Formats of the skill matrix
+const (
+	MatrixJSONFormat string = "json"
+	MatrixCSVFormat  string = "csv"
+)
+
+// matrixStatusRanks orders the statuses of a cell, the furthest one is shown
+var matrixStatusRanks = map[string]int{Planned: 1, InProgress: 2, AwaitingAssessment: 3, Completed: 4}
+
+// SkillMatrixCell is the furthest status of a user for a skill and the date of the status, the status is empty without record
+type SkillMatrixCell struct {
+	SkillID string `json:"skillid"`
+	Status  string `json:"status"`
+	Date    string `json:"date"`
+}
+
+// SkillMatrixRow is a user with a cell for every skill of the matrix
+type SkillMatrixRow struct {
+	UserID string            `json:"userid"`
+	Cells  []SkillMatrixCell `json:"cells"`
+}
+
+// SkillMatrix is the progress of a team on a set of skills
+type SkillMatrix struct {
+	SkillIDs []string         `json:"skillids"`
+	Rows     []SkillMatrixRow `json:"rows"`
+}
+
+// getSkillMatrix returns the status of every user of a team for every skill of a track or knowledge group.
+// The rows are the users of an org unit (with the units below it) or the members of a knowledge group,
+// every user must be accessible by the caller. The date of a planned skill is its planned end,
+// of a skill in progress its start, of an assessment request its request and of a completed skill its completion.
+// args[0] is "orgunit" or "knowledgegroup", args[1] is its id, args[2] is "track" or "knowledgegroup", args[3] is its id,
+// args[4] is the optional format "json" (default) or "csv"
+func getSkillMatrix(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 && len(args) != 5 {
+		return errs.Fail(errs.InvalidArgument, "Incorrect number of arguments. Expecting 4 or 5")
+	}
+
+	format := MatrixJSONFormat
+	if len(args) == 5 && args[4] != "" {
+		format = strings.ToLower(args[4])
+	}
+
+	if format != MatrixJSONFormat && format != MatrixCSVFormat {
+		return errs.Fail(errs.InvalidArgument, "Invalid format " + args[4] + ", expecting " + MatrixJSONFormat + " or " + MatrixCSVFormat)
+	}
+
+	userIDs, err := getMatrixUsers(APIstub, strings.ToLower(args[0]), args[1])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the skill matrix"))
+	}
+
+	for _, userID := range userIDs {
+		if err := checkUserAccess(APIstub, userID, "0"); err != nil {
+			return errs.Response(err)
+		}
+	}
+
+	skillIDs, err := getMatrixSkills(APIstub, strings.ToLower(args[2]), args[3])
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the skill matrix"))
+	}
+
+	matrix, err := buildSkillMatrix(APIstub, userIDs, skillIDs)
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to get the skill matrix"))
+	}
+
+	if format == MatrixCSVFormat {
+		return shim.Success(matrix.toCSV())
+	}
+
+	data, _ := json.Marshal(matrix)
+
+	return shim.Success(data)
+}
+
+// buildSkillMatrix reads the planned, in progress, requested and completed skills of the users with one query each
+func buildSkillMatrix(APIstub shim.ChaincodeStubInterface, userIDs []string, skillIDs []string) (SkillMatrix, error) {
+	matrix := SkillMatrix{SkillIDs: skillIDs, Rows: []SkillMatrixRow{}}
+	cells := map[string]map[string]SkillMatrixCell{}
+
+	setCell := func(userID string, skillID string, status string, date string) {
+		if cells[userID] == nil {
+			cells[userID] = map[string]SkillMatrixCell{}
+		}
+
+		cell := cells[userID][skillID]
+		if matrixStatusRanks[status] > matrixStatusRanks[cell.Status] || (status == cell.Status && date > cell.Date) {
+			cells[userID][skillID] = SkillMatrixCell{SkillID: skillID, Status: status, Date: date}
+		}
+	}
+
+	if len(userIDs) > 0 && len(skillIDs) > 0 {
+		var planned []SkillPlanPlannedSkill
+		if err := queryMatrixRecords(APIstub, models.PlannedSkillDocType, models.SkillPlanUserIDColumnName, userIDs, skillIDs, &planned); err != nil {
+			return matrix, err
+		}
+
+		for _, skill := range planned {
+			setCell(skill.UserID, skill.SkillID, Planned, skill.PlannedTo)
+		}
+
+		var inProgress []SkillPlanInProgressSkill
+		if err := queryMatrixRecords(APIstub, models.InProgressSkillDocType, models.SkillPlanUserIDColumnName, userIDs, skillIDs, &inProgress); err != nil {
+			return matrix, err
+		}
+
+		for _, skill := range inProgress {
+			setCell(skill.UserID, skill.SkillID, InProgress, skill.SkillACStartdate)
+		}
+
+		var requests []SkillPlanAssessmentRequest
+		if err := queryMatrixRecords(APIstub, models.AssessmentRequestDocType, models.SkillPlanAssesseeIDColumnName, userIDs, skillIDs, &requests); err != nil {
+			return matrix, err
+		}
+
+		for _, request := range requests {
+			setCell(request.AssesseeID, request.SkillID, AwaitingAssessment, toPlanDate(request.RequestedOn))
+		}
+
+		var completed []SkillPlanCompletedSkill
+		if err := queryMatrixRecords(APIstub, models.CompletedSkillDocType, models.SkillPlanUserIDColumnName, userIDs, skillIDs, &completed); err != nil {
+			return matrix, err
+		}
+
+		for _, skill := range completed {
+			setCell(skill.UserID, skill.SkillID, Completed, toPlanDate(skill.CompletedOn))
+		}
+	}
+
+	for _, userID := range userIDs {
+		row := SkillMatrixRow{UserID: userID, Cells: []SkillMatrixCell{}}
+		for _, skillID := range skillIDs {
+			cell, ok := cells[userID][skillID]
+			if !ok {
+				cell = SkillMatrixCell{SkillID: skillID}
+			}
+
+			row.Cells = append(row.Cells, cell)
+		}
+
+		matrix.Rows = append(matrix.Rows, row)
+	}
+
+	return matrix, nil
+}
+
+// toCSV renders a row per user and a column per skill, a cell is its status followed by its date
+func (m SkillMatrix) toCSV() []byte {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	writer.Write(append([]string{models.SkillPlanUserIDColumnName}, m.SkillIDs...))
+
+	for _, row := range m.Rows {
+		record := []string{row.UserID}
+		for _, cell := range row.Cells {
+			record = append(record, strings.TrimSpace(cell.Status+" "+cell.Date))
+		}
+
+		writer.Write(record)
+	}
+
+	writer.Flush()
+
+	return buffer.Bytes()
+}
+
+func queryMatrixRecords(APIstub shim.ChaincodeStubInterface, docType string, userColumn string, userIDs []string, skillIDs []string, records interface{}) error {
+	selector := map[string]interface{}{
+		models.DocTypeColumnName:          docType,
+		userColumn:                        map[string]interface{}{"$in": userIDs},
+		models.SkillPlanSkillIDColumnName: map[string]interface{}{"$in": skillIDs}}
+	query, _ := json.Marshal(map[string]interface{}{"selector": selector})
+
+	data, err := repository.InitRepo(docType).GetByQuery(APIstub, string(query))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, records)
+}
+
+// getMatrixUsers returns the sorted users of an org unit or the members of a knowledge group
+func getMatrixUsers(APIstub shim.ChaincodeStubInterface, source string, id string) ([]string, error) {
+	userIDs := []string{}
+	found := map[string]bool{}
+
+	addUser := func(userID string) {
+		if !found[userID] {
+			found[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	switch source {
+	case models.OrgUnitDocType:
+		response := core.InvokeChaincode(APIstub, "security", "GetOrgUnitUsers", id)
+		if response.Status != shim.OK {
+			return nil, errs.Wrapf(errs.FromResponse(response), "Failed to get the users of org unit %s", id)
+		}
+
+		var users []models.User
+		if err := json.Unmarshal(response.Payload, &users); err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			addUser(user.ADLogin)
+		}
+
+	case models.KnowledgeGroupDocType:
+		response := core.InvokeChaincode(APIstub, "knowledgegroup", "GetMemberByGroupID", id)
+		if response.Status != shim.OK {
+			return nil, errs.Wrapf(errs.FromResponse(response), "Failed to get the members of knowledge group %s", id)
+		}
+
+		var members []models.KnowledgeGroupMember
+		if err := json.Unmarshal(response.Payload, &members); err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			addUser(member.UserID)
+		}
+
+	default:
+		return nil, errs.Errorf(errs.InvalidArgument, "Invalid rows %s, expecting %s or %s", source, models.OrgUnitDocType, models.KnowledgeGroupDocType)
+	}
+
+	sort.Strings(userIDs)
+
+	return userIDs, nil
+}
+
+// getMatrixSkills returns the skills of a track in the order of its milestones, or the sorted skills of a knowledge group
+func getMatrixSkills(APIstub shim.ChaincodeStubInterface, source string, id string) ([]string, error) {
+	skillIDs := []string{}
+
+	switch source {
+	case models.TrackDocType:
+		milestones, err := getTrackMilestones(APIstub, id)
+		if err != nil {
+			return nil, err
+		}
+
+		found := map[string]bool{}
+		for _, milestone := range milestones {
+			for _, skillID := range milestone.SkillIDs {
+				if !found[skillID] {
+					found[skillID] = true
+					skillIDs = append(skillIDs, skillID)
+				}
+			}
+		}
+
+	case models.KnowledgeGroupDocType:
+		response := core.InvokeChaincode(APIstub, "skill", "GetSkillsByKnowledgeGroup", id)
+		if response.Status != shim.OK {
+			return nil, errs.Wrapf(errs.FromResponse(response), "Failed to get the skills of knowledge group %s", id)
+		}
+
+		var skills []models.Skill
+		if err := json.Unmarshal(response.Payload, &skills); err != nil {
+			return nil, err
+		}
+
+		for _, skill := range skills {
+			skillIDs = append(skillIDs, skill.SkillID)
+		}
+		sort.Strings(skillIDs)
+
+	default:
+		return nil, errs.Errorf(errs.InvalidArgument, "Invalid columns %s, expecting %s or %s", source, models.TrackDocType, models.KnowledgeGroupDocType)
+	}
+
+	return skillIDs, nil
+}
+
+// toPlanDate returns the day of a transaction time, the records written before the time was kept have none
+func toPlanDate(txTime string) string {
+	date, err := time.Parse(time.RFC3339, txTime)
+	if err != nil {
+		return txTime
+	}
+
+	return date.Format(PlanDateLayout)
+}
@@ -1,45 +1,75 @@
-package main
-
-
-const (
-	Planned 			string = "planned"
-	InProgress			string = "inprogress"
-	Completed			string = "completed"
-	AssessmentRequest	string = "assessmentrequest"
-)
-
-type SkillPlanPlannedSkill struct {
-	ID				string	`json:"id"`
-	PlannedFrom		string	`json:"plannedfrom"`
-	PlannedTo		string	`json:"plannedto"`
-	Priority		string	`json:"priority"`
-	SkillID			string	`json:"skillid"`
-	UserID			string	`json:"userid"`
-	DocType			string	`json:"doctype"`
-}
-
-type SkillPlanInProgressSkill struct {
-	ID					string	`json:"id"`
-	SkillACID			string	`json:"skillacid"`
-	SkillACStartdate	string	`json:"skillacstartdate"`
-	SkillID				string	`json:"skillid"`
-	UserID				string	`json:"userid"`
-	DocType				string	`json:"doctype"`
-}
-
-type SkillPlanCompletedSkill struct {
-	ID				string	`json:"id"`
-	AccessedBy		string	`json:"assessedby"`
-	CompletedOn		string 	`json:"completedon"`
-	SkillID			string	`json:"skillid"`
-	UserID			string	`json:"userid"`
-	DocType			string	`json:"doctype"`
-}
-
-type SkillPlanAssessmentRequest struct {
-	ID				string	`json:"id"`
-	AssesseeID		string	`json:"assesseeid"`
-	AssessorID		string	`json:"assessorid"`
-	SkillID			string	`json:"skillid"`
-	DocType			string	`json:"doctype"`
-}
+package main
+
+
+const (
+	Planned 			string = "planned"
+	InProgress			string = "inprogress"
+	Completed			string = "completed"
+	AssessmentRequest	string = "assessmentrequest"
+)
+
+type SkillPlanPlannedSkill struct {
+	ID				string	`json:"id"`
+	PlannedFrom		string	`json:"plannedfrom"`
+	PlannedTo		string	`json:"plannedto"`
+	Priority		string	`json:"priority"`
+	SkillID			string	`json:"skillid"`
+	UserID			string	`json:"userid"`
+	DocType			string	`json:"doctype"`
+}
+
+type SkillPlanInProgressSkill struct {
+	ID					string	`json:"id"`
+	SkillACID			string	`json:"skillacid"`
+	SkillACStartdate	string	`json:"skillacstartdate"`
+	SkillID				string	`json:"skillid"`
+	UserID				string	`json:"userid"`
+	DocType				string	`json:"doctype"`
+}
+
+const (
+	AssessmentResultCollection	string = "collectionAssessmentResults"
+	AssessmentTransientKey		string = "assessment"
+	AssessmentSaltTransientKey	string = "salt"
+	// AssessmentSaltMinLength is the least number of random bytes the client salts the assessment hash with
+	AssessmentSaltMinLength		int = 16
+)
+
+// SkillPlanCompletedSkill is public, the assessment result is kept in a private collection and only its hash is stored here.
+// CompletedOn is the time of the transaction which completed the skill.
+// SkillVersion and SkillLevel are the skill as it was assessed, the assessor signs them with the record.
+type SkillPlanCompletedSkill struct {
+	ID					string	`json:"id"`
+	SkillID				string	`json:"skillid"`
+	SkillVersion		string	`json:"skillversion"`
+	SkillLevel			string	`json:"skilllevel"`
+	UserID				string	`json:"userid"`
+	TxID				string	`json:"txid"`
+	AssessorCertificate	string	`json:"assessorcertificate"`
+	AssessmentHash		string	`json:"assessmenthash"`
+	CompletedOn			string	`json:"completedon"`
+	AssessorSignature	string	`json:"assessorsignature"`
+	DocType				string	`json:"doctype"`
+}
+
+// SkillPlanAssessmentResult is stored in the AssessmentResultCollection with the key of the completed skill
+// Salt is the hex of the random bytes the client passed, so the public hash of a result can not be guessed
+type SkillPlanAssessmentResult struct {
+	ID				string	`json:"id"`
+	AccessedBy		string	`json:"assessedby"`
+	CompletedOn		string 	`json:"completedon"`
+	Outcome			string	`json:"outcome"`
+	Comment			string	`json:"comment"`
+	Salt			string	`json:"salt"`
+	DocType			string	`json:"doctype"`
+}
+
+// SkillPlanAssessmentRequest is a skill awaiting the assessment, RequestedOn is the time of the transaction which requested it
+type SkillPlanAssessmentRequest struct {
+	ID				string	`json:"id"`
+	AssesseeID		string	`json:"assesseeid"`
+	AssessorID		string	`json:"assessorid"`
+	SkillID			string	`json:"skillid"`
+	RequestedOn		string	`json:"requestedon"`
+	DocType			string	`json:"doctype"`
+}
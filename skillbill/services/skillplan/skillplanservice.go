@@ -1,304 +1,521 @@
-package main
-
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"strings"
-
-	"github.com/beevik/guid"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-
-	log "github.com/sirupsen/logrus"
-)
-
-type SkillPlanChaincode struct {
-
-}
-
-// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
-func (s *SkillPlanChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
-	setUpLogging(filepath)
-
-	return shim.Success(nil)
-}
-
-// Invoke method is called as a result of an application request to run the Smart Contract "skill plan"
-func (s *SkillPlanChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	// Retrieve the requested Smart Contract function and arguments
-	function, args := APIstub.GetFunctionAndParameters()
-
-	log.Info("Function is calling: " , function)
-	// Route to the appropriate handler function to interact with the ledger appropriately
-	if function == "getAllByQuery" {
-		return getAllByQuery(APIstub, args)
-	} else if function == "createSkillPlan" {
-		return createSkillPlan(APIstub, args)
-	} else if function == "deleteSkillPlan" {
-		return deleteSkillPlan(APIstub, args)
-	} else if function == "updatePlannedSkill" {
-		return updatePlannedSkill(APIstub, args)
-	} else if function == "updateCompletedSkill" {
-		return updateCompletedSkill(APIstub, args)
-	} else if function == "updateAssessmentRequest" {
-		return updateAssessmentRequest(APIstub, args)
-	}
-
-	return shim.Error("Invalid Smart Contract function name.")
-}
-
-func createSkillPlan(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	// Build skill plan object base on type
-	id, data, errMsg := buildSkillPlanObject(args)
-
-	if errMsg != "" {
-		log.Info(errMsg)
-
-		return shim.Error(errMsg)
-	}
-
-	log.Info("Skill Plan Id: %s", id)
-	APIstub.PutState(id, data)
-
-	return shim.Success([]byte(id))
-}
-
-func deleteSkillPlan(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Skill id are empty. Please specify the skill id to delete.")
-	}
-
-	var id = args[0]
-	data, e := APIstub.GetState(id)
-
-	if e != nil{
-		return shim.Error("Failed to delete skill plan" + id + " due to " + e.Error() + "\n")
-	}
-
-	if len(string(data)) == 0 {
-		return shim.Error("Failed to delete skill plan, because the skill " + id + " does not exist.")
-	}
-	
-	err := APIstub.DelState(id)
-
-	if err != nil{
-		return shim.Error("Failed to delete skill plan " + id + " due to " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
-
-func updatePlannedSkill(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 6 {
-		return shim.Error("Incorrect number of arguments. Expecting 6")
-	}
-
-	data, err := APIstub.GetState(args[0])
-
-	if err != nil{
-		return shim.Error("Failed to update planned skill " + args[0] + ": " + err.Error())
-	}
-
-	if len(string(data)) == 0 {
-		return shim.Error("Failed to update planned skill, because the skill does not exist.")
-	}
-
-	plannedskill := SkillPlanPlannedSkill{}
-	json.Unmarshal(data, &plannedskill)
-
-	plannedskill.PlannedFrom = args[1]
-	plannedskill.PlannedTo = args[2]
-	plannedskill.Priority = args[3]
-	plannedskill.SkillID = args[4]
-	plannedskill.UserID = args[5]
-
-	data, _ = json.Marshal(plannedskill)
-
-	APIstub.PutState(args[0], data)
-
-	return shim.Success(nil)
-}
-
-func updateCompletedSkill(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 5 {
-		return shim.Error("Incorrect number of arguments. Expecting 5")
-	}
-
-	data, err := APIstub.GetState(args[0])
-
-	if err != nil{
-		return shim.Error("Failed to update completed skill " + args[0] + ": " + err.Error())
-	}
-
-	if len(string(data)) == 0 {
-		return shim.Error("Failed to update completed skill, because the skill does not exist.")
-	}
-
-	skill := SkillPlanCompletedSkill{}
-	json.Unmarshal(data, &skill)
-	skill.AccessedBy = args[1]
-	skill.CompletedOn = args[2]
-	skill.SkillID = args[3]
-	skill.UserID = args[4]
-
-	data, _ = json.Marshal(skill)
-
-	APIstub.PutState(args[0], data)
-
-	return shim.Success(nil)
-}
-
-func updateAssessmentRequest(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 4 {
-		return shim.Error("Incorrect number of arguments. Expecting 4")
-	}
-
-	data, err := APIstub.GetState(args[0])
-
-	if err != nil{
-		return shim.Error("Failed to update completed skill " + args[0] + ": " + err.Error())
-	}
-
-	if len(string(data)) == 0 {
-		return shim.Error("Failed to update planned skill, because the skill does not exist.")
-	}
-
-	skill := SkillPlanAssessmentRequest{}
-	json.Unmarshal(data, &skill)
-	skill.AssesseeID = args[1]
-	skill.AssessorID = args[2]
-	skill.SkillID = args[3]
-
-	data, _ = json.Marshal(skill)
-
-	APIstub.PutState(args[0], data)
-
-	return shim.Success(nil)
-}
-
-// args[0].. args[n] are pair column and value
-// e.g: args['doctype,milestone', 'milestoneid,E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', ....]
-func getAllByQuery(APIstub shim.ChaincodeStubInterface, args[] string) sc.Response {
-	
-	var b bytes.Buffer
-	i := 0
-	for i < len(args) {
-		var params = strings.Split(args[i], ",")
-		b.WriteString("\""+ params[0] +"\":\""+ params[1] +"\"")
-		i = i + 1
-		if i != len(args){
-			b.WriteString(",");
-		}
-	}
-
-	var query = `{"selector":{`+ b.String() +`}}`
-
-	resultsIterator, err := APIstub.GetQueryResult(query)
-
-	if err != nil {
-		return shim.Error(err.Error() + "query: \n"+ query)
-	}
-	
-	defer resultsIterator.Close()
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
-
-	bArrayMemberAlreadyWritten := false
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		
-		if err != nil {
-			return shim.Error(err.Error())
-		}
-
-		if bArrayMemberAlreadyWritten == true {
-			buffer.WriteString(",")
-		}
-
-		buffer.WriteString(string(queryResponse.Value))
-		bArrayMemberAlreadyWritten = true
-	}
-	buffer.WriteString("]")
-
-	fmt.Printf("Data: \n%s\n", buffer.String())
-
-	return shim.Success(buffer.Bytes())
-}
-
-func buildSkillPlanObject(args []string) (string, []byte, string) {
-	objType := strings.ToLower(args[0])
-
-	log.Info("Action Type: ", objType)
-	
-	switch objType {
-	case Planned:
-		var skill = SkillPlanPlannedSkill { 
-			ID: guid.New().StringUpper(), 
-			PlannedFrom: args[1], 
-			PlannedTo: args[2], 
-			Priority: args[3], 
-			SkillID: args[4], 
-			UserID: args[5], 
-			DocType: "plannedskill"}
-	
-		data, _ := json.Marshal(skill)
-
-		return skill.ID, data, ""
-	
-	case InProgress:
-		var skill = SkillPlanInProgressSkill { 
-			ID: guid.New().StringUpper(), 
-			SkillACID: args[1], 
-			SkillACStartdate: args[2],
-			SkillID: args[3], 
-			UserID: args[4], 
-			DocType: "inprogressskill"}
-	
-		data, _ := json.Marshal(skill)
-
-		return skill.ID, data, ""
-
-	case Completed:
-		var skill = SkillPlanCompletedSkill { 
-			ID: guid.New().StringUpper(), 
-			AccessedBy: args[1], 
-			CompletedOn: args[2],
-			SkillID: args[3], 
-			UserID: args[4], 
-			DocType: "completedskill"}
-	
-		data, _ := json.Marshal(skill)
-
-		return skill.ID, data, ""
-
-	case AssessmentRequest:
-		var skill = SkillPlanAssessmentRequest { 
-			ID: guid.New().StringUpper(), 
-			AssesseeID: args[1], 
-			AssessorID: args[2],
-			SkillID: args[3], 
-			DocType: "assessmentrequest"}
-	
-		data, _ := json.Marshal(skill)
-
-		return skill.ID, data, ""
-
-	default:
-			return "Invalid skill plan type: " + objType, nil, ""
-	}
-}
-
-func main() {
-	err := shim.Start(new(SkillPlanChaincode))
-	if err != nil {
-		fmt.Printf("Error creating new skill plan chaincode: %s", err)
-	}
-}
+package main
+
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/repository"
+	"github.com/skillbill/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type SkillPlanChaincode struct {
+
+}
+
+// integrity of the skill plans, they are referenced by no record but point to the skills of the skill chaincode
+var integrity = core.Integrity{Inbound: []core.Inbound{
+	{DocType: models.PlannedSkillDocType, Column: models.SkillPlanSkillIDColumnName, FeatureID: core.SkillManagementFeatureID},
+}}
+
+// schemas of the arguments of the functions, they are checked before the functions are called
+var schemas = core.Schemas{
+	"getAllByQuery":   {{Name: "filter", Type: core.ArgPair, Repeated: true}},
+	"deleteSkillPlan": {{Name: "id", Required: true}, {Name: "delete mode"}},
+	"updatePlannedSkill": {{Name: "id", Required: true}, {Name: "planned from", Type: core.ArgDate, Required: true}, {Name: "planned to", Type: core.ArgDate, Required: true},
+		{Name: "priority", Type: core.ArgInt, Required: true}, {Name: "skill id", Required: true}, {Name: "user id", Required: true}},
+	"updateCompletedSkill":    {{Name: "id", Required: true}, {Name: "skill id", Required: true}, {Name: "user id", Required: true}},
+	"signCompletedSkill":      {{Name: "id", Required: true}, {Name: "signature", Required: true}},
+	"updateAssessmentRequest": {{Name: "id", Required: true}, {Name: "assessee id", Required: true}, {Name: "assessor id", Required: true}, {Name: "skill id", Required: true}},
+}
+
+// createSchemas of the arguments of createSkillPlan by skill plan type, args[0] is the type
+var createSchemas = core.Schemas{
+	Planned: {{Name: "type", Required: true}, {Name: "planned from", Type: core.ArgDate, Required: true}, {Name: "planned to", Type: core.ArgDate, Required: true},
+		{Name: "priority", Type: core.ArgInt, Required: true}, {Name: "skill id", Required: true}, {Name: "user id", Required: true}},
+	InProgress: {{Name: "type", Required: true}, {Name: "skill acceptance criteria id", Required: true}, {Name: "start date", Type: core.ArgDate, Required: true},
+		{Name: "skill id", Required: true}, {Name: "user id", Required: true}},
+	Completed:         {{Name: "type", Required: true}, {Name: "skill id", Required: true}, {Name: "user id", Required: true}},
+	AssessmentRequest: {{Name: "type", Required: true}, {Name: "assessee id", Required: true}, {Name: "assessor id", Required: true}, {Name: "skill id", Required: true}},
+}
+
+// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
+func (s *SkillPlanChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
+	setUpLogging(filepath)
+	assessmentResultRepo = repository.InitPrivateRepo(AssessmentResultCollection)
+
+	if err := core.InitAttributeMSPIDs(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to configure the MSPs trusted to issue the certificate attributes"))
+	}
+
+	return shim.Success(nil)
+}
+
+// Invoke method is called as a result of an application request to run the Smart Contract "skill plan"
+func (s *SkillPlanChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	// Retrieve the requested Smart Contract function and arguments
+	function, args := APIstub.GetFunctionAndParameters()
+
+	log.Info("Function is calling: " , function)
+
+	// Check the arguments against the schema of the function, so the handlers can index them
+	if err := schemas.Validate(function, args); err != nil {
+		return errs.Response(err)
+	}
+
+	// Route to the appropriate handler function to interact with the ledger appropriately
+	if function == "getAllByQuery" {
+		return getAllByQuery(APIstub, args)
+	} else if function == "createSkillPlan" {
+		return createSkillPlan(APIstub, args)
+	} else if function == "deleteSkillPlan" {
+		return deleteSkillPlan(APIstub, args)
+	} else if function == "updatePlannedSkill" {
+		return updatePlannedSkill(APIstub, args)
+	} else if function == "updateCompletedSkill" {
+		return updateCompletedSkill(APIstub, args)
+	} else if function == "updateAssessmentRequest" {
+		return updateAssessmentRequest(APIstub, args)
+	} else if function == "getSkillGap" {
+		return getSkillGap(APIstub, args)
+	} else if function == "getSkillMatrix" {
+		return getSkillMatrix(APIstub, args)
+	} else if function == "GeneratePlan" {
+		return generatePlan(APIstub, args)
+	} else if function == "getOpenBadgeAssertion" {
+		return getOpenBadgeAssertion(APIstub, args)
+	} else if function == "signCompletedSkill" {
+		return signCompletedSkill(APIstub, args)
+	} else if function == "getVerifiableCredential" {
+		return getVerifiableCredential(APIstub, args)
+	} else if function == "exportLedgerProof" {
+		return exportLedgerProof(APIstub, args)
+	} else if function == "getAssessmentResult" {
+		return getAssessmentResult(APIstub, args)
+	} else if function == "verifyAssessmentResult" {
+		return verifyAssessmentResult(APIstub, args)
+	} else if function == "GetReferences" {
+		return integrity.GetReferences(APIstub, args)
+	} else if function == "DeleteReferences" {
+		return integrity.DeleteReferences(APIstub, args)
+	} else if function == "Migrate" {
+		return core.CreateBase().Migrate(APIstub, args)
+	} else if function == "GetMigrations" {
+		return core.CreateBase().GetMigrations(APIstub)
+	} else if function == "Restore" {
+		return core.CreateBase().Restore(APIstub, args, canDeletePlannedSkill)
+	} else if function == "ListArchived" {
+		return core.CreateBase().ListArchived(APIstub, args, canDeletePlannedSkill, models.PlannedSkillDocType)
+	} else if function == "Purge" {
+		return core.CreateBase().Purge(APIstub, args)
+	}
+
+	return errs.Fail(errs.InvalidArgument, "Invalid Smart Contract function name.")
+}
+
+func createSkillPlan(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	// Build skill plan object base on type
+	id, data, err := buildSkillPlanObject(APIstub, args)
+
+	if err != nil {
+		log.Info(err.Error())
+
+		return errs.Response(err)
+	}
+
+	log.Infof("Skill Plan Id: %s", id)
+	err = repository.CreateDocument(APIstub, id, data)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create skill plan " + id))
+	}
+
+	return shim.Success([]byte(id))
+}
+
+func deleteSkillPlan(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var id = args[0]
+
+	mode, err := core.GetDeleteMode(args, 1)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = canDeletePlannedSkill(APIstub, id)
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to delete skill plan " + id))
+	}
+
+	err = integrity.Delete(APIstub, id, mode)
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to delete skill plan " + id))
+	}
+
+	return shim.Success(nil)
+}
+
+// canDeletePlannedSkill checks the caller can plan the skills of the user of a planned skill,
+// to delete, restore or list it among the archived records
+func canDeletePlannedSkill(APIstub shim.ChaincodeStubInterface, id string) error {
+
+	data, err := repository.GetDocument(APIstub, id)
+
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return errs.Errorf(errs.NotFound, "The skill plan %s does not exist.", id)
+	}
+
+	plannedskill := SkillPlanPlannedSkill{}
+	json.Unmarshal(data, &plannedskill)
+
+	return checkUserAccess(APIstub, plannedskill.UserID, "1")
+}
+
+func updatePlannedSkill(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if err := checkPlannedDates(args[1], args[2]); err != nil {
+		return errs.Response(err)
+	}
+
+	data, err := repository.GetDocument(APIstub, args[0])
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to update planned skill " + args[0]))
+	}
+
+	if len(string(data)) == 0 {
+		return errs.Fail(errs.NotFound, "Failed to update planned skill, because the skill does not exist.")
+	}
+
+	plannedskill := SkillPlanPlannedSkill{}
+	json.Unmarshal(data, &plannedskill)
+
+	// The plan is moved from its user to the new one, both must be planned by the caller
+	for _, userID := range []string{plannedskill.UserID, args[5]} {
+		if err := checkUserAccess(APIstub, userID, "1"); err != nil {
+			return errs.Response(errs.Wrap(err, "Failed to update planned skill " + args[0]))
+		}
+	}
+
+	plannedskill.PlannedFrom = args[1]
+	plannedskill.PlannedTo = args[2]
+	plannedskill.Priority = args[3]
+	plannedskill.SkillID = args[4]
+	plannedskill.UserID = args[5]
+
+	data, _ = json.Marshal(plannedskill)
+
+	repository.PutDocument(APIstub, args[0], data)
+
+	return shim.Success(nil)
+}
+
+// args[0] is completed skill id, args[1] is skill id, args[2] is user id, the record must be the completion of the skill by the user
+// the assessment result is passed in the transient field "assessment" with its salt in "salt", the caller must assess the knowledge group of the skill
+func updateCompletedSkill(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	data, err := repository.GetDocument(APIstub, args[0])
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to update completed skill " + args[0]))
+	}
+
+	if len(string(data)) == 0 {
+		return errs.Fail(errs.NotFound, "Failed to update completed skill, because the skill does not exist.")
+	}
+
+	skill := SkillPlanCompletedSkill{}
+	json.Unmarshal(data, &skill)
+
+	// The record is reassessed, it can not be turned into the completion of another skill or user
+	if skill.DocType != models.CompletedSkillDocType || skill.SkillID != args[1] || skill.UserID != args[2] {
+		return errs.Fail(errs.InvalidArgument, "Failed to update completed skill, " + args[0] + " is not the completed skill " + args[1] + " of user " + args[2])
+	}
+
+	assessed, err := checkAssessor(APIstub, args[1])
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	certificate, err := utils.GetCreatorCertPEM(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Could not get Certificate"))
+	}
+
+	result, err := getTransientAssessmentResult(APIstub, args[0])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update completed skill " + args[0]))
+	}
+
+	hash, err := saveAssessmentResult(APIstub, result)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update completed skill " + args[0]))
+	}
+
+	completedOn, err := utils.GetTxTime(APIstub)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to update completed skill " + args[0]))
+	}
+
+	skill.TxID = APIstub.GetTxID()
+	skill.AssessorCertificate = certificate
+	skill.AssessmentHash = hash
+	skill.CompletedOn = completedOn
+	skill.SkillVersion = assessed.Version
+	skill.SkillLevel = assessed.Level
+	// The record changed, the assessor signs it again
+	skill.AssessorSignature = ""
+
+	data, _ = json.Marshal(skill)
+
+	repository.PutDocument(APIstub, args[0], data)
+
+	return shim.Success(nil)
+}
+
+func updateAssessmentRequest(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	data, err := repository.GetDocument(APIstub, args[0])
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to update completed skill " + args[0]))
+	}
+
+	if len(string(data)) == 0 {
+		return errs.Fail(errs.NotFound, "Failed to update planned skill, because the skill does not exist.")
+	}
+
+	skill := SkillPlanAssessmentRequest{}
+	json.Unmarshal(data, &skill)
+	skill.AssesseeID = args[1]
+	skill.AssessorID = args[2]
+	skill.SkillID = args[3]
+
+	data, _ = json.Marshal(skill)
+
+	repository.PutDocument(APIstub, args[0], data)
+
+	return shim.Success(nil)
+}
+
+// args[0].. args[n] are pair column and value, the query must filter by a user the caller can access
+// e.g: args['doctype,milestone', 'milestoneid,E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', ....]
+func getAllByQuery(APIstub shim.ChaincodeStubInterface, args[] string) sc.Response {
+	
+	filters := map[string]string{}
+	for _, arg := range args {
+		var params = strings.SplitN(arg, ",", 2)
+		if len(params) != 2 || params[0] == "" {
+			return errs.Failf(errs.InvalidArgument, "Invalid filter %s, expecting a pair column,value", arg)
+		}
+
+		filters[models.CanonicalColumn(params[0])] = params[1]
+	}
+
+	// The skill plans of a user are seen by the user, their managers and the skill plan managers
+	err := checkQueryAccess(APIstub, filters)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	// The query is marshaled from the checked filters, so a value can not add another selector
+	query, _ := json.Marshal(map[string]interface{}{"selector": filters})
+
+	resultsIterator, err := APIstub.GetQueryResult(string(query))
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to query the skill plans"))
+	}
+	
+	defer resultsIterator.Close()
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	bArrayMemberAlreadyWritten := false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		
+		if err != nil {
+			return errs.Response(err)
+		}
+
+		if repository.IsArchived(queryResponse.Value) {
+			continue
+		}
+
+		if bArrayMemberAlreadyWritten == true {
+			buffer.WriteString(",")
+		}
+
+		buffer.WriteString(string(queryResponse.Value))
+		bArrayMemberAlreadyWritten = true
+	}
+	buffer.WriteString("]")
+
+	fmt.Printf("Data: \n%s\n", buffer.String())
+
+	return shim.Success(buffer.Bytes())
+}
+
+func buildSkillPlanObject(APIstub shim.ChaincodeStubInterface, args []string) (string, []byte, error) {
+	if len(args) == 0 {
+		return "", nil, errs.New(errs.InvalidArgument, "Incorrect number of arguments. Expecting the skill plan type")
+	}
+
+	objType := strings.ToLower(args[0])
+
+	log.Info("Action Type: ", objType)
+
+	if err := createSchemas.Validate(objType, args); err != nil {
+		return "", nil, err
+	}
+	
+	switch objType {
+	case Planned:
+		if err := checkPlannedDates(args[1], args[2]); err != nil {
+			return "", nil, err
+		}
+
+		if err := checkUserAccess(APIstub, args[5], "1"); err != nil {
+			return "", nil, err
+		}
+
+		var skill = SkillPlanPlannedSkill { 
+			ID: utils.NewID(APIstub, models.PlannedSkillDocType, args[4], args[5]), 
+			PlannedFrom: args[1], 
+			PlannedTo: args[2], 
+			Priority: args[3], 
+			SkillID: args[4], 
+			UserID: args[5], 
+			DocType: "plannedskill"}
+	
+		data, _ := json.Marshal(skill)
+
+		return skill.ID, data, nil
+	
+	case InProgress:
+		var skill = SkillPlanInProgressSkill { 
+			ID: utils.NewID(APIstub, models.InProgressSkillDocType, args[3], args[4]), 
+			SkillACID: args[1], 
+			SkillACStartdate: args[2],
+			SkillID: args[3], 
+			UserID: args[4], 
+			DocType: "inprogressskill"}
+	
+		data, _ := json.Marshal(skill)
+
+		return skill.ID, data, nil
+
+	case Completed:
+		assessed, err := checkAssessor(APIstub, args[1])
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Keep the assessor certificate and transaction id, so the completion can be exported as a credential
+		certificate, err := utils.GetCreatorCertPEM(APIstub)
+
+		if err != nil {
+			return "", nil, errs.Wrap(err, "Could not get Certificate")
+		}
+
+		// Assessment outcome and comments go to the private collection, only the hash is public
+		id := utils.NewID(APIstub, models.CompletedSkillDocType, args[1], args[2])
+		result, err := getTransientAssessmentResult(APIstub, id)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		hash, err := saveAssessmentResult(APIstub, result)
+
+		if err != nil {
+			return "", nil, errs.Wrap(err, "Failed to store the assessment result")
+		}
+
+		completedOn, err := utils.GetTxTime(APIstub)
+
+		if err != nil {
+			return "", nil, errs.Wrap(err, "Could not get the transaction time")
+		}
+
+		var skill = SkillPlanCompletedSkill { 
+			ID: id, 
+			SkillID: args[1], 
+			SkillVersion: assessed.Version,
+			SkillLevel: assessed.Level,
+			UserID: args[2], 
+			TxID: APIstub.GetTxID(),
+			AssessorCertificate: certificate,
+			AssessmentHash: hash,
+			CompletedOn: completedOn,
+			DocType: "completedskill"}
+	
+		data, _ := json.Marshal(skill)
+
+		return skill.ID, data, nil
+
+	case AssessmentRequest:
+		requestedOn, err := utils.GetTxTime(APIstub)
+
+		if err != nil {
+			return "", nil, errs.Wrap(err, "Could not get the transaction time")
+		}
+
+		var skill = SkillPlanAssessmentRequest { 
+			ID: utils.NewID(APIstub, models.AssessmentRequestDocType, args[1], args[2], args[3]), 
+			AssesseeID: args[1], 
+			AssessorID: args[2],
+			SkillID: args[3], 
+			RequestedOn: requestedOn,
+			DocType: "assessmentrequest"}
+	
+		data, _ := json.Marshal(skill)
+
+		return skill.ID, data, nil
+
+	default:
+			return "", nil, errs.New(errs.InvalidArgument, "Invalid skill plan type: " + objType)
+	}
+}
+
+// checkPlannedDates returns an error when a planned skill ends before it starts, the dates have the same layout so they compare as text
+func checkPlannedDates(plannedFrom string, plannedTo string) error {
+	if plannedTo < plannedFrom {
+		return errs.Errorf(errs.InvalidArgument, "The planned skill ends on %s before it starts on %s", plannedTo, plannedFrom)
+	}
+
+	return nil
+}
+
+func main() {
+	err := shim.Start(new(SkillPlanChaincode))
+	if err != nil {
+		fmt.Printf("Error creating new skill plan chaincode: %s", err)
+	}
+}
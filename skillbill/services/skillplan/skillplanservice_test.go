@@ -0,0 +1,440 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/credential"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+var assessor = testsupport.MustNewIdentity("Org1MSP", "assessor", nil)
+
+var administrator = testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": "Administrators"})
+
+var assessment = map[string][]byte{
+	AssessmentTransientKey:     []byte(`{"assessedby":"assessor","completedon":"2018-06-01T00:00:00Z","outcome":"passed","comment":"well done"}`),
+	AssessmentSaltTransientKey: []byte("0123456789abcdef")}
+
+// trackMilestones is the track T1, the skill SKILL3 depends on SKILL2 which depends on SKILL1
+var trackMilestones = []models.TrackMilestone{{MilestoneID: "M1", Order: 1, SkillIDs: []string{"SKILL2"}}, {MilestoneID: "M2", Order: 2, SkillIDs: []string{"SKILL3"}}}
+
+var trackGraph = models.SkillGraph{
+	Skills:       []models.Skill{{SkillID: "SKILL1", TimeEstimationInHours: "4"}, {SkillID: "SKILL2", TimeEstimationInHours: "8"}, {SkillID: "SKILL3", TimeEstimationInHours: "2"}},
+	Dependencies: []models.SkillDependency{{ID: "D1", SkillID: "SKILL2", DependingOnSkill: "SKILL1"}, {ID: "D2", SkillID: "SKILL3", DependingOnSkill: "SKILL2"}}}
+
+func newSkillPlanStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("skillplan", new(SkillPlanChaincode), assessor)
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("security", testsupport.NewSecurityFake(models.User{ADLogin: "assessor", MSPID: "Org1MSP", PublicKey: assessor.PublicKey(), RoleID: "Assessors", DocType: "user"}).
+		On("GetOrgUnitUsers", testsupport.ReturnsJSON([]models.User{{ADLogin: "bob", OrgUnitID: "OU2"}, {ADLogin: "alice", OrgUnitID: "OU1"}})))
+	stub.MockPeer("role", testsupport.NewRoleFake(models.RoleFeature{ID: "1", RoleID: "Assessors", FeatureID: core.SkillPlanManagementFeatureID, AccessLevel: models.ReadOnly},
+		models.RoleFeature{ID: "2", RoleID: "Administrators", FeatureID: core.FeatureManagementFeatureID, AccessLevel: models.ReadWrite}))
+	stub.MockPeer("skill", testsupport.NewSkillFake(
+		[]models.Skill{{SkillID: "SKILL1", KnowledgeGroupID: "G1", NameTranslationID: "TRANS1", Level: "2", Version: "1", DocType: "skill"},
+			{SkillID: "SKILL4", NameTranslationID: "TRANS4", Level: "1", Version: "1", DocType: "skill"}},
+		models.SkillAcceptanceCriteria{ID: "AC1", SkillID: "SKILL1", DescriptionTranslationID: "TRANS2", DocType: "skillacceptancecriteria"}).
+		On("getPrerequisites", testsupport.ReturnsJSON(trackGraph)).
+		On("GetSkillsByKnowledgeGroup", testsupport.ReturnsJSON([]models.Skill{{SkillID: "SKILL1", KnowledgeGroupID: "G1"}})))
+	stub.MockPeer("milestone", testsupport.NewFakeChaincode().
+		On("GetTrackMilestones", testsupport.ReturnsJSON(trackMilestones)))
+	stub.MockPeer("knowledgegroup", testsupport.NewFakeChaincode().
+		On("GetByQuery", testsupport.ReturnsJSON([]models.KnowledgeGroup{{GroupID: "G1", GroupName: "Backend", DocType: "knowledgegroup"}})).
+		On("GetMemberByGroupID", testsupport.ReturnsJSON([]models.KnowledgeGroupMember{{UserID: "alice", MemberType: "Professional"}})).
+		On("IsAssessor", testsupport.Returns([]byte("true"))))
+	stub.MockPeer("translation", testsupport.NewFakeChaincode().
+		On("getByID", testsupport.ReturnsJSON(models.TranslationObject{DocType: "translation", LanguageID: "en", Translation: "Go"})))
+
+	return stub
+}
+
+// denyUserAccess makes the caller neither the user nor one of their managers
+func denyUserAccess(stub *testsupport.Stub) {
+	stub.MockPeer("security", testsupport.NewFakeChaincode().On("CheckUserAccess", testsupport.Returns([]byte("false"))))
+}
+
+// denyAssessment makes the caller no assessor of the knowledge group G1 of the skill SKILL1
+func denyAssessment(stub *testsupport.Stub) {
+	stub.MockPeer("knowledgegroup", testsupport.NewFakeChaincode().On("IsAssessor", testsupport.Returns([]byte("false"))))
+}
+
+func putPlannedSkill(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "P1", SkillPlanPlannedSkill{ID: "P1", PlannedFrom: "2018-01-01", PlannedTo: "2018-02-01", Priority: "1", SkillID: "SKILL1", UserID: "alice", DocType: "plannedskill"})
+	testsupport.PutJSON(stub, "A1", SkillPlanAssessmentRequest{ID: "A1", AssesseeID: "alice", AssessorID: "assessor", SkillID: "SKILL1", DocType: "assessmentrequest"})
+}
+
+// putCompletedSkill completes the skill C1 by the assessor, the assessment result stays in the transient data
+func putCompletedSkill(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "C1", SkillPlanCompletedSkill{ID: "C1", SkillID: "SKILL1", UserID: "alice", DocType: "completedskill"})
+	stub.SetTransient(assessment)
+	stub.Invoke("updateCompletedSkill", "C1", "SKILL1", "alice")
+}
+
+// putSignedSkill completes the skill C1 and signs its record hash with the key of the assessor
+func putSignedSkill(stub *testsupport.Stub) {
+	putCompletedSkill(stub)
+	hash, _ := credential.HashRecord(stub.State["C1"])
+	stub.Invoke("signCompletedSkill", "C1", assessor.Sign([]byte(hash)))
+}
+
+// putTeamProgress completes SKILL2 for alice after planning it, bob awaits the assessment of SKILL3 he started
+func putTeamProgress(stub *testsupport.Stub) {
+	putPlannedSkill(stub)
+	testsupport.PutJSON(stub, "P2", SkillPlanPlannedSkill{ID: "P2", PlannedTo: "2018-02-01", SkillID: "SKILL2", UserID: "alice", DocType: "plannedskill"})
+	testsupport.PutJSON(stub, "C2", SkillPlanCompletedSkill{ID: "C2", SkillID: "SKILL2", UserID: "alice", CompletedOn: "2018-06-01T09:30:00Z", DocType: "completedskill"})
+	testsupport.PutJSON(stub, "I3", SkillPlanInProgressSkill{ID: "I3", SkillACStartdate: "2018-03-01", SkillID: "SKILL3", UserID: "bob", DocType: "inprogressskill"})
+	testsupport.PutJSON(stub, "A3", SkillPlanAssessmentRequest{ID: "A3", AssesseeID: "bob", AssessorID: "assessor", SkillID: "SKILL3", RequestedOn: "2018-03-15T10:00:00Z", DocType: "assessmentrequest"})
+}
+
+func TestSkillPlanInvoke(t *testing.T) {
+	testsupport.RunInvokeCases(t, newSkillPlanStub, []testsupport.InvokeCase{
+		{Name: "getAllByQuery filters by the columns", Function: "getAllByQuery", Args: []string{"doctype,plannedskill", "userid,alice"}, Setup: putPlannedSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getAllByQuery of a user the caller can not access", Function: "getAllByQuery", Args: []string{"doctype,plannedskill", "userid,alice"},
+			Setup: func(stub *testsupport.Stub) {
+				putPlannedSkill(stub)
+				denyUserAccess(stub)
+			}, Status: shim.ERROR},
+		{Name: "getAllByQuery with a value closing the selector", Function: "getAllByQuery",
+			Args: []string{"userid,alice", `doctype,plannedskill","userid":{"$gt":""},"z":"`},
+			Setup: func(stub *testsupport.Stub) {
+				putPlannedSkill(stub)
+				testsupport.PutJSON(stub, "P2", SkillPlanPlannedSkill{ID: "P2", SkillID: "SKILL1", UserID: "bob", DocType: "plannedskill"})
+			},
+			Check: testsupport.ExpectCount(0)},
+		{Name: "getAllByQuery with a filter without value", Function: "getAllByQuery", Args: []string{"userid"}, Code: errs.InvalidArgument},
+		{Name: "getAllByQuery of every user by a skill plan manager", Function: "getAllByQuery", Args: []string{"doctype,plannedskill"}, Setup: putPlannedSkill,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getAllByQuery of every user without the skill plan feature", Function: "getAllByQuery", Args: []string{"doctype,plannedskill"},
+			Setup: func(stub *testsupport.Stub) {
+				putPlannedSkill(stub)
+				stub.MockPeer("role", testsupport.NewRoleFake())
+			}, Status: shim.ERROR},
+		{Name: "createSkillPlan of a planned skill", Function: "createSkillPlan", Args: []string{"Planned", "2018-01-01", "2018-02-01", "1", "SKILL1", "alice"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "createSkillPlan of a planned skill of a user the caller can not access", Function: "createSkillPlan", Args: []string{"Planned", "2018-01-01", "2018-02-01", "1", "SKILL1", "alice"},
+			Setup: denyUserAccess, Status: shim.ERROR},
+		{Name: "createSkillPlan of an in progress skill", Function: "createSkillPlan", Args: []string{"inprogress", "AC1", "2018-01-01", "SKILL1", "alice"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "createSkillPlan of a completed skill", Function: "createSkillPlan", Args: []string{"completed", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) { stub.SetTransient(assessment) },
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var completed SkillPlanCompletedSkill
+				json.Unmarshal(stub.State[string(res.Payload)], &completed)
+				if completed.AssessmentHash == "" || completed.TxID == "" || strings.Contains(string(stub.State[string(res.Payload)]), "well done") {
+					t.Errorf("Expected a completed skill with the hash of the private result but got %s", string(stub.State[string(res.Payload)]))
+				}
+			}},
+		{Name: "createSkillPlan of a completed skill by a caller who does not assess its knowledge group", Function: "createSkillPlan", Args: []string{"completed", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.SetTransient(assessment)
+				denyAssessment(stub)
+			}, Code: errs.PermissionDenied},
+		{Name: "createSkillPlan of a completed skill without knowledge group by an administrator", Function: "createSkillPlan", Args: []string{"completed", "SKILL4", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.SetTransient(assessment)
+				stub.SetIdentity(administrator)
+				denyAssessment(stub)
+			},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "createSkillPlan of a completed skill without knowledge group by an assessor", Function: "createSkillPlan", Args: []string{"completed", "SKILL4", "alice"},
+			Setup: func(stub *testsupport.Stub) { stub.SetTransient(assessment) }, Code: errs.PermissionDenied},
+		{Name: "createSkillPlan of a completed unknown skill", Function: "createSkillPlan", Args: []string{"completed", "SKILL9", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.SetTransient(assessment)
+				stub.SetIdentity(administrator)
+			}, Code: errs.NotFound},
+		{Name: "createSkillPlan of a completed skill by a user of another organization with the name of the assessor", Function: "createSkillPlan", Args: []string{"completed", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.SetTransient(assessment)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org2MSP", "assessor", nil))
+			}, Status: shim.ERROR,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				// The trusted MSPs are the only record
+				if stub.Keys.Len() != 1 {
+					t.Errorf("Expected no completed skill but got %d records", stub.Keys.Len())
+				}
+			}},
+		{Name: "createSkillPlan of a completed skill without assessment", Function: "createSkillPlan", Args: []string{"completed", "SKILL1", "alice"}, Status: shim.ERROR},
+		{Name: "createSkillPlan of an assessment request", Function: "createSkillPlan", Args: []string{"assessmentrequest", "alice", "assessor", "SKILL1"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "createSkillPlan of an invalid type", Function: "createSkillPlan", Args: []string{"unknown"}, Status: shim.ERROR},
+		{Name: "createSkillPlan without arguments", Function: "createSkillPlan", Code: errs.InvalidArgument},
+		{Name: "createSkillPlan of a planned skill with missing arguments", Function: "createSkillPlan", Args: []string{"Planned", "2018-01-01"}, Code: errs.InvalidArgument},
+		{Name: "createSkillPlan of a planned skill with an invalid date", Function: "createSkillPlan", Args: []string{"Planned", "01/01/2018", "2018-02-01", "1", "SKILL1", "alice"},
+			Code: errs.InvalidArgument},
+		{Name: "createSkillPlan of a planned skill with an invalid priority", Function: "createSkillPlan", Args: []string{"Planned", "2018-01-01", "2018-02-01", "high", "SKILL1", "alice"},
+			Code: errs.InvalidArgument},
+		{Name: "createSkillPlan of a planned skill which ends before it starts", Function: "createSkillPlan", Args: []string{"Planned", "2018-02-01", "2018-01-01", "1", "SKILL1", "alice"},
+			Code: errs.InvalidArgument},
+		{Name: "createSkillPlan of an in progress skill with an invalid start date", Function: "createSkillPlan", Args: []string{"inprogress", "AC1", "tomorrow", "SKILL1", "alice"},
+			Code: errs.InvalidArgument},
+		{Name: "deleteSkillPlan removes the record", Function: "deleteSkillPlan", Args: []string{"P1"}, Setup: putPlannedSkill,
+			Check: testsupport.ExpectNoState("P1")},
+		{Name: "deleteSkillPlan of an unknown record", Function: "deleteSkillPlan", Args: []string{"P9"}, Code: errs.NotFound},
+		{Name: "updatePlannedSkill changes the planned skill", Function: "updatePlannedSkill", Args: []string{"P1", "2018-03-01", "2018-04-01", "2", "SKILL1", "alice"}, Setup: putPlannedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var planned SkillPlanPlannedSkill
+				json.Unmarshal(stub.State["P1"], &planned)
+				if planned.PlannedFrom != "2018-03-01" || planned.Priority != "2" {
+					t.Errorf("Expected the updated planned skill but got %s", string(stub.State["P1"]))
+				}
+			}},
+		{Name: "updatePlannedSkill of a user the caller can not access", Function: "updatePlannedSkill", Args: []string{"P1", "2018-03-01", "2018-04-01", "2", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				putPlannedSkill(stub)
+				denyUserAccess(stub)
+			}, Status: shim.ERROR},
+		{Name: "updatePlannedSkill with missing arguments", Function: "updatePlannedSkill", Args: []string{"P1"}, Code: errs.InvalidArgument},
+		{Name: "updatePlannedSkill with an invalid date", Function: "updatePlannedSkill", Args: []string{"P1", "2018-03-01", "2018-13-01", "2", "SKILL1", "alice"}, Setup: putPlannedSkill,
+			Code: errs.InvalidArgument},
+		{Name: "updatePlannedSkill which ends before it starts", Function: "updatePlannedSkill", Args: []string{"P1", "2018-04-01", "2018-03-01", "2", "SKILL1", "alice"}, Setup: putPlannedSkill,
+			Code: errs.InvalidArgument},
+		{Name: "updateCompletedSkill stores the assessor and the hash", Function: "updateCompletedSkill", Args: []string{"C1", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, "C1", SkillPlanCompletedSkill{ID: "C1", SkillID: "SKILL1", UserID: "alice", DocType: "completedskill"})
+				stub.SetTransient(assessment)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var completed SkillPlanCompletedSkill
+				json.Unmarshal(stub.State["C1"], &completed)
+				if completed.AssessorCertificate != string(assessor.CertPEM) || completed.AssessmentHash == "" {
+					t.Errorf("Expected the assessor certificate and the hash but got %s", string(stub.State["C1"]))
+				}
+			}},
+		{Name: "updateCompletedSkill by a caller who does not assess the knowledge group", Function: "updateCompletedSkill", Args: []string{"C1", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, "C1", SkillPlanCompletedSkill{ID: "C1", SkillID: "SKILL1", UserID: "alice", DocType: "completedskill"})
+				stub.SetTransient(assessment)
+				denyAssessment(stub)
+			}, Code: errs.PermissionDenied},
+		{Name: "updateCompletedSkill of an unknown record", Function: "updateCompletedSkill", Args: []string{"C9", "SKILL1", "alice"}, Code: errs.NotFound},
+		{Name: "updateCompletedSkill of the completed skill of another user", Function: "updateCompletedSkill", Args: []string{"C1", "SKILL1", "bob"},
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, "C1", SkillPlanCompletedSkill{ID: "C1", SkillID: "SKILL1", UserID: "alice", DocType: "completedskill"})
+				stub.SetTransient(assessment)
+			}, Code: errs.InvalidArgument},
+		{Name: "updateCompletedSkill of a planned skill", Function: "updateCompletedSkill", Args: []string{"P1", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				putPlannedSkill(stub)
+				stub.SetTransient(assessment)
+			}, Code: errs.InvalidArgument},
+		{Name: "updateAssessmentRequest changes the request", Function: "updateAssessmentRequest", Args: []string{"A1", "alice", "bob", "SKILL1"}, Setup: putPlannedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var request SkillPlanAssessmentRequest
+				json.Unmarshal(stub.State["A1"], &request)
+				if request.AssessorID != "bob" {
+					t.Errorf("Expected the assessor bob but got %s", string(stub.State["A1"]))
+				}
+			}},
+		{Name: "updateAssessmentRequest with missing arguments", Function: "updateAssessmentRequest", Args: []string{"A1"}, Code: errs.InvalidArgument},
+		{Name: "getOpenBadgeAssertion renders the badge", Function: "getOpenBadgeAssertion", Args: []string{"C1", "https://skillbill.example.com/"}, Setup: putCompletedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var badge OpenBadgeAssertion
+				json.Unmarshal(res.Payload, &badge)
+				if badge.ID != "https://skillbill.example.com/completedskill/C1" || badge.Badge.Name != "Go" || badge.Badge.Issuer.Name != "Backend" {
+					t.Errorf("Unexpected badge %s", string(res.Payload))
+				}
+
+				var completed SkillPlanCompletedSkill
+				json.Unmarshal(stub.State["C1"], &completed)
+				identity := sha256.Sum256([]byte("https://skillbill.example.com/user/alice" + "C1"))
+				if badge.Recipient.Type != BadgeRecipientType || badge.Recipient.Identity != "sha256$"+hex.EncodeToString(identity[:]) ||
+					badge.IssuedOn == "" || badge.IssuedOn != completed.CompletedOn {
+					t.Errorf("Expected the hashed url of alice issued on the completion but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "getOpenBadgeAssertion of an unknown record", Function: "getOpenBadgeAssertion", Args: []string{"C9", "https://skillbill.example.com"}, Code: errs.NotFound},
+		{Name: "signCompletedSkill stores the signature of the assessor", Function: "exportLedgerProof", Args: []string{"C1"}, Setup: putSignedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var proof credential.LedgerProof
+				var completed SkillPlanCompletedSkill
+				json.Unmarshal(res.Payload, &proof)
+				json.Unmarshal(proof.Record, &completed)
+				hash, _ := credential.HashRecord(proof.Record)
+				if err := credential.VerifyRecordSignature(proof.AssessorCertificate, hash, completed.AssessorSignature); err != nil {
+					t.Errorf("Expected the record signed by the assessor but got %v, record %s", err, string(proof.Record))
+				}
+			}},
+		{Name: "signCompletedSkill without signature", Function: "signCompletedSkill", Args: []string{"C1", ""}, Setup: putCompletedSkill, Code: errs.InvalidArgument},
+		{Name: "signCompletedSkill with a signature of another key", Function: "signCompletedSkill", Args: []string{"C1", testsupport.MustNewIdentity("Org1MSP", "assessor", nil).Sign([]byte("C1"))},
+			Setup: putCompletedSkill, Code: errs.InvalidArgument},
+		{Name: "signCompletedSkill by a caller who is not the assessor", Function: "signCompletedSkill", Args: []string{"C1", "00"},
+			Setup: func(stub *testsupport.Stub) {
+				putCompletedSkill(stub)
+				stub.SetIdentity(administrator)
+			}, Code: errs.PermissionDenied},
+		{Name: "getVerifiableCredential renders the credential verified by the ledger proof", Function: "getVerifiableCredential", Args: []string{"C1"}, Setup: putSignedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var vc credential.VerifiableCredential
+				json.Unmarshal(res.Payload, &vc)
+				if vc.CredentialSubject.ID != credential.UserURNPrefix+"alice" || vc.CredentialSubject.Level != "2" || vc.Proof.LedgerKey != "C1" || vc.Proof.ProofValue == "" {
+					t.Errorf("Unexpected credential %s", string(res.Payload))
+				}
+
+				var proof credential.LedgerProof
+				json.Unmarshal(stub.Invoke("exportLedgerProof", "C1").Payload, &proof)
+				if err := credential.NewVerifier(nil).Verify(vc, proof); err != nil {
+					t.Errorf("Expected the credential verified by the ledger proof but got %v", err)
+				}
+			}},
+		{Name: "getVerifiableCredential of a record the assessor has not signed", Function: "getVerifiableCredential", Args: []string{"C1"}, Setup: putCompletedSkill,
+			Code: errs.Conflict},
+		{Name: "getVerifiableCredential with missing arguments", Function: "getVerifiableCredential", Code: errs.InvalidArgument},
+		{Name: "getVerifiableCredential claims the skill as it was assessed", Function: "getVerifiableCredential", Args: []string{"C1"},
+			Setup: func(stub *testsupport.Stub) {
+				putSignedSkill(stub)
+				stub.MockPeer("skill", testsupport.NewSkillFake([]models.Skill{{SkillID: "SKILL1", KnowledgeGroupID: "G1", Level: "3", Version: "2", DocType: "skill"}}))
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var vc credential.VerifiableCredential
+				json.Unmarshal(res.Payload, &vc)
+				if vc.CredentialSubject.Level != "2" || vc.CredentialSubject.Version != "1" {
+					t.Errorf("Expected the level and version of the assessment but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "exportLedgerProof exports the record", Function: "exportLedgerProof", Args: []string{"C1"}, Setup: putCompletedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var proof credential.LedgerProof
+				json.Unmarshal(res.Payload, &proof)
+				if string(proof.Record) != string(stub.State["C1"]) || !strings.Contains(string(proof.Record), `"skilllevel":"2"`) {
+					t.Errorf("Unexpected ledger proof %s", string(res.Payload))
+				}
+			}},
+		{Name: "exportLedgerProof of an unknown record", Function: "exportLedgerProof", Args: []string{"C9"}, Code: errs.NotFound},
+		{Name: "getAssessmentResult returns the private result", Function: "getAssessmentResult", Args: []string{"C1"}, Setup: putCompletedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var result SkillPlanAssessmentResult
+				json.Unmarshal(res.Payload, &result)
+				if result.Outcome != "passed" {
+					t.Errorf("Expected the outcome passed but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "getAssessmentResult by the assessor without access to the user", Function: "getAssessmentResult", Args: []string{"C1"},
+			Setup: func(stub *testsupport.Stub) {
+				putCompletedSkill(stub)
+				denyUserAccess(stub)
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var result SkillPlanAssessmentResult
+				json.Unmarshal(res.Payload, &result)
+				if result.Salt != hex.EncodeToString([]byte("0123456789abcdef")) {
+					t.Errorf("Expected the salt in the private result but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "getAssessmentResult by another user", Function: "getAssessmentResult", Args: []string{"C1"},
+			Setup: func(stub *testsupport.Stub) {
+				putCompletedSkill(stub)
+				denyUserAccess(stub)
+				stub.SetIdentity(administrator)
+			},
+			Code: errs.PermissionDenied},
+		{Name: "getAssessmentResult of an unknown record", Function: "getAssessmentResult", Args: []string{"C9"}, Code: errs.NotFound},
+		{Name: "verifyAssessmentResult matches the hash", Function: "verifyAssessmentResult", Args: []string{"C1"}, Setup: putCompletedSkill,
+			Check: testsupport.ExpectPayload("true")},
+		{Name: "verifyAssessmentResult of a changed result", Function: "verifyAssessmentResult", Args: []string{"C1"},
+			Setup: func(stub *testsupport.Stub) {
+				putCompletedSkill(stub)
+				stub.SetTransient(map[string][]byte{AssessmentTransientKey: []byte(`{"outcome":"failed"}`), AssessmentSaltTransientKey: assessment[AssessmentSaltTransientKey]})
+			},
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "verifyAssessmentResult with another salt", Function: "verifyAssessmentResult", Args: []string{"C1"},
+			Setup: func(stub *testsupport.Stub) {
+				putCompletedSkill(stub)
+				stub.SetTransient(map[string][]byte{AssessmentTransientKey: assessment[AssessmentTransientKey], AssessmentSaltTransientKey: []byte("fedcba9876543210")})
+			},
+			Check: testsupport.ExpectPayload("false")},
+		{Name: "updateCompletedSkill without salt", Function: "updateCompletedSkill", Args: []string{"C1", "SKILL1", "alice"},
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, "C1", SkillPlanCompletedSkill{ID: "C1", SkillID: "SKILL1", UserID: "alice", DocType: "completedskill"})
+				stub.SetTransient(map[string][]byte{AssessmentTransientKey: assessment[AssessmentTransientKey]})
+			},
+			Code: errs.InvalidArgument},
+		{Name: "getSkillGap skips the completed prerequisites", Function: "getSkillGap", Args: []string{"alice", "T1"}, Setup: putCompletedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var gap SkillGap
+				json.Unmarshal(res.Payload, &gap)
+				if len(gap.MissingSkills) != 2 || len(gap.MissingSkills[0].Prerequisites) != 0 || gap.MissingSkills[1].TotalTimeEstimationInHours != 10 || gap.TotalTimeEstimationInHours != 10 {
+					t.Errorf("Unexpected skill gap %s", string(res.Payload))
+				}
+			}},
+		{Name: "getSkillGap orders the prerequisites by dependency", Function: "getSkillGap", Args: []string{"bob", "T1"}, Setup: putCompletedSkill,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var gap SkillGap
+				json.Unmarshal(res.Payload, &gap)
+				last := gap.MissingSkills[len(gap.MissingSkills)-1]
+				if last.SkillID != "SKILL3" || len(last.Prerequisites) != 2 || last.Prerequisites[0].SkillID != "SKILL1" || last.TotalTimeEstimationInHours != 14 || gap.TotalTimeEstimationInHours != 14 {
+					t.Errorf("Unexpected skill gap %s", string(res.Payload))
+				}
+			}},
+		{Name: "getSkillGap of a user the caller can not access", Function: "getSkillGap", Args: []string{"alice", "T1"}, Setup: denyUserAccess, Status: shim.ERROR},
+		{Name: "getSkillGap with missing arguments", Function: "getSkillGap", Args: []string{"alice"}, Code: errs.InvalidArgument},
+		{Name: "getSkillMatrix of an org unit for a track", Function: "getSkillMatrix", Args: []string{"orgunit", "OU1", "track", "T1"}, Setup: putTeamProgress,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var matrix SkillMatrix
+				json.Unmarshal(res.Payload, &matrix)
+				expected := SkillMatrix{SkillIDs: []string{"SKILL2", "SKILL3"}, Rows: []SkillMatrixRow{
+					{UserID: "alice", Cells: []SkillMatrixCell{{SkillID: "SKILL2", Status: Completed, Date: "2018-06-01"}, {SkillID: "SKILL3"}}},
+					{UserID: "bob", Cells: []SkillMatrixCell{{SkillID: "SKILL2"}, {SkillID: "SKILL3", Status: AwaitingAssessment, Date: "2018-03-15"}}}}}
+				if !reflect.DeepEqual(matrix, expected) {
+					t.Errorf("Expected the matrix %+v but got %s", expected, string(res.Payload))
+				}
+			}},
+		{Name: "getSkillMatrix as csv", Function: "getSkillMatrix", Args: []string{"orgunit", "OU1", "track", "T1", "CSV"}, Setup: putTeamProgress,
+			Check: testsupport.ExpectPayload("userid,SKILL2,SKILL3\nalice,completed 2018-06-01,\nbob,,awaitingassessment 2018-03-15\n")},
+		{Name: "getSkillMatrix of a knowledge group", Function: "getSkillMatrix", Args: []string{"knowledgegroup", "G1", "knowledgegroup", "G1", "csv"}, Setup: putTeamProgress,
+			Check: testsupport.ExpectPayload("userid,SKILL1\nalice,awaitingassessment\n")},
+		{Name: "getSkillMatrix of users the caller can not access", Function: "getSkillMatrix", Args: []string{"knowledgegroup", "G1", "track", "T1"},
+			Setup: func(stub *testsupport.Stub) {
+				putTeamProgress(stub)
+				denyUserAccess(stub)
+			}, Status: shim.ERROR},
+		{Name: "getSkillMatrix with an invalid format", Function: "getSkillMatrix", Args: []string{"orgunit", "OU1", "track", "T1", "xlsx"}, Status: shim.ERROR},
+		{Name: "getSkillMatrix with invalid rows", Function: "getSkillMatrix", Args: []string{"role", "R1", "track", "T1"}, Status: shim.ERROR},
+		{Name: "getSkillMatrix with missing arguments", Function: "getSkillMatrix", Args: []string{"orgunit", "OU1"}, Code: errs.InvalidArgument},
+		{Name: "GeneratePlan plans the skills in dependency order", Function: "GeneratePlan", Args: []string{"bob", "T1", "2018-01-01", "14"},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var plan []SkillPlanPlannedSkill
+				json.Unmarshal(res.Payload, &plan)
+				expected := []SkillPlanPlannedSkill{
+					{PlannedFrom: "2018-01-01", PlannedTo: "2018-01-03", Priority: "1", SkillID: "SKILL1"},
+					{PlannedFrom: "2018-01-03", PlannedTo: "2018-01-07", Priority: "1", SkillID: "SKILL2"},
+					{PlannedFrom: "2018-01-07", PlannedTo: "2018-01-08", Priority: "2", SkillID: "SKILL3"}}
+				if len(plan) != len(expected) {
+					t.Fatalf("Unexpected plan %s", string(res.Payload))
+				}
+				for i, skill := range plan {
+					if skill.PlannedFrom != expected[i].PlannedFrom || skill.PlannedTo != expected[i].PlannedTo || skill.Priority != expected[i].Priority ||
+						skill.SkillID != expected[i].SkillID || skill.UserID != "bob" || len(stub.State[skill.ID]) == 0 {
+						t.Errorf("Expected the planned skill %+v but got %+v", expected[i], skill)
+					}
+				}
+			}},
+		{Name: "GeneratePlan keeps the skills already planned", Function: "GeneratePlan", Args: []string{"bob", "T1", "2018-01-01", "14"},
+			Setup: func(stub *testsupport.Stub) {
+				testsupport.PutJSON(stub, "P2", SkillPlanPlannedSkill{ID: "P2", SkillID: "SKILL2", UserID: "bob", DocType: "plannedskill"})
+			},
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var plan []SkillPlanPlannedSkill
+				json.Unmarshal(res.Payload, &plan)
+				if len(plan) != 2 || plan[1].SkillID != "SKILL3" || plan[1].PlannedFrom != "2018-01-03" || plan[1].PlannedTo != "2018-01-04" {
+					t.Errorf("Unexpected plan %s", string(res.Payload))
+				}
+			}},
+		{Name: "GeneratePlan for a user the caller can not access", Function: "GeneratePlan", Args: []string{"bob", "T1", "2018-01-01", "14"}, Setup: denyUserAccess, Status: shim.ERROR},
+		{Name: "GeneratePlan with an invalid start date", Function: "GeneratePlan", Args: []string{"bob", "T1", "01/01/2018", "14"}, Status: shim.ERROR},
+		{Name: "GeneratePlan without capacity", Function: "GeneratePlan", Args: []string{"bob", "T1", "2018-01-01", "0"}, Status: shim.ERROR},
+		{Name: "GeneratePlan with missing arguments", Function: "GeneratePlan", Args: []string{"bob", "T1"}, Code: errs.InvalidArgument},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+)
+
+// userColumns are the columns holding the user a skill plan record belongs to
+var userColumns = []string{models.SkillPlanUserIDColumnName, models.SkillPlanAssesseeIDColumnName, models.SkillPlanAssessorIDColumnName}
+
+// checkUserAccess returns an error unless the caller is the user, one of their direct or indirect managers,
+// or can access the skill plans with the access level (0- readonly, 1- write and read)
+func checkUserAccess(APIstub shim.ChaincodeStubInterface, userID string, accessLevel string) error {
+
+	response := core.InvokeChaincode(APIstub, "security", "CheckUserAccess", userID, core.SkillPlanManagementFeatureID, accessLevel)
+	if response.Status != shim.OK {
+		return errs.Wrapf(errs.FromResponse(response), "Could not check the access to user %s", userID)
+	}
+
+	if string(response.Payload) != "true" {
+		return errs.Errorf(errs.PermissionDenied, "Permission denied, the caller can not access the skill plan of user %s", userID)
+	}
+
+	return nil
+}
+
+// checkQueryAccess checks the access to every user a query filters by, a query of no user needs the skill plan feature
+func checkQueryAccess(APIstub shim.ChaincodeStubInterface, filters map[string]string) error {
+	users := 0
+
+	for _, column := range userColumns {
+		userID, ok := filters[column]
+		if !ok {
+			continue
+		}
+
+		if err := checkUserAccess(APIstub, userID, "0"); err != nil {
+			return err
+		}
+
+		users++
+	}
+
+	if users > 0 {
+		return nil
+	}
+
+	response := core.CreateBase().CheckUserPermission(APIstub, core.SkillPlanManagementFeatureID, "0")
+	if response.Status != shim.OK {
+		return errs.Wrap(errs.FromResponse(response), "Could not check the permission")
+	}
+
+	if string(response.Payload) != "true" {
+		return errs.Errorf(errs.PermissionDenied, "Permission denied, the query must filter by one of the columns %s", strings.Join(userColumns, ", "))
+	}
+
+	return nil
+}
+
+// checkAssessor returns the skill unless the caller does not assess the knowledge group of the skill or one of the groups above it,
+// a skill without knowledge group can only be assessed by an administrator
+func checkAssessor(APIstub shim.ChaincodeStubInterface, skillID string) (models.Skill, error) {
+
+	skill, err := getSkill(APIstub, skillID)
+	if err != nil {
+		return skill, err
+	}
+
+	if skill.KnowledgeGroupID == "" {
+		if err := core.CreateBase().CheckAdministrator(APIstub); err != nil {
+			return skill, errs.Wrapf(err, "The skill %s has no knowledge group", skillID)
+		}
+
+		return skill, nil
+	}
+
+	userID, err := getCallerID(APIstub)
+	if err != nil {
+		return skill, err
+	}
+
+	response := core.InvokeChaincode(APIstub, "knowledgegroup", "IsAssessor", userID, skill.KnowledgeGroupID)
+	if response.Status != shim.OK {
+		return skill, errs.Wrapf(errs.FromResponse(response), "Could not check the assessors of knowledge group %s", skill.KnowledgeGroupID)
+	}
+
+	if string(response.Payload) != "true" {
+		return skill, errs.Errorf(errs.PermissionDenied, "Permission denied, the caller does not assess the knowledge group %s", skill.KnowledgeGroupID)
+	}
+
+	return skill, nil
+}
+
+// getCallerID returns the login of the registered user of the caller, the security chaincode matches
+// the MSP ID and the common name of the certificate, so a user of another organization can not pass for them
+func getCallerID(APIstub shim.ChaincodeStubInterface) (string, error) {
+
+	response := core.InvokeChaincode(APIstub, "security", "GetCurrentUser")
+	if response.Status != shim.OK {
+		return "", errs.Wrap(errs.FromResponse(response), "Could not get the caller")
+	}
+
+	var user models.User
+	if err := json.Unmarshal(response.Payload, &user); err != nil {
+		return "", errs.Wrap(err, "Could not parse json to user object")
+	}
+
+	return user.ADLogin, nil
+}
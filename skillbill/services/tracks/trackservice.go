@@ -1,150 +1,203 @@
-package main
-
-
-import (
-	"fmt"
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	sc "github.com/hyperledger/fabric/protos/peer"
-)
-
-// RoleChaincode define the Smart Contract structure
-type TrackChaincode struct {
-	repo 	TrackRepo
-}
-
-// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
-func (t *TrackChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
-	InitRepo()
-
-	return shim.Success(nil)
-}
-
-// Invoke method is called as a result of an application request to run the Smart Contract "Feature"
-func (t *TrackChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
-
-	// Retrieve the requested Smart Contract function and arguments
-	function, args := APIstub.GetFunctionAndParameters()
-
-	// Route to the appropriate handler function to interact with the ledger appropriately
-	if function == "GetAllByQuery" {
-		return t.GetAllByQuery(APIstub, args)
-	} else if function == "GetTrackByID" {
-		return t.GetTrackByID(APIstub, args)
-	} else if function == "CreateTrack" {
-		return t.CreateTrack(APIstub, args)
-	} else if function == "UpdateTrack" {
-		return t.UpdateTrack(APIstub, args)
-	} else if function == "DeleteTrack" {
-		return t.DeleteTrack(APIstub, args)
-	}
-
-	return shim.Error("Invalid Smart Contract function name.")
-}
-
-// args[0].. args[n] are pair column and value
-// e.g: args['DocType,track', 'TrackID,E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', ....]
-func (t *TrackChaincode) GetAllByQuery(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-	
-	result, err := t.repo.GetByQuery(APIstub, args)
-
-	if err != nil {
-		return shim.Error("Failed to query track due to " + err.Error())
-	}
-
-	return shim.Success(result)
-}
-
-func (t *TrackChaincode) GetTrackByID(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	var id = args[0]
-	value, err := t.repo.GetByKey(APIstub, id)
-
-	if err != nil{
-		return shim.Error("Failed to delete track " + id + " due to " + err.Error())
-	}
-
-	if string(value) == "" {
-		return shim.Error("Failed to delete track, because the track " + id + " does not exist.")
-	}
-
-	return shim.Success([]byte(value))
-}
-
-func (t *TrackChaincode) CreateTrack(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2")
-	}
-
-	id, err := t.repo.CreateTrack(APIstub, args[0], args[1])
-
-	if err != nil {
-		return shim.Error("Failed to create track due to " + err.Error())
-	}
-
-	return shim.Success([]byte(id))
-}
-
-func (t *TrackChaincode) DeleteTrack(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
-	}
-
-	var trackId = args[0]
-
-	data, e := t.repo.GetByKey(APIstub, trackId)
-
-	if e != nil{
-		return shim.Error("Failed to delete track " + trackId + " due to " + e.Error())
-	}
-
-	if string(data) == "" {
-		return shim.Error("Failed to delete track, because the track " + trackId + " does not exist.")
-	}
-
-	err := t.repo.DeleteTrack(APIstub, trackId)
-
-	if err != nil{
-		return shim.Error("Failed to delete track " + trackId + " due to " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
-
-func (t *TrackChaincode) UpdateTrack(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
-
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
-	}
-
-	var trackId = args[0]
-
-	trk, err := t.repo.GetByKey(APIstub, trackId)
-
-	if err != nil{
-		return shim.Error("Failed to update track " + trackId + ": " + err.Error())
-	}
-
-	if string(trk) == "" {
-		return shim.Error("Failed to update track, because the track does not exist.")
-	}
-
-	err = t.repo.UpdateTrack(APIstub, trackId, args[1], args[2])
-
-	if err != nil{
-		return shim.Error("Failed to update track " + trackId + ": " + err.Error())
-	}
-
-	return shim.Success(nil)
-}
-
-func main() {
-	err := shim.Start(new(TrackChaincode))
-	if err != nil {
-		fmt.Printf("Error creating new TrackChaincode: %s", err)
-	}
-}
+package main
+
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+)
+
+// integrity of the tracks, the milestones of the milestone chaincode point to them
+var integrity = core.Integrity{References: map[string][]core.Reference{
+	models.TrackDocType: {
+		{Chaincode: "milestone", DocType: models.MilestoneDocType, Column: models.MilestoneTrackIDColumnName},
+	},
+}}
+
+// schemas of the arguments of the functions, they are checked before the functions are called
+var schemas = core.Schemas{
+	"GetAllByQuery": {{Name: "filter", Type: core.ArgPair, Repeated: true}},
+	"GetTrackByID":  {{Name: "track id", Required: true}},
+	"CreateTrack":   {{Name: "track translation id", Required: true}, {Name: "version", Type: core.ArgInt, Required: true}},
+	"UpdateTrack":   {{Name: "track id", Required: true}, {Name: "track translation id", Required: true}, {Name: "version", Type: core.ArgInt, Required: true}},
+	"DeleteTrack":   {{Name: "track id", Required: true}, {Name: "delete mode"}},
+}
+
+// RoleChaincode define the Smart Contract structure
+type TrackChaincode struct {
+	repo 	TrackRepo
+}
+
+// Init method is called when the Smart Contract "Feature" is instantiated by the blockchain network
+func (t *TrackChaincode) Init(APIstub shim.ChaincodeStubInterface) sc.Response {
+	InitRepo()
+
+	if err := core.InitAttributeMSPIDs(APIstub); err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to configure the MSPs trusted to issue the certificate attributes"))
+	}
+
+	return shim.Success(nil)
+}
+
+// Invoke method is called as a result of an application request to run the Smart Contract "Feature"
+func (t *TrackChaincode) Invoke(APIstub shim.ChaincodeStubInterface) sc.Response {
+
+	// Retrieve the requested Smart Contract function and arguments
+	function, args := APIstub.GetFunctionAndParameters()
+
+	// Check the arguments against the schema of the function, so the handlers can index them
+	if err := schemas.Validate(function, args); err != nil {
+		return errs.Response(err)
+	}
+
+	// Route to the appropriate handler function to interact with the ledger appropriately
+	if function == "GetAllByQuery" {
+		return t.GetAllByQuery(APIstub, args)
+	} else if function == "GetTrackByID" {
+		return t.GetTrackByID(APIstub, args)
+	} else if function == "CreateTrack" {
+		return t.CreateTrack(APIstub, args)
+	} else if function == "UpdateTrack" {
+		return t.UpdateTrack(APIstub, args)
+	} else if function == "DeleteTrack" {
+		return t.DeleteTrack(APIstub, args)
+	} else if function == "Migrate" {
+		return core.CreateBase().Migrate(APIstub, args)
+	} else if function == "GetMigrations" {
+		return core.CreateBase().GetMigrations(APIstub)
+	} else if function == "Restore" {
+		return core.CreateBase().Restore(APIstub, args, checkTrackPermission)
+	} else if function == "ListArchived" {
+		return core.CreateBase().ListArchived(APIstub, args, checkTrackPermission, models.TrackDocType)
+	} else if function == "Purge" {
+		return core.CreateBase().Purge(APIstub, args)
+	}
+
+	return errs.Fail(errs.InvalidArgument, "Invalid Smart Contract function name.")
+}
+
+// args[0].. args[n] are pair column and value
+// e.g: args['DocType,track', 'TrackID,E1ED5DAD-B286-4522-8A93-926E6D5DC9C9', ....]
+func (t *TrackChaincode) GetAllByQuery(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+	
+	result, err := t.repo.GetByQuery(APIstub, args)
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to query track"))
+	}
+
+	return shim.Success(result)
+}
+
+func (t *TrackChaincode) GetTrackByID(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+	var id = args[0]
+	value, err := t.repo.GetByKey(APIstub, id)
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to delete track " + id))
+	}
+
+	if string(value) == "" {
+		return errs.Fail(errs.NotFound, "Failed to delete track, because the track " + id + " does not exist.")
+	}
+
+	return shim.Success([]byte(value))
+}
+
+func (t *TrackChaincode) CreateTrack(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	err := core.NewAccessControl().CheckPermission(APIstub, core.TrackManagementFeatureID, "1")
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	id, err := t.repo.CreateTrack(APIstub, args[0], args[1])
+
+	if err != nil {
+		return errs.Response(errs.Wrap(err, "Failed to create track"))
+	}
+
+	return shim.Success([]byte(id))
+}
+
+// args[0] is track id, args[1] is the optional delete mode: restrict (default), cascade or soft
+func (t *TrackChaincode) DeleteTrack(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var trackId = args[0]
+
+	mode, err := core.GetDeleteMode(args, 1)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	err = checkTrackPermission(APIstub, trackId)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	data, e := t.repo.GetByKey(APIstub, trackId)
+
+	if e != nil{
+		return errs.Response(errs.Wrap(e, "Failed to delete track " + trackId))
+	}
+
+	if string(data) == "" {
+		return errs.Fail(errs.NotFound, "Failed to delete track, because the track " + trackId + " does not exist.")
+	}
+
+	err = t.repo.DeleteTrack(APIstub, trackId, mode)
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to delete track " + trackId))
+	}
+
+	return shim.Success(nil)
+}
+
+// checkTrackPermission checks the caller can manage the tracks, globally or within the scope of the track,
+// to change, delete, restore or list the archived track
+func checkTrackPermission(APIstub shim.ChaincodeStubInterface, trackId string) error {
+	return core.NewAccessControl().CheckPermission(APIstub, core.TrackManagementFeatureID, "1", trackId)
+}
+
+func (t *TrackChaincode) UpdateTrack(APIstub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	var trackId = args[0]
+
+	err := checkTrackPermission(APIstub, trackId)
+
+	if err != nil {
+		return errs.Response(err)
+	}
+
+	trk, err := t.repo.GetByKey(APIstub, trackId)
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to update track " + trackId))
+	}
+
+	if string(trk) == "" {
+		return errs.Fail(errs.NotFound, "Failed to update track, because the track does not exist.")
+	}
+
+	err = t.repo.UpdateTrack(APIstub, trackId, args[1], args[2])
+
+	if err != nil{
+		return errs.Response(errs.Wrap(err, "Failed to update track " + trackId))
+	}
+
+	return shim.Success(nil)
+}
+
+func main() {
+	err := shim.Start(new(TrackChaincode))
+	if err != nil {
+		fmt.Printf("Error creating new TrackChaincode: %s", err)
+	}
+}
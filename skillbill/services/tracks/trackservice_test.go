@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/models"
+	"github.com/skillbill/packages/core"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+func newTrackStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("track", new(TrackChaincode), testsupport.MustNewIdentity("Org1MSP", "admin", map[string]string{"skillbill.role": "Administrators"}))
+	testsupport.MustInit(t, stub, testsupport.InitArgs...)
+
+	stub.MockPeer("milestone", testsupport.NewFakeChaincode().WithReferences())
+	stub.MockPeer("security", testsupport.NewSecurityFake())
+	stub.MockPeer("role", testsupport.NewRoleFake(
+		models.RoleFeature{ID: "1", RoleID: "Administrators", FeatureID: core.TrackManagementFeatureID, AccessLevel: models.ReadWrite},
+		models.RoleFeature{ID: "2", RoleID: "TrackManagers", FeatureID: core.TrackManagementFeatureID, AccessLevel: models.ReadWrite}))
+
+	return stub
+}
+
+// asTrackManager makes bob the caller, who is assigned the role TrackManagers within the track T1 only
+func asTrackManager(stub *testsupport.Stub) {
+	putTrack(stub)
+	testsupport.PutJSON(stub, "T2", models.Track{TrackID: "T2", TrackTranslationID: "TRANS2", Version: "1", DocType: "track"})
+
+	stub.MockPeer("security", testsupport.NewSecurityFake(models.User{ADLogin: "bob", MSPID: "Org1MSP", RoleID: "Users"}).
+		On("GetScopedRoles", func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+			roles := []string{}
+			for _, scopeID := range strings.Split(args[0], ",") {
+				if scopeID == "T1" {
+					roles = append(roles, "TrackManagers")
+				}
+			}
+
+			data, _ := json.Marshal(roles)
+			return shim.Success(data)
+		}))
+	stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+}
+
+func putTrack(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "T1", models.Track{TrackID: "T1", TrackTranslationID: "TRANS1", Version: "1", DocType: "track"})
+}
+
+func TestTrackInvoke(t *testing.T) {
+	milestones := testsupport.NewFakeChaincode().WithReferences("M1")
+	putReferencedTrack := func(stub *testsupport.Stub) {
+		putTrack(stub)
+		stub.MockPeer("milestone", milestones)
+	}
+
+	testsupport.RunInvokeCases(t, newTrackStub, []testsupport.InvokeCase{
+		{Name: "GetAllByQuery filters by the columns", Function: "GetAllByQuery", Args: []string{"doctype,track"}, Setup: putTrack,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "GetTrackByID returns the track", Function: "GetTrackByID", Args: []string{"T1"}, Setup: putTrack,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var track models.Track
+				json.Unmarshal(res.Payload, &track)
+				if track.TrackID != "T1" {
+					t.Errorf("Expected the track T1 but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "GetTrackByID of an unknown track", Function: "GetTrackByID", Args: []string{"T9"}, Code: errs.NotFound},
+		{Name: "CreateTrack stores the track", Function: "CreateTrack", Args: []string{"TRANS2", "1"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "CreateTrack with missing arguments", Function: "CreateTrack", Args: []string{"TRANS2"}, Code: errs.InvalidArgument},
+		{Name: "CreateTrack with a version which is not a number", Function: "CreateTrack", Args: []string{"TRANS2", "one"}, Code: errs.InvalidArgument},
+		{Name: "GetAllByQuery with a filter without value", Function: "GetAllByQuery", Args: []string{"doctype"}, Code: errs.InvalidArgument},
+		{Name: "UpdateTrack changes the track", Function: "UpdateTrack", Args: []string{"T1", "TRANS3", "2"}, Setup: putTrack,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var track models.Track
+				json.Unmarshal(stub.State["T1"], &track)
+				if track.TrackTranslationID != "TRANS3" || track.Version != "2" {
+					t.Errorf("Expected the updated track but got %s", string(stub.State["T1"]))
+				}
+			}},
+		{Name: "UpdateTrack of an unknown track", Function: "UpdateTrack", Args: []string{"T9", "TRANS3", "2"}, Code: errs.NotFound},
+		{Name: "UpdateTrack by a manager of the track", Function: "UpdateTrack", Args: []string{"T1", "TRANS3", "2"}, Setup: asTrackManager},
+		{Name: "UpdateTrack by a manager of another track", Function: "UpdateTrack", Args: []string{"T2", "TRANS3", "2"}, Setup: asTrackManager,
+			Code: errs.PermissionDenied},
+		{Name: "CreateTrack by a manager of a track", Function: "CreateTrack", Args: []string{"TRANS2", "1"}, Setup: asTrackManager,
+			Code: errs.PermissionDenied},
+		{Name: "DeleteTrack removes the track", Function: "DeleteTrack", Args: []string{"T1"}, Setup: putTrack,
+			Check: testsupport.ExpectNoState("T1")},
+		{Name: "DeleteTrack of an unknown track", Function: "DeleteTrack", Args: []string{"T9"}, Code: errs.NotFound},
+		{Name: "DeleteTrack without the track management feature", Function: "DeleteTrack", Args: []string{"T1"},
+			Setup: func(stub *testsupport.Stub) {
+				putTrack(stub)
+				stub.SetIdentity(testsupport.MustNewIdentity("Org1MSP", "bob", nil))
+			}, Code: errs.PermissionDenied},
+		{Name: "DeleteTrack of a track with milestones", Function: "DeleteTrack", Args: []string{"T1"}, Setup: putReferencedTrack, Code: errs.Conflict},
+		{Name: "DeleteTrack cascades to the milestones", Function: "DeleteTrack", Args: []string{"T1", "cascade"}, Setup: putReferencedTrack,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				if len(stub.State["T1"]) > 0 || !milestones.Called("DeleteReferences") {
+					t.Errorf("Expected the track and its milestones deleted")
+				}
+			}},
+		{Name: "DeleteTrack soft keeps the track flagged", Function: "DeleteTrack", Args: []string{"T1", "soft"}, Setup: putReferencedTrack,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var track map[string]interface{}
+				json.Unmarshal(stub.State["T1"], &track)
+				if track[models.DeletedColumnName] != true {
+					t.Errorf("Expected the track flagged as deleted but got %s", string(stub.State["T1"]))
+				}
+			}},
+		{Name: "DeleteTrack with an invalid mode", Function: "DeleteTrack", Args: []string{"T1", "force"}, Setup: putTrack, Status: shim.ERROR},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}
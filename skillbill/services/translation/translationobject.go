@@ -1,9 +1,9 @@
-package main
-
-// TranslationObject support multi language
-type TranslationObject struct {
-	DocType             string
-	LanguageID          string
-	Translation         string
-	TranslationObjectID string
-}
+package main
+
+// TranslationObject support multi language
+type TranslationObject struct {
+	DocType             string `json:"doctype"`
+	LanguageID          string `json:"languageid"`
+	Translation         string `json:"translation"`
+	TranslationObjectID string `json:"translationobjectid"`
+}
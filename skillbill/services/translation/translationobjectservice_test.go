@@ -1,11 +1,84 @@
-package main
-
-import (
-	"testing"
-
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-)
-
-func checkInit(t *testing.T, stub *shim.MockStub, args []string) {
-
-}
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/skillbill/packages/errs"
+	"github.com/skillbill/packages/testsupport"
+)
+
+func checkInit(t *testing.T, stub *testsupport.Stub, args []string) {
+	res := stub.Init(args...)
+	if res.Status != shim.OK {
+		t.Fatalf("Init failed: %s", res.Message)
+	}
+}
+
+func newTranslationStub(t *testing.T) *testsupport.Stub {
+	stub := testsupport.NewStub("translation", new(TranslationObjectChaincode), testsupport.MustNewIdentity("Org1MSP", "admin", nil))
+	checkInit(t, stub, testsupport.InitArgs)
+
+	return stub
+}
+
+func putTranslation(stub *testsupport.Stub) {
+	testsupport.PutJSON(stub, "TRANS7", TranslationObject{DocType: "translation", LanguageID: "en", Translation: "Go", TranslationObjectID: "7"})
+}
+
+func TestTranslationInvoke(t *testing.T) {
+	testsupport.RunInvokeCases(t, newTranslationStub, []testsupport.InvokeCase{
+		{Name: "initLedger seeds translations", Function: "initLedger",
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				testsupport.ExpectState("TRANS0")(t, stub, res)
+				testsupport.ExpectState("TRANS1")(t, stub, res)
+			}},
+		{Name: "getAll returns the translation range", Function: "getAll", Setup: putTranslation,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "getAllByQuery filters by doctype", Function: "getAllByQuery", Setup: putTranslation,
+			Check: testsupport.ExpectCount(1)},
+		{Name: "create stores the translation with a derived key", Function: "create", Args: []string{"de", "Hallo", "translation"},
+			Check: testsupport.ExpectPayloadState()},
+		{Name: "create with missing arguments", Function: "create", Args: []string{"de"}, Code: errs.InvalidArgument},
+		{Name: "getByID returns the translation", Function: "getByID", Args: []string{"TRANS7"}, Setup: putTranslation,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var translation TranslationObject
+				json.Unmarshal(res.Payload, &translation)
+				if translation.Translation != "Go" {
+					t.Errorf("Expected translation Go but got %s", string(res.Payload))
+				}
+			}},
+		{Name: "getByID with missing arguments", Function: "getByID", Code: errs.InvalidArgument},
+		{Name: "delete removes the translation", Function: "delete", Args: []string{"TRANS7"}, Setup: putTranslation,
+			Check: testsupport.ExpectNoState("TRANS7")},
+		{Name: "Restore by a caller who is no administrator", Function: "Restore", Args: []string{"TRANS7"},
+			Setup: func(stub *testsupport.Stub) {
+				stub.PutRecord("TRANS7", []byte(`{"doctype":"translation","languageid":"en","deleted":true}`))
+				stub.MockPeer("role", testsupport.NewRoleFake())
+				stub.MockPeer("security", testsupport.NewSecurityFake())
+			}, Code: errs.PermissionDenied},
+		{Name: "update changes the translation", Function: "update", Args: []string{"TRANS7", "en", "Golang"}, Setup: putTranslation,
+			Check: func(t *testing.T, stub *testsupport.Stub, res pb.Response) {
+				var translation TranslationObject
+				json.Unmarshal(stub.State["TRANS7"], &translation)
+				if translation.Translation != "Golang" {
+					t.Errorf("Expected translation Golang but got %s", string(stub.State["TRANS7"]))
+				}
+			}},
+		{Name: "update with missing arguments", Function: "update", Args: []string{"TRANS7", "en"}, Setup: putTranslation, Code: errs.InvalidArgument},
+		{Name: "migrateFieldNames rewrites untagged translations", Function: "migrateFieldNames",
+			Setup: func(stub *testsupport.Stub) {
+				stub.PutRecord("TRANS8", []byte(`{"DocType":"Translation","LanguageID":"en","Translation":"Go","TranslationObjectID":"8"}`))
+			},
+			Check: testsupport.ExpectPayload("1")},
+		{Name: "getAllByQuery after migrateFieldNames", Function: "getAllByQuery",
+			Setup: func(stub *testsupport.Stub) {
+				stub.PutRecord("TRANS8", []byte(`{"DocType":"Translation","LanguageID":"en","Translation":"Go","TranslationObjectID":"8"}`))
+				stub.Invoke("migrateFieldNames")
+			},
+			Check: testsupport.ExpectCount(1)},
+		{Name: "unknown function", Function: "unknown", Code: errs.InvalidArgument},
+	})
+}